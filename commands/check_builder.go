@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/spf13/cobra"
+)
+
+type checkBuilderFlags struct {
+	builderFile  string
+	lifecycleURI string
+	platformAPIs []string
+}
+
+func checkBuilder() *cobra.Command {
+	flags := &checkBuilderFlags{}
+	cmd := &cobra.Command{
+		Use:   "check-builder",
+		Short: "check builder compatibility",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkBuilderRun(*flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.builderFile, "builder-file", "", "path to the builder.toml file (required)")
+	cmd.Flags().StringVar(&flags.lifecycleURI, "lifecycle-uri", "index.docker.io/buildpacksio/lifecycle", "URI for lifecycle image (optional: default=index.docker.io/buildpacksio/lifecycle)")
+	cmd.Flags().StringArrayVar(&flags.platformAPIs, "platform-api", nil, "platform API that the builder is used with, checked against the platform APIs supported by the lifecycle (can be repeated)")
+
+	err := cmd.MarkFlagRequired("builder-file")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to mark builder-file flag as required")
+	}
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(checkBuilder())
+}
+
+func checkBuilderRun(flags checkBuilderFlags) error {
+	builder, err := internal.ParseBuilderConfig(flags.builderFile)
+	if err != nil {
+		return err
+	}
+
+	lookup := internal.NewRegistryLookup(insecureRegistries(), os.Stdout)
+
+	var images []string
+	for _, buildpack := range builder.Buildpacks {
+		images = append(images, buildpack.URI)
+	}
+	for _, extension := range builder.Extensions {
+		images = append(images, extension.URI)
+	}
+
+	compatibility, err := builderAPICompatibility(lookup, flags.lifecycleURI, builder.Lifecycle.Version, images, flags.platformAPIs)
+	if err != nil {
+		return err
+	}
+
+	err = compatibility.Print(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	platforms := internal.TargetPlatforms(builder.Targets)
+
+	var resolved []resolvedImage
+	for _, uri := range images {
+		named, tag, err := internal.ParseImageURI(uri)
+		if err != nil {
+			return fmt.Errorf("failed to parse image URI %s: %w", uri, err)
+		}
+
+		image := internal.Image{Name: named.Name(), Version: tag}
+		imagePlatforms, err := targetPlatforms(lookup, image, platforms)
+		if err != nil {
+			return err
+		}
+
+		resolved = append(resolved, resolvedImage{Image: image, URI: uri, Platforms: imagePlatforms})
+	}
+
+	err = verifyBuilderTargets(os.Stdout, platforms, resolved)
+	if err != nil {
+		return err
+	}
+
+	return compatibility.Check()
+}
+
+// builderAPICompatibility reads the buildpack and platform APIs supported by
+// the given lifecycle version and the buildpack APIs of the buildpacks and
+// extensions packaged in the given images.
+func builderAPICompatibility(lookup *internal.RegistryLookup, lifecycleURI, lifecycleVersion string, images, platformAPIs []string) (internal.APICompatibility, error) {
+	lifecycleImage := fmt.Sprintf("%s:%s", lifecycleURI, lifecycleVersion)
+
+	labels, err := lookup.Labels(lifecycleImage)
+	if err != nil {
+		return internal.APICompatibility{}, fmt.Errorf("failed to read lifecycle APIs: %w", err)
+	}
+
+	lifecycle, err := internal.ParseLifecycleAPIs(labels)
+	if err != nil {
+		return internal.APICompatibility{}, fmt.Errorf("failed to read lifecycle APIs of %s: %w", lifecycleImage, err)
+	}
+
+	buildpacks, err := builderBuildpackAPIs(lookup, images)
+	if err != nil {
+		return internal.APICompatibility{}, err
+	}
+
+	return internal.APICompatibility{
+		LifecycleVersion: lifecycleVersion,
+		Lifecycle:        lifecycle,
+		Buildpacks:       buildpacks,
+		Platforms:        platformAPIs,
+	}, nil
+}
+
+// builderBuildpackAPIs reads the buildpack APIs of the buildpacks and
+// extensions packaged in the given images.
+func builderBuildpackAPIs(lookup *internal.RegistryLookup, images []string) ([]internal.BuildpackAPI, error) {
+	var buildpacks []internal.BuildpackAPI
+	for _, uri := range images {
+		labels, err := lookup.Labels(uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read buildpack APIs: %w", err)
+		}
+
+		apis, err := internal.ParseBuildpackAPIs(labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read buildpack APIs of %s: %w", uri, err)
+		}
+
+		for _, api := range apis {
+			// buildpacks can be packaged in more than one image, ex. as part of a composite buildpack
+			if !slices.Contains(buildpacks, api) {
+				buildpacks = append(buildpacks, api)
+			}
+		}
+	}
+
+	return buildpacks, nil
+}
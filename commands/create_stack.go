@@ -101,6 +101,7 @@ func createStackRun(flags createStackFlags) error {
 	if err != nil {
 		return err
 	}
+	client = client.WithInsecureRegistries(insecureRegistries())
 
 	builder := ihop.NewBuilder(client, ihop.Cataloger{}, runtime.NumCPU())
 	creator := ihop.NewCreator(client, builder, ihop.UserLayerCreator{}, ihop.SBOMLayerCreator{}, ihop.OsReleaseLayerCreator{Def: definition}, time.Now, logger)
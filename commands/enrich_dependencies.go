@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/spf13/cobra"
+)
+
+type enrichDependenciesFlags struct {
+	buildpackFile string
+	extensionFile string
+	templates     string
+}
+
+func enrichDependencies() *cobra.Command {
+	flags := &enrichDependenciesFlags{}
+	cmd := &cobra.Command{
+		Use:   "enrich-dependencies",
+		Short: "fills in missing purl and cpes fields of the dependencies in a buildpack.toml or extension.toml from templates",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return enrichDependenciesRun(*flags)
+		},
+	}
+	cmd.Flags().StringVar(&flags.buildpackFile, "buildpack-file", "", "path to the buildpack.toml file (required unless --extension-file is set)")
+	cmd.Flags().StringVar(&flags.extensionFile, "extension-file", "", "path to the extension.toml file (required unless --buildpack-file is set)")
+	cmd.Flags().StringVar(&flags.templates, "templates", "", "JSON file of purl and cpes templates keyed by dependency ID, or * for any dependency, ex. {\"node\": {\"purl\": \"pkg:generic/node@{{.Version}}\"}} (required)")
+
+	cmd.MarkFlagsOneRequired("buildpack-file", "extension-file")
+	cmd.MarkFlagsMutuallyExclusive("buildpack-file", "extension-file")
+	err := cmd.MarkFlagRequired("templates")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to mark templates flag as required")
+	}
+	return cmd
+}
+
+func init() {
+	rootCmd.AddCommand(enrichDependencies())
+}
+
+func enrichDependenciesRun(flags enrichDependenciesFlags) error {
+	templates, err := internal.ReadEnrichmentTemplates(flags.templates)
+	if err != nil {
+		return err
+	}
+
+	kind, configFile, config, err := readDependencyConfig(flags.buildpackFile, flags.extensionFile)
+	if err != nil {
+		return err
+	}
+
+	explicitCPEs, err := internal.ReadDependencyCPEs(configFile)
+	if err != nil {
+		return err
+	}
+
+	var enrichments []internal.DependencyEnrichment
+	config.Metadata.Dependencies, enrichments, err = internal.EnrichDependencies(config.Metadata.Dependencies, templates, explicitCPEs)
+	if err != nil {
+		return err
+	}
+
+	if len(enrichments) == 0 {
+		fmt.Printf("No dependencies of the %s.toml to enrich\n", kind)
+		return nil
+	}
+
+	err = writeDependencyConfig(kind, configFile, config, internal.IndexDependencyCPEs(config.Metadata.Dependencies, explicitCPEs, enrichments), os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	printEnrichments(os.Stdout, config.Metadata.Dependencies, enrichments)
+
+	return nil
+}
@@ -68,6 +68,7 @@ func publishImageRun(flags publishImageFlags) error {
 	if err != nil {
 		return err
 	}
+	client = client.WithInsecureRegistries(insecureRegistries())
 
 	logger.Process("Uploading image to %s", flags.imageReference)
 	err = client.Upload(flags.imageReference, tmpExtractedImage)
@@ -91,6 +91,7 @@ func publishStackRun(flags publishStackFlags) error {
 	if err != nil {
 		return err
 	}
+	client = client.WithInsecureRegistries(insecureRegistries())
 
 	logger.Process("Uploading build image to %s", flags.buildReference)
 	err = client.Upload(flags.buildReference, tmpBuild)
@@ -1,13 +1,30 @@
 package commands
 
-import "github.com/spf13/cobra"
+import (
+	"os"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/spf13/cobra"
+)
 
 var (
 	rootCmd = &cobra.Command{
 		Use: "jam",
 	}
+
+	insecureRegistryFlag []string
 )
 
+func init() {
+	rootCmd.PersistentFlags().StringSliceVar(&insecureRegistryFlag, "insecure-registry", nil, "registry (host or host:port) to reach over plain HTTP or without verifying its certificate, can be repeated or set in JAM_INSECURE_REGISTRIES as a comma separated list (optional)")
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
+
+// insecureRegistries returns the registries given with --insecure-registry and
+// in the JAM_INSECURE_REGISTRIES environment variable.
+func insecureRegistries() internal.InsecureRegistries {
+	return internal.ParseInsecureRegistries(insecureRegistryFlag, os.Getenv("JAM_INSECURE_REGISTRIES"))
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/spf13/cobra"
+)
+
+func schema() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "prints the JSON Schema of the documents jam reads",
+	}
+	cmd.AddCommand(metadataSchema())
+	return cmd
+}
+
+func metadataSchema() *cobra.Command {
+	return &cobra.Command{
+		Use:   "metadata",
+		Short: "prints the JSON Schema of the dependency metadata files read by update-dependencies",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return metadataSchemaRun()
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(schema())
+}
+
+func metadataSchemaRun() error {
+	content, err := internal.MetadataJSONSchema()
+	if err != nil {
+		return fmt.Errorf("failed to generate metadata schema: %w", err)
+	}
+
+	fmt.Println(string(content))
+	return nil
+}
@@ -2,7 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/paketo-buildpacks/jam/v2/internal"
 	"github.com/spf13/cobra"
@@ -11,6 +14,25 @@ import (
 type updateBuilderFlags struct {
 	builderFile  string
 	lifecycleURI string
+	dryRun       bool
+	report       string
+	reportFile   string
+	pinDigest    bool
+	concurrency  int
+	channel      string
+
+	skipMissingTargets bool
+	checkAPIs          string
+	platformAPIs       []string
+
+	patchOnly         bool
+	minorOnly         bool
+	maxBump           string
+	buildpacksMaxBump string
+	extensionsMaxBump string
+	lifecycleMaxBump  string
+	imagesMaxBump     string
+	maxBumpOverrides  []string
 }
 
 func updateBuilder() *cobra.Command {
@@ -24,6 +46,23 @@ func updateBuilder() *cobra.Command {
 	}
 	cmd.Flags().StringVar(&flags.builderFile, "builder-file", "", "path to the builder.toml file (required)")
 	cmd.Flags().StringVar(&flags.lifecycleURI, "lifecycle-uri", "index.docker.io/buildpacksio/lifecycle", "URI for lifecycle image (optional: default=index.docker.io/buildpacksio/lifecycle)")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "print a diff of the changes and a table of the version updates without writing the builder.toml file")
+	cmd.Flags().StringVar(&flags.report, "report", "", "write a machine-readable report of every change in the given format (supported: json)")
+	cmd.Flags().StringVar(&flags.reportFile, "report-file", "", "path to write the report to (default: stdout)")
+	cmd.Flags().BoolVar(&flags.pinDigest, "pin-digest", false, "pin updated buildpack, extension, build and run image references to the digest of their tag (ex. <image>:1.2.3@sha256:<digest>)")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", defaultConcurrency, "number of buildpacks and extensions to look up in parallel")
+	cmd.Flags().StringVar(&flags.channel, "channel", internal.ChannelStable, "prerelease versions to consider: stable, rc, all or a regular expression that the prerelease component must match")
+	cmd.Flags().StringVar(&flags.checkAPIs, "check-apis", "", "check that the lifecycle supports the buildpack APIs of the updated buildpacks and extensions and every --platform-api, and either warn or fail when it does not; with fail, the lifecycle is only updated to a version that supports them (supported: warn, fail)")
+	cmd.Flags().StringArrayVar(&flags.platformAPIs, "platform-api", nil, "platform API that the builder is used with, checked by --check-apis against the platform APIs supported by the lifecycle (can be repeated)")
+	cmd.Flags().BoolVar(&flags.skipMissingTargets, "skip-missing-targets", false, "update buildpacks and extensions to the highest version that is available for every builder target instead of failing")
+	cmd.Flags().BoolVar(&flags.patchOnly, "patch-only", false, "allow patch changes ONLY to version bumps (shorthand for --max-bump patch)")
+	cmd.Flags().BoolVar(&flags.minorOnly, "minor-only", false, "allow minor and patch changes ONLY to version bumps (shorthand for --max-bump minor)")
+	cmd.Flags().StringVar(&flags.maxBump, "max-bump", "", "largest version bump allowed for every entry: major, minor, patch or none (default: major)")
+	cmd.Flags().StringVar(&flags.buildpacksMaxBump, "buildpacks-max-bump", "", "largest version bump allowed for buildpacks, overrides --max-bump")
+	cmd.Flags().StringVar(&flags.extensionsMaxBump, "extensions-max-bump", "", "largest version bump allowed for extensions, overrides --max-bump")
+	cmd.Flags().StringVar(&flags.lifecycleMaxBump, "lifecycle-max-bump", "", "largest version bump allowed for the lifecycle, overrides --max-bump")
+	cmd.Flags().StringVar(&flags.imagesMaxBump, "images-max-bump", "", "largest version bump allowed for build and run images, overrides --max-bump")
+	cmd.Flags().StringArrayVar(&flags.maxBumpOverrides, "max-bump-override", nil, "largest version bump allowed for a single buildpack, extension or image, in the form <id-or-image>=<bump> (can be repeated)")
 
 	err := cmd.MarkFlagRequired("builder-file")
 	if err != nil {
@@ -37,54 +76,199 @@ func init() {
 }
 
 func updateBuilderRun(flags updateBuilderFlags) error {
+	err := validateReportFormat(flags.report)
+	if err != nil {
+		return err
+	}
+
+	err = validateConcurrency(flags.concurrency)
+	if err != nil {
+		return err
+	}
+
+	if flags.checkAPIs != "" && flags.checkAPIs != checkAPIsWarn && flags.checkAPIs != checkAPIsFail {
+		return fmt.Errorf("unsupported --check-apis value %q: must be %q or %q", flags.checkAPIs, checkAPIsWarn, checkAPIsFail)
+	}
+
+	policy, err := builderUpdatePolicy(flags)
+	if err != nil {
+		return err
+	}
+
+	channel, err := internal.ParseChannel(flags.channel)
+	if err != nil {
+		return err
+	}
+
 	builder, err := internal.ParseBuilderConfig(flags.builderFile)
 	if err != nil {
 		return err
 	}
 
-	for i, buildpack := range builder.Buildpacks {
-		image, err := internal.FindLatestImage(buildpack.URI, "")
+	var changes []internal.VersionChange
+
+	lookup := internal.NewRegistryLookup(insecureRegistries(), humanOutput(flags.report, flags.reportFile))
+
+	// buildpacks and extensions must be available for every builder target
+	platforms := internal.TargetPlatforms(builder.Targets)
+	var platformOptions []internal.FindOption
+	if flags.skipMissingTargets {
+		platformOptions = append(platformOptions, internal.WithPlatforms(platforms))
+	}
+
+	buildpacks, err := resolveConcurrently(len(builder.Buildpacks), flags.concurrency, func(i int) (resolvedImage, error) {
+		buildpack := builder.Buildpacks[i]
+
+		// the buildpackage ID is only needed up front when it may select an override or a version hold
+		var (
+			buildpackageID string
+			err            error
+		)
+		if policy.HasOverrides() || len(builder.Metadata.Jam.Constraints) > 0 {
+			buildpackageID, err = lookup.BuildpackageID(buildpack.URI)
+			if err != nil {
+				return resolvedImage{}, fmt.Errorf("failed to get buildpackage ID for %s: %w", buildpack.URI, err)
+			}
+		}
+
+		constraint, err := internal.BumpConstraint(imageTag(buildpack.URI), policy.MaxBump(updateCategoryBuildpacks, append([]string{buildpackageID}, imageKeys(buildpack.URI)...)...))
 		if err != nil {
-			return err
+			return resolvedImage{}, err
 		}
 
-		builder.Buildpacks[i].Version = image.Version
-		builder.Buildpacks[i].URI = fmt.Sprintf("%s:%s", image.Name, image.Version)
+		hold, err := builder.Metadata.Jam.HoldConstraint(buildpack.Constraint, append([]string{buildpackageID}, imageKeys(buildpack.URI)...)...)
+		if err != nil {
+			return resolvedImage{}, fmt.Errorf("failed to read version hold for %s: %w", buildpack.URI, err)
+		}
+
+		image, err := internal.FindLatestImage(buildpack.URI, "", append([]internal.FindOption{internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(constraint), internal.WithConstraint(hold)}, platformOptions...)...)
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		uri, err := imageReference(lookup, image, flags.pinDigest)
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		if buildpackageID == "" {
+			buildpackageID, err = lookup.BuildpackageID(buildpack.URI)
+			if err != nil {
+				return resolvedImage{}, fmt.Errorf("failed to get buildpackage ID for %s: %w", buildpack.URI, err)
+			}
+		}
 
-		buildpackageID, err := internal.GetBuildpackageID(buildpack.URI)
+		imagePlatforms, err := targetPlatforms(lookup, image, platforms)
 		if err != nil {
-			return fmt.Errorf("failed to get buildpackage ID for %s: %w", buildpack.URI, err)
+			return resolvedImage{}, err
 		}
 
+		return resolvedImage{ID: buildpackageID, Image: image, URI: uri, Platforms: imagePlatforms}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, buildpack := range builder.Buildpacks {
+		buildpackageID, image := buildpacks[i].ID, buildpacks[i].Image
+
+		builder.Buildpacks[i].Version = image.Version
+		builder.Buildpacks[i].URI = buildpacks[i].URI
+
+		changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("buildpacks[%d].uri", i), buildpackageID,
+			image.Name, imageTag(buildpack.URI), image.Version, imageSource(buildpack.URI)))
+
 		for j, order := range builder.Order {
 			for k, group := range order.Group {
 				if group.ID == buildpackageID {
 					if builder.Order[j].Group[k].Version != "" {
+						changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("order[%d].group[%d].version", j, k), buildpackageID,
+							image.Name, group.Version, image.Version, imageSource(buildpack.URI)))
+
 						builder.Order[j].Group[k].Version = image.Version
 					}
 				}
 			}
 		}
+
+		for _, system := range []struct {
+			path       string
+			buildpacks []internal.BuilderConfigSystemBuildpack
+		}{
+			{"system.pre.buildpacks", builder.System.Pre.Buildpacks},
+			{"system.post.buildpacks", builder.System.Post.Buildpacks},
+		} {
+			for j, systemBuildpack := range system.buildpacks {
+				if systemBuildpack.ID == buildpackageID && systemBuildpack.Version != "" {
+					changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("%s[%d].version", system.path, j), buildpackageID,
+						image.Name, systemBuildpack.Version, image.Version, imageSource(buildpack.URI)))
+
+					system.buildpacks[j].Version = image.Version
+				}
+			}
+		}
 	}
 
-	for i, extension := range builder.Extensions {
-		image, err := internal.FindLatestImage(extension.URI, "")
+	extensions, err := resolveConcurrently(len(builder.Extensions), flags.concurrency, func(i int) (resolvedImage, error) {
+		extension := builder.Extensions[i]
+
+		constraint, err := internal.BumpConstraint(imageTag(extension.URI), policy.MaxBump(updateCategoryExtensions, append([]string{extension.ID}, imageKeys(extension.URI)...)...))
 		if err != nil {
-			return err
+			return resolvedImage{}, err
 		}
 
-		builder.Extensions[i].Version = image.Version
-		builder.Extensions[i].URI = fmt.Sprintf("%s:%s", image.Name, image.Version)
+		hold, err := builder.Metadata.Jam.HoldConstraint(extension.Constraint, append([]string{extension.ID}, imageKeys(extension.URI)...)...)
+		if err != nil {
+			return resolvedImage{}, fmt.Errorf("failed to read version hold for %s: %w", extension.URI, err)
+		}
+
+		image, err := internal.FindLatestImage(extension.URI, "", append([]internal.FindOption{internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(constraint), internal.WithConstraint(hold)}, platformOptions...)...)
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		uri, err := imageReference(lookup, image, flags.pinDigest)
+		if err != nil {
+			return resolvedImage{}, err
+		}
 
-		extensionID, err := internal.GetBuildpackageID(extension.URI)
+		extensionID, err := lookup.BuildpackageID(extension.URI)
 		if err != nil {
-			return fmt.Errorf("failed to get extension ID for %s: %w", extension.URI, err)
+			return resolvedImage{}, fmt.Errorf("failed to get extension ID for %s: %w", extension.URI, err)
 		}
 
+		imagePlatforms, err := targetPlatforms(lookup, image, platforms)
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		return resolvedImage{ID: extensionID, Image: image, URI: uri, Platforms: imagePlatforms}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = verifyBuilderTargets(humanOutput(flags.report, flags.reportFile), platforms, append(slices.Clone(buildpacks), extensions...))
+	if err != nil {
+		return err
+	}
+
+	for i, extension := range builder.Extensions {
+		extensionID, image := extensions[i].ID, extensions[i].Image
+
+		builder.Extensions[i].Version = image.Version
+		builder.Extensions[i].URI = extensions[i].URI
+
+		changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("extensions[%d].uri", i), extensionID,
+			image.Name, imageTag(extension.URI), image.Version, imageSource(extension.URI)))
+
 		for j, orderextensions := range builder.OrderExtension {
 			for k, group := range orderextensions.Group {
 				if group.ID == extensionID {
 					if builder.OrderExtension[j].Group[k].Version != "" {
+						changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("order-extensions[%d].group[%d].version", j, k), extensionID,
+							image.Name, group.Version, image.Version, imageSource(extension.URI)))
+
 						builder.OrderExtension[j].Group[k].Version = image.Version
 					}
 				}
@@ -92,26 +276,107 @@ func updateBuilderRun(flags updateBuilderFlags) error {
 		}
 	}
 
-	lifecycleImage, err := internal.FindLatestImage(flags.lifecycleURI, "")
+	lifecycleConstraint, err := internal.BumpConstraint(builder.Lifecycle.Version, policy.MaxBump(updateCategoryLifecycle, append([]string{"lifecycle"}, imageKeys(flags.lifecycleURI)...)...))
+	if err != nil {
+		return err
+	}
+
+	lifecycleHold, err := builder.Metadata.Jam.HoldConstraint("", append([]string{"lifecycle"}, imageKeys(flags.lifecycleURI)...)...)
+	if err != nil {
+		return fmt.Errorf("failed to read version hold for lifecycle: %w", err)
+	}
+
+	var images []string
+	for _, buildpack := range builder.Buildpacks {
+		images = append(images, buildpack.URI)
+	}
+	for _, extension := range builder.Extensions {
+		images = append(images, extension.URI)
+	}
+
+	lifecycleOptions := []internal.FindOption{internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(lifecycleConstraint), internal.WithConstraint(lifecycleHold)}
+	if flags.checkAPIs == checkAPIsFail {
+		// only update to a lifecycle that supports every API, so that the
+		// check below only fails when no such lifecycle can be found
+		buildpackAPIs, err := builderBuildpackAPIs(lookup, images)
+		if err != nil {
+			return err
+		}
+
+		lifecycleOptions = append(lifecycleOptions, internal.WithSupportedAPIs(buildpackAPIs, flags.platformAPIs))
+	}
+
+	lifecycleImage, err := internal.FindLatestImage(flags.lifecycleURI, "", lifecycleOptions...)
 	if err != nil {
 		return err
 	}
 
+	changes = append(changes, versionChange(flags.builderFile, "lifecycle.version", "",
+		lifecycleImage.Name, builder.Lifecycle.Version, lifecycleImage.Version, imageSource(flags.lifecycleURI)))
+
 	builder.Lifecycle.Version = lifecycleImage.Version
 
+	if flags.checkAPIs != "" {
+		compatibility, err := builderAPICompatibility(lookup, flags.lifecycleURI, builder.Lifecycle.Version, images, flags.platformAPIs)
+		if err != nil {
+			return err
+		}
+
+		output := humanOutput(flags.report, flags.reportFile)
+		err = compatibility.Print(output)
+		if err != nil {
+			return err
+		}
+
+		err = compatibility.Check()
+		if err != nil {
+			if flags.checkAPIs == checkAPIsFail {
+				return err
+			}
+
+			fmt.Fprintf(output, "Warning: %s\n", err)
+		}
+	}
+
 	if builder.Build.Image != "" {
-		latestBuildImage, err := internal.FindLatestImage(builder.Build.Image, "")
+		constraint, err := internal.BumpConstraint(imageTag(builder.Build.Image), policy.MaxBump(updateCategoryImages, imageKeys(builder.Build.Image)...))
+		if err != nil {
+			return err
+		}
+
+		hold, err := builder.Metadata.Jam.HoldConstraint("", imageKeys(builder.Build.Image)...)
+		if err != nil {
+			return fmt.Errorf("failed to read version hold for %s: %w", builder.Build.Image, err)
+		}
+
+		latestBuildImage, err := internal.FindLatestImage(builder.Build.Image, "", internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(constraint), internal.WithConstraint(hold))
 		if err != nil {
 			return err
 		}
 
-		builder.Build.Image = fmt.Sprintf("%s:%s", latestBuildImage.Name, latestBuildImage.Version)
+		changes = append(changes, versionChange(flags.builderFile, "build.image", "",
+			latestBuildImage.Name, imageTag(builder.Build.Image), latestBuildImage.Version, imageSource(builder.Build.Image)))
+
+		builder.Build.Image, err = imageReference(lookup, latestBuildImage, flags.pinDigest)
+		if err != nil {
+			return err
+		}
 	}
 
 	if len(builder.Run.Images) > 0 {
 		latestRunImages := []internal.ImageRegistry{}
-		for _, img := range builder.Run.Images {
-			runImage, err := internal.FindLatestImage(img.Image, "")
+		for i, img := range builder.Run.Images {
+			constraint, err := internal.BumpConstraint(imageTag(img.Image), policy.MaxBump(updateCategoryImages, imageKeys(img.Image)...))
+			if err != nil {
+				return err
+			}
+
+			hold, err := builder.Metadata.Jam.HoldConstraint("", imageKeys(img.Image)...)
+			if err != nil {
+				return fmt.Errorf("failed to read version hold for %s: %w", img.Image, err)
+			}
+
+			runImage, err := internal.FindLatestImage(img.Image, "", internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(constraint), internal.WithConstraint(hold))
 			if err != nil {
 				return err
 			}
@@ -122,12 +387,39 @@ func updateBuilderRun(flags updateBuilderFlags) error {
 			}
 
 			if imgTag != "latest" {
+				changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("run.images[%d].image", i), "",
+					runImage.Name, imgTag, runImage.Version, imageSource(img.Image)))
+
+				runImageRef, err := imageReference(lookup, runImage, flags.pinDigest)
+				if err != nil {
+					return err
+				}
+
+				mirrors, err := internal.UpdateRunImageMirrors(runImage.Version, slices.Clone(img.Mirrors))
+				if err != nil {
+					return err
+				}
+
+				for j, mirror := range mirrors {
+					if mirror != img.Mirrors[j] {
+						mirrorName, _, err := internal.ParseImageURI(mirror)
+						if err != nil {
+							return fmt.Errorf("failed to parse image URI %s: %w", mirror, err)
+						}
+
+						changes = append(changes, versionChange(flags.builderFile, fmt.Sprintf("run.images[%d].mirrors[%d]", i, j), "",
+							mirrorName.Name(), imageTag(img.Mirrors[j]), runImage.Version, imageSource(img.Mirrors[j])))
+					}
+				}
+
 				latestRunImages = append(latestRunImages, internal.ImageRegistry{
-					Image: fmt.Sprintf("%s:%s", runImage.Name, runImage.Version),
+					Image:   runImageRef,
+					Mirrors: mirrors,
 				})
 			} else {
 				latestRunImages = append(latestRunImages, internal.ImageRegistry{
-					Image: fmt.Sprintf("%s:%s", runImage.Name, "latest"),
+					Image:   fmt.Sprintf("%s:%s", runImage.Name, "latest"),
+					Mirrors: img.Mirrors,
 				})
 			}
 		}
@@ -137,14 +429,38 @@ func updateBuilderRun(flags updateBuilderFlags) error {
 
 	// Deprecated: when builder.Run.Images and builder.Build.Image are also specified, the lifecycle will ignore stack-based images
 	if builder.Stack.BuildImage != "" && builder.Stack.RunImage != "" {
-		runImage, buildImage, err := internal.FindLatestStackImages(builder.Stack.RunImage, builder.Stack.BuildImage)
+		constraint, err := internal.BumpConstraint(imageTag(builder.Stack.BuildImage), policy.MaxBump(updateCategoryImages, imageKeys(builder.Stack.BuildImage)...))
+		if err != nil {
+			return err
+		}
+
+		hold, err := builder.Metadata.Jam.HoldConstraint("", imageKeys(builder.Stack.BuildImage)...)
+		if err != nil {
+			return fmt.Errorf("failed to read version hold for %s: %w", builder.Stack.BuildImage, err)
+		}
+
+		runImage, buildImage, err := internal.FindLatestStackImages(builder.Stack.RunImage, builder.Stack.BuildImage, internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(constraint), internal.WithConstraint(hold))
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, versionChange(flags.builderFile, "stack.build-image", "",
+			buildImage.Name, imageTag(builder.Stack.BuildImage), buildImage.Version, imageSource(builder.Stack.BuildImage)))
+
+		builder.Stack.BuildImage, err = imageReference(lookup, buildImage, flags.pinDigest)
 		if err != nil {
 			return err
 		}
 
-		builder.Stack.BuildImage = fmt.Sprintf("%s:%s", buildImage.Name, buildImage.Version)
 		if runImage != (internal.Image{}) {
-			builder.Stack.RunImage = fmt.Sprintf("%s:%s", runImage.Name, runImage.Version)
+			changes = append(changes, versionChange(flags.builderFile, "stack.run-image", "",
+				runImage.Name, imageTag(builder.Stack.RunImage), runImage.Version, imageSource(builder.Stack.RunImage)))
+
+			builder.Stack.RunImage, err = imageReference(lookup, runImage, flags.pinDigest)
+			if err != nil {
+				return err
+			}
+
 			updatedMirrors, err := internal.UpdateRunImageMirrors(runImage.Version, builder.Stack.RunImageMirrors)
 			if err != nil {
 				return err
@@ -153,10 +469,146 @@ func updateBuilderRun(flags updateBuilderFlags) error {
 		}
 	}
 
-	err = internal.OverwriteBuilderConfig(flags.builderFile, builder)
+	if flags.dryRun {
+		err = printBuilderDryRun(humanOutput(flags.report, flags.reportFile), flags.builderFile, builder, changes)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = internal.OverwriteBuilderConfig(flags.builderFile, builder, humanOutput(flags.report, flags.reportFile))
+		if err != nil {
+			return err
+		}
+	}
+
+	if flags.report != "" {
+		return writeReport(flags.report, flags.reportFile, internal.UpdateReport{
+			HighestBump: highestChangeBump(changes),
+			Changes:     changes,
+		})
+	}
+
+	return nil
+}
+
+// targetPlatforms returns the platforms that the given image version is
+// available for. Nothing is fetched when the builder has no targets.
+func targetPlatforms(lookup *internal.RegistryLookup, image internal.Image, platforms []internal.Platform) ([]internal.Platform, error) {
+	if len(platforms) == 0 {
+		return nil, nil
+	}
+
+	imagePlatforms, err := lookup.Platforms(fmt.Sprintf("%s:%s", image.Name, image.Version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the targets of %s:%s: %w", image.Name, image.Version, err)
+	}
+
+	return imagePlatforms, nil
+}
+
+// verifyBuilderTargets prints a matrix of the availability of the resolved
+// images for each builder target, and returns an error when any of them is
+// missing a target.
+func verifyBuilderTargets(w io.Writer, platforms []internal.Platform, resolved []resolvedImage) error {
+	if len(platforms) == 0 || len(resolved) == 0 {
+		return nil
+	}
+
+	var (
+		availability []internal.TargetAvailability
+		missing      []string
+	)
+	for _, r := range resolved {
+		a := internal.TargetAvailability{
+			ID:        r.ID,
+			Image:     r.Image.Name,
+			Version:   r.Image.Version,
+			Platforms: r.Platforms,
+		}
+		availability = append(availability, a)
+
+		for _, platform := range a.Missing(platforms) {
+			missing = append(missing, fmt.Sprintf("%s:%s is not available for %s", a.Image, a.Version, platform))
+		}
+	}
+
+	err := internal.PrintTargetMatrix(w, platforms, availability)
 	if err != nil {
 		return err
 	}
 
+	if len(missing) > 0 {
+		return fmt.Errorf("failed to verify builder targets (use --skip-missing-targets to update to the highest version that is available for every target): %s", strings.Join(missing, ", "))
+	}
+
 	return nil
 }
+
+const (
+	checkAPIsWarn = "warn"
+	checkAPIsFail = "fail"
+)
+
+const (
+	updateCategoryBuildpacks = "buildpacks"
+	updateCategoryExtensions = "extensions"
+	updateCategoryLifecycle  = "lifecycle"
+	updateCategoryImages     = "images"
+)
+
+func builderUpdatePolicy(flags updateBuilderFlags) (internal.UpdatePolicy, error) {
+	if flags.patchOnly && flags.minorOnly {
+		return internal.UpdatePolicy{}, fmt.Errorf("--patch-only and --minor-only cannot be used together")
+	}
+
+	defaultBump := flags.maxBump
+	if flags.patchOnly || flags.minorOnly {
+		if defaultBump != "" {
+			return internal.UpdatePolicy{}, fmt.Errorf("--max-bump cannot be used with --patch-only or --minor-only")
+		}
+
+		defaultBump = internal.BumpMinor
+		if flags.patchOnly {
+			defaultBump = internal.BumpPatch
+		}
+	}
+
+	categories := map[string]string{
+		updateCategoryBuildpacks: flags.buildpacksMaxBump,
+		updateCategoryExtensions: flags.extensionsMaxBump,
+		updateCategoryLifecycle:  flags.lifecycleMaxBump,
+		updateCategoryImages:     flags.imagesMaxBump,
+	}
+
+	for _, bump := range append([]string{defaultBump}, flags.buildpacksMaxBump, flags.extensionsMaxBump, flags.lifecycleMaxBump, flags.imagesMaxBump) {
+		err := internal.ValidateBump(bump)
+		if err != nil {
+			return internal.UpdatePolicy{}, err
+		}
+	}
+
+	overrides, err := internal.ParseBumpOverrides(flags.maxBumpOverrides)
+	if err != nil {
+		return internal.UpdatePolicy{}, err
+	}
+
+	return internal.UpdatePolicy{
+		Default:    defaultBump,
+		Categories: categories,
+		Overrides:  overrides,
+	}, nil
+}
+
+func printBuilderDryRun(w io.Writer, path string, builder internal.BuilderConfig, changes []internal.VersionChange) error {
+	content, err := internal.RenderBuilderConfig(path, builder, w)
+	if err != nil {
+		return err
+	}
+
+	err = printFileDiff(w, path, content)
+	if err != nil {
+		return err
+	}
+
+	return internal.PrintVersionChanges(w, changes)
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -14,9 +15,16 @@ type updateBuildpackFlags struct {
 	buildpackFile string
 	packageFile   string
 	api           string
+	registryIndex string
 
 	noCNBRegistry bool
 	patchOnly     bool
+	dryRun        bool
+	report        string
+	reportFile    string
+	pinDigest     bool
+	concurrency   int
+	channel       string
 }
 
 func updateBuildpack() *cobra.Command {
@@ -35,8 +43,15 @@ func updateBuildpack() *cobra.Command {
 	cmd.Flags().StringVar(&flags.buildpackFile, "buildpack-file", "", "path to the buildpack.toml file (required)")
 	cmd.Flags().StringVar(&flags.packageFile, "package-file", "", "path to the package.toml file (required)")
 	cmd.Flags().StringVar(&flags.api, "api", "https://registry.buildpacks.io/api/", "api for cnb registry (default: https://registry.buildpacks.io/api/)")
+	cmd.Flags().StringVar(&flags.registryIndex, "registry-index", "", "path to a local checkout of the cnb registry index to read buildpack versions from instead of the api (optional)")
 	cmd.Flags().BoolVar(&flags.noCNBRegistry, "no-cnb-registry", false, "when false updates dependencies to use cnb-registry uris (DEPRECATED and ignored)")
 	cmd.Flags().BoolVar(&flags.patchOnly, "patch-only", false, "allow patch changes ONLY to buildpack version bumps")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "print a diff of the changes and a table of the version updates without writing the buildpack.toml and package.toml files")
+	cmd.Flags().BoolVar(&flags.pinDigest, "pin-digest", false, "pin updated image dependencies in the package.toml to the digest of their tag (ex. <image>:1.2.3@sha256:<digest>), registry URIs are left unchanged")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", defaultConcurrency, "number of dependencies to look up in parallel")
+	cmd.Flags().StringVar(&flags.channel, "channel", internal.ChannelStable, "prerelease versions to consider: stable, rc, all or a regular expression that the prerelease component must match")
+	cmd.Flags().StringVar(&flags.report, "report", "", "write a machine-readable report of every change in the given format (supported: json)")
+	cmd.Flags().StringVar(&flags.reportFile, "report-file", "", "path to write the report to (default: stdout)")
 
 	err := cmd.MarkFlagRequired("buildpack-file")
 	if err != nil {
@@ -54,6 +69,21 @@ func init() {
 }
 
 func updateBuildpackRun(flags updateBuildpackFlags) error {
+	err := validateReportFormat(flags.report)
+	if err != nil {
+		return err
+	}
+
+	err = validateConcurrency(flags.concurrency)
+	if err != nil {
+		return err
+	}
+
+	channel, err := internal.ParseChannel(flags.channel)
+	if err != nil {
+		return err
+	}
+
 	bp, err := internal.ParseBuildpackConfig(flags.buildpackFile)
 	if err != nil {
 		return err
@@ -64,46 +94,102 @@ func updateBuildpackRun(flags updateBuildpackFlags) error {
 		return err
 	}
 
-	highestFoundSemverBump := "<none>"
-	for i, dependency := range pkg.Dependencies {
-		var (
-			buildpackageID string
-			image          internal.Image
-			err            error
-			oldVersion     string
-		)
+	var changes []internal.VersionChange
+
+	output := humanOutput(flags.report, flags.reportFile)
 
+	cnbRegistryOptions := []internal.FindOption{internal.WithChannel(channel), internal.WithRetryOutput(output)}
+	if flags.registryIndex != "" {
+		cnbRegistryOptions = append(cnbRegistryOptions, internal.WithRegistryIndex(flags.registryIndex))
+	}
+
+	lookup := internal.NewRegistryLookup(insecureRegistries(), output)
+
+	dependencies, err := resolveConcurrently(len(pkg.Dependencies), flags.concurrency, func(i int) (resolvedImage, error) {
+		dependency := pkg.Dependencies[i]
+
+		var oldVersion string
 		if strings.HasPrefix(dependency.URI, "urn:cnb:registry") {
 			if flags.patchOnly {
 				oldVersion = strings.Split(dependency.URI, "@")[1]
 			}
 
-			image, err = internal.FindLatestImageOnCNBRegistry(dependency.URI, flags.api, oldVersion)
+			hold, err := pkg.Metadata.Jam.HoldConstraint(dependency.Constraint, strings.TrimPrefix(strings.Split(dependency.URI, "@")[0], "urn:cnb:registry:"), dependency.URI)
 			if err != nil {
-				return err
+				return resolvedImage{}, fmt.Errorf("failed to read version hold for %s: %w", dependency.URI, err)
 			}
 
-			pkg.Dependencies[i].URI = fmt.Sprintf("%s@%s", image.Name, image.Version)
-			buildpackageID = image.Path
-
-		} else {
-			if flags.patchOnly {
-				oldVersionSlice := strings.Split(dependency.URI, ":")
-				oldVersion = oldVersionSlice[len(oldVersionSlice)-1]
+			image, err := internal.FindLatestImageOnCNBRegistry(dependency.URI, flags.api, oldVersion, append([]internal.FindOption{internal.WithConstraint(hold)}, cnbRegistryOptions...)...)
+			if err != nil {
+				return resolvedImage{}, err
 			}
 
-			image, err = internal.FindLatestImage(dependency.URI, oldVersion)
+			return resolvedImage{ID: image.Path, Image: image, URI: fmt.Sprintf("%s@%s", image.Name, image.Version)}, nil
+		}
+
+		if flags.patchOnly {
+			oldVersion = imageTag(dependency.URI)
+		}
+
+		// the buildpackage ID is only needed up front when it may select a version hold
+		var (
+			buildpackageID string
+			err            error
+		)
+		if len(pkg.Metadata.Jam.Constraints) > 0 {
+			buildpackageID, err = lookup.BuildpackageID(dependency.URI)
 			if err != nil {
-				return err
+				return resolvedImage{}, fmt.Errorf("failed to get buildpackage ID for %s: %w", dependency.URI, err)
 			}
+		}
+
+		hold, err := pkg.Metadata.Jam.HoldConstraint(dependency.Constraint, append([]string{buildpackageID}, imageKeys(dependency.URI)...)...)
+		if err != nil {
+			return resolvedImage{}, fmt.Errorf("failed to read version hold for %s: %w", dependency.URI, err)
+		}
 
-			pkg.Dependencies[i].URI = fmt.Sprintf("%s:%s", image.Name, image.Version)
-			buildpackageID, err = internal.GetBuildpackageID(dependency.URI)
+		image, err := internal.FindLatestImage(dependency.URI, oldVersion, internal.WithRegistryLookup(lookup), internal.WithChannel(channel), internal.WithConstraint(hold))
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		uri, err := imageReference(lookup, image, flags.pinDigest)
+		if err != nil {
+			return resolvedImage{}, err
+		}
+
+		if buildpackageID == "" {
+			buildpackageID, err = lookup.BuildpackageID(dependency.URI)
 			if err != nil {
-				return fmt.Errorf("failed to get buildpackage ID for %s: %w", dependency.URI, err)
+				return resolvedImage{}, fmt.Errorf("failed to get buildpackage ID for %s: %w", dependency.URI, err)
 			}
 		}
 
+		return resolvedImage{ID: buildpackageID, Image: image, URI: uri}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	highestFoundSemverBump := "<none>"
+	for i, dependency := range pkg.Dependencies {
+		var currentVersion, source string
+		if strings.HasPrefix(dependency.URI, "urn:cnb:registry") {
+			if _, version, found := strings.Cut(dependency.URI, "@"); found {
+				currentVersion = version
+			}
+			source = flags.api
+		} else {
+			currentVersion = imageTag(dependency.URI)
+			source = imageSource(dependency.URI)
+		}
+
+		buildpackageID, image := dependencies[i].ID, dependencies[i].Image
+		pkg.Dependencies[i].URI = dependencies[i].URI
+
+		changes = append(changes, versionChange(flags.packageFile, fmt.Sprintf("dependencies[%d].uri", i), buildpackageID,
+			image.Name, currentVersion, image.Version, source))
+
 		for j, order := range bp.Order {
 			for k, group := range order.Group {
 				if group.ID == buildpackageID {
@@ -113,25 +199,66 @@ func updateBuildpackRun(flags updateBuildpackFlags) error {
 					}
 					highestFoundSemverBump = highestSemverBump(highestFoundSemverBump, bump)
 
+					changes = append(changes, versionChange(flags.buildpackFile, fmt.Sprintf("order[%d].group[%d].version", j, k), buildpackageID,
+						image.Name, group.Version, image.Version, source))
+
 					bp.Order[j].Group[k].Version = image.Version
 				}
 			}
 		}
 	}
 
-	err = internal.OverwriteBuildpackConfig(flags.buildpackFile, bp)
+	if flags.dryRun {
+		err = printBuildpackDryRun(output, flags, bp, pkg, changes)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = internal.OverwriteBuildpackConfig(flags.buildpackFile, bp, output)
+		if err != nil {
+			return err
+		}
+
+		err = internal.OverwritePackageConfig(flags.packageFile, pkg, output)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(output, "Highest semver bump: %s\n", highestFoundSemverBump)
+
+	if flags.report != "" {
+		return writeReport(flags.report, flags.reportFile, internal.UpdateReport{
+			HighestBump: highestFoundSemverBump,
+			Changes:     changes,
+		})
+	}
+
+	return nil
+}
+
+func printBuildpackDryRun(w io.Writer, flags updateBuildpackFlags, bp internal.BuildpackConfig, pkg internal.PackageConfig, changes []internal.VersionChange) error {
+	buildpackContent, err := internal.RenderBuildpackConfig(flags.buildpackFile, bp, w)
 	if err != nil {
 		return err
 	}
 
-	err = internal.OverwritePackageConfig(flags.packageFile, pkg)
+	err = printFileDiff(w, flags.buildpackFile, buildpackContent)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Highest semver bump: %s\n", highestFoundSemverBump)
+	packageContent, err := internal.RenderPackageConfig(flags.packageFile, pkg, w)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	err = printFileDiff(w, flags.packageFile, packageContent)
+	if err != nil {
+		return err
+	}
+
+	return internal.PrintVersionChanges(w, changes)
 }
 
 func semverBump(oldVersion, newVersion string) (string, error) {
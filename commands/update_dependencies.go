@@ -1,41 +1,67 @@
 package commands
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/jam/v2/internal"
 	"github.com/paketo-buildpacks/packit/v2/cargo"
 	"github.com/spf13/cobra"
 )
 
 type updateDependenciesFlags struct {
-	buildpackFile string
-	metadataFile  string
+	buildpackFile  string
+	extensionFile  string
+	metadataFiles  []string
+	metadataURL    string
+	verify         bool
+	strict         bool
+	perTarget      bool
+	dropDeprecated bool
+	graceDays      int
+	eolCalendar    string
+	templates      string
+	unconstrained  string
+	defaults       string
+	report         string
+	reportFile     string
 }
 
 func updateDependencies() *cobra.Command {
 	flags := &updateDependenciesFlags{}
 	cmd := &cobra.Command{
 		Use:   "update-dependencies",
-		Short: "updates all depdendencies in a buildpack.toml from a metadata JSON file.",
+		Short: "updates all depdendencies in a buildpack.toml or extension.toml from a metadata JSON file.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return updateDependenciesRun(*flags)
 		},
 	}
-	cmd.Flags().StringVar(&flags.buildpackFile, "buildpack-file", "", "path to the buildpack.toml file (required)")
-	cmd.Flags().StringVar(&flags.metadataFile, "metadata-file", "", "metadata.json file with all entries to be added to the buildpack.toml (required)")
+	cmd.Flags().StringVar(&flags.buildpackFile, "buildpack-file", "", "path to the buildpack.toml file (required unless --extension-file is set)")
+	cmd.Flags().StringVar(&flags.extensionFile, "extension-file", "", "path to the extension.toml file (required unless --buildpack-file is set)")
+	cmd.Flags().StringArrayVar(&flags.metadataFiles, "metadata-file", nil, "metadata file with entries to be added to the buildpack.toml, in JSON, YAML or TOML (see jam schema metadata), a directory of them or a glob pattern; can be given multiple times (required)")
+	cmd.Flags().StringVar(&flags.metadataURL, "metadata-url", "", "base URL of a dep-server compatible API to query for the new versions of every constrained dependency")
+	cmd.Flags().BoolVar(&flags.verify, "verify-checksums", false, "download the artifacts of the new dependencies and verify their checksums before writing the buildpack.toml")
+	cmd.Flags().BoolVar(&flags.strict, "strict", false, "fail on metadata warnings, ex. missing checksums, purls or cpes, as well as on errors")
+	cmd.Flags().BoolVar(&flags.perTarget, "per-target-patches", false, "count the patches of each dependency-constraint per os/arch target, so that every target keeps its newest available versions, and warn about targets with fewer versions available")
+	cmd.Flags().BoolVar(&flags.dropDeprecated, "drop-deprecated", false, "exclude dependencies whose deprecation_date has passed before applying the dependency-constraints")
+	cmd.Flags().IntVar(&flags.graceDays, "deprecation-grace-days", 0, "warn about dependencies that are deprecated or will be deprecated within the given number of days")
+	cmd.Flags().StringVar(&flags.eolCalendar, "eol-calendar", "", "JSON file of deprecation dates keyed by dependency ID and version line, ex. {\"node\": {\"18\": \"2025-04-30\"}}, used to fill in missing deprecation_date fields")
+	cmd.Flags().StringVar(&flags.templates, "enrichment-templates", "", "JSON file of purl and cpes templates keyed by dependency ID, used to fill in missing purl and cpes fields, see enrich-dependencies")
+	cmd.Flags().StringVar(&flags.unconstrained, "unconstrained", internal.UnconstrainedKeep, "what to do with dependencies not covered by any dependency-constraint (supported: keep, drop, error)")
+	cmd.Flags().StringVar(&flags.defaults, "default-versions", internal.DefaultVersionsKeep, "what to do with metadata.default-versions: keep them, update exact versions to the highest version within their dependency-constraint, or error when one matches no dependency version (supported: keep, update, error)")
+	cmd.Flags().StringVar(&flags.report, "report", "", "write a machine-readable report of every change in the given format (supported: json)")
+	cmd.Flags().StringVar(&flags.reportFile, "report-file", "", "path to write the report to (default: stdout)")
 
-	err := cmd.MarkFlagRequired("buildpack-file")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to mark buildpack-file flag as required")
-	}
-	err = cmd.MarkFlagRequired("metadata-file")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to mark metadata-file flag as required")
-	}
+	cmd.MarkFlagsOneRequired("buildpack-file", "extension-file")
+	cmd.MarkFlagsMutuallyExclusive("buildpack-file", "extension-file")
+	cmd.MarkFlagsOneRequired("metadata-file", "metadata-url")
 	return cmd
 }
 
@@ -44,10 +70,30 @@ func init() {
 }
 
 func updateDependenciesRun(flags updateDependenciesFlags) error {
-	configParser := cargo.NewBuildpackParser()
-	config, err := configParser.Parse(flags.buildpackFile)
+	err := validateReportFormat(flags.report)
+	if err != nil {
+		return err
+	}
+
+	err = internal.ValidateUnconstrainedPolicy(flags.unconstrained)
+	if err != nil {
+		return err
+	}
+
+	err = internal.ValidateDefaultVersionsPolicy(flags.defaults)
 	if err != nil {
-		return fmt.Errorf("failed to parse buildpack.toml: %s", err)
+		return err
+	}
+
+	kind, configFile, config, err := readDependencyConfig(flags.buildpackFile, flags.extensionFile)
+	if err != nil {
+		return err
+	}
+
+	// cargo does not model cpes, so they are read and written separately
+	explicitCPEs, err := internal.ReadDependencyCPEs(configFile)
+	if err != nil {
+		return err
 	}
 
 	originalVersions := map[string]string{}
@@ -57,39 +103,183 @@ func updateDependenciesRun(flags updateDependenciesFlags) error {
 
 	var matchingDependencies []cargo.ConfigMetadataDependency
 
-	metadataFile, err := os.Open(flags.metadataFile)
+	metadataFiles, err := internal.ReadMetadataFiles(flags.metadataFiles)
 	if err != nil {
-		return fmt.Errorf("failed to open metadata.json file: %w", err)
+		return err
 	}
 
-	newVersions := []cargo.ConfigMetadataDependency{}
-	err = json.NewDecoder(metadataFile).Decode(&newVersions)
+	if flags.metadataURL != "" {
+		apiFiles, err := dependencyAPIMetadata(flags.metadataURL, config)
+		if err != nil {
+			return err
+		}
+		metadataFiles = append(metadataFiles, apiFiles...)
+	}
+
+	var templates internal.EnrichmentTemplates
+	if flags.templates != "" {
+		templates, err = internal.ReadEnrichmentTemplates(flags.templates)
+		if err != nil {
+			return err
+		}
+	}
+
+	output := humanOutput(flags.report, flags.reportFile)
+
+	err = validateMetadataFiles(output, metadataFiles, config.Metadata.DependencyConstraints, config.Stacks, templates, flags.strict)
 	if err != nil {
-		return fmt.Errorf("failed decode metadata.json: %w", err)
+		return err
 	}
-	err = metadataFile.Close()
+
+	// the pipelines produce a metadata file per dependency and target, which
+	// can overlap
+	newVersions, err := internal.MergeMetadataFiles(metadataFiles)
 	if err != nil {
-		//untested
-		return fmt.Errorf("failed close metadata.json: %w", err)
+		return err
+	}
+
+	// the cpes of the new versions are written along with them, without
+	// overwriting those of the buildpack.toml
+	for _, file := range metadataFiles {
+		explicitCPEs.Merge(file.CPEs)
+	}
+
+	// only the versions within the constraints can be written, the others are
+	// neither validated nor selected
+	newVersions = internal.DependenciesWithinConstraints(newVersions, config.Metadata.DependencyConstraints)
+
+	var populated, deprecated []cargo.ConfigMetadataDependency
+	if flags.eolCalendar != "" {
+		calendar, err := internal.ReadEOLCalendar(flags.eolCalendar)
+		if err != nil {
+			return err
+		}
+
+		var populatedNew []cargo.ConfigMetadataDependency
+		config.Metadata.Dependencies, populated = internal.ApplyEOLCalendar(config.Metadata.Dependencies, calendar)
+		newVersions, populatedNew = internal.ApplyEOLCalendar(newVersions, calendar)
+		populated = append(populated, populatedNew...)
+	}
+
+	now := time.Now()
+	if flags.dropDeprecated {
+		config.Metadata.Dependencies, deprecated = internal.SplitDeprecatedDependencies(config.Metadata.Dependencies, now)
+		newVersions, _ = internal.SplitDeprecatedDependencies(newVersions, now)
 	}
 
 	// combine buildpack.toml versions and new versions
+	originalDependencies := config.Metadata.Dependencies
 	allDependencies := append(config.Metadata.Dependencies, newVersions...)
 
+	var (
+		changes    []internal.VersionChange
+		shortfalls []internal.TargetShortfall
+	)
 	for _, constraint := range config.Metadata.DependencyConstraints {
 		// Filter allDependencies for only those that match the constraint
 		// mds is a just the right number of deps for the constraint
-		mds, err := internal.GetCargoDependenciesWithinConstraint(allDependencies, constraint)
+		var mds []cargo.ConfigMetadataDependency
+		if flags.perTarget {
+			var short []internal.TargetShortfall
+			mds, short, err = internal.GetCargoDependenciesWithinConstraintPerTarget(allDependencies, constraint)
+			shortfalls = append(shortfalls, short...)
+		} else {
+			mds, err = internal.GetCargoDependenciesWithinConstraint(allDependencies, constraint)
+		}
 		if err != nil {
 			return err
 		}
 
+		oldVersion := highestDependencyVersion(originalDependencies, constraint)
+		reported := map[string]bool{}
+		for k, d := range mds {
+			if reported[d.Version] || containsDependencyVersion(originalDependencies, d.ID, d.Version) {
+				continue
+			}
+			reported[d.Version] = true
+
+			changes = append(changes, versionChange(configFile, fmt.Sprintf("metadata.dependencies[%d]", len(matchingDependencies)+k), d.ID,
+				"", oldVersion, d.Version, internal.MetadataSource(metadataFiles, d.ID, d.Version)))
+		}
+
 		matchingDependencies = append(matchingDependencies, mds...)
 		if len(matchingDependencies) > 0 {
 			config.Metadata.Dependencies = matchingDependencies
 		}
 	}
 
+	// the constraints only select versions of the dependencies they name, so
+	// the other dependencies are handled by the unconstrained policy
+	_, unconstrained := internal.SplitConstrainedDependencies(originalDependencies, config.Metadata.DependencyConstraints)
+	if len(unconstrained) > 0 {
+		switch flags.unconstrained {
+		case internal.UnconstrainedError:
+			return fmt.Errorf("found dependencies not covered by any dependency-constraint: %s", describeDependencies(unconstrained))
+		case internal.UnconstrainedDrop:
+			config.Metadata.Dependencies, _ = internal.SplitConstrainedDependencies(config.Metadata.Dependencies, config.Metadata.DependencyConstraints)
+		default:
+			if len(matchingDependencies) > 0 {
+				config.Metadata.Dependencies = append(config.Metadata.Dependencies, unconstrained...)
+			}
+		}
+	}
+
+	// rolling the patch windows forward can remove the default versions
+	var defaultMessages, dangling []string
+	for _, update := range internal.UpdateDefaultVersions(config.Metadata.DefaultVersions, config.Metadata.Dependencies, config.Metadata.DependencyConstraints, flags.defaults == internal.DefaultVersionsUpdate) {
+		if update.NewVersion != "" {
+			config.Metadata.DefaultVersions[update.ID] = update.NewVersion
+			defaultMessages = append(defaultMessages, fmt.Sprintf("Updating default version of %s: %s -> %s", update.ID, update.OldVersion, update.NewVersion))
+			changes = append(changes, versionChange(configFile, fmt.Sprintf("metadata.default-versions.%s", update.ID), update.ID, "", update.OldVersion, update.NewVersion, ""))
+		}
+
+		if update.Dangling {
+			version := update.OldVersion
+			if update.NewVersion != "" {
+				version = update.NewVersion
+			}
+			dangling = append(dangling, fmt.Sprintf("%s %s", update.ID, version))
+		}
+	}
+
+	if len(dangling) > 0 {
+		if flags.defaults != internal.DefaultVersionsKeep {
+			return fmt.Errorf("found default versions that match no dependency version: %s", strings.Join(dangling, ", "))
+		}
+
+		for _, d := range dangling {
+			defaultMessages = append(defaultMessages, fmt.Sprintf("Warning: default version %s matches no dependency version", d))
+		}
+	}
+
+	// only the deprecation dates of the dependencies that are written are
+	// reported
+	populated = slices.DeleteFunc(populated, func(d cargo.ConfigMetadataDependency) bool {
+		return !slices.ContainsFunc(config.Metadata.Dependencies, func(c cargo.ConfigMetadataDependency) bool { return reflect.DeepEqual(c, d) })
+	})
+
+	if flags.verify {
+		verifier := internal.NewDependencyVerifier(cargo.NewTransport())
+		for _, d := range config.Metadata.Dependencies {
+			if slices.ContainsFunc(originalDependencies, func(o cargo.ConfigMetadataDependency) bool { return reflect.DeepEqual(o, d) }) {
+				continue
+			}
+
+			err = verifier.Verify(d)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var enrichments []internal.DependencyEnrichment
+	if flags.templates != "" {
+		config.Metadata.Dependencies, enrichments, err = internal.EnrichDependencies(config.Metadata.Dependencies, templates, explicitCPEs)
+		if err != nil {
+			return err
+		}
+	}
+
 	newVersionsFound := map[string]string{}
 	for _, d := range config.Metadata.Dependencies {
 		if _, ok := originalVersions[d.Version]; !ok {
@@ -97,9 +287,118 @@ func updateDependenciesRun(flags updateDependenciesFlags) error {
 		}
 	}
 
-	file, err := os.OpenFile(flags.buildpackFile, os.O_RDWR|os.O_TRUNC, 0600)
+	err = writeDependencyConfig(kind, configFile, config, internal.IndexDependencyCPEs(config.Metadata.Dependencies, explicitCPEs, enrichments), output)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "Updating %s.toml with new versions:  %v\n", kind, reflect.ValueOf(newVersionsFound).MapKeys())
+	for _, message := range defaultMessages {
+		fmt.Fprintln(output, message)
+	}
+
+	printEnrichments(output, config.Metadata.Dependencies, enrichments)
+
+	for _, d := range populated {
+		fmt.Fprintf(output, "Setting deprecation date of %s to %s\n", internal.DescribeDependency(d), d.DeprecationDate.Format(time.DateOnly))
+	}
+
+	if len(deprecated) > 0 {
+		fmt.Fprintln(output, "Dropped deprecated dependencies:")
+		for _, d := range deprecated {
+			fmt.Fprintf(output, "  %s (deprecated on %s)\n", internal.DescribeDependency(d), d.DeprecationDate.Format(time.DateOnly))
+		}
+	}
+
+	if flags.graceDays > 0 {
+		for _, d := range internal.DeprecatedWithin(config.Metadata.Dependencies, now, time.Duration(flags.graceDays)*24*time.Hour) {
+			tense := "will be"
+			if !d.DeprecationDate.After(now) {
+				tense = "was"
+			}
+			fmt.Fprintf(output, "Warning: %s %s deprecated on %s\n", internal.DescribeDependency(d), tense, d.DeprecationDate.Format(time.DateOnly))
+		}
+	}
+
+	for _, shortfall := range shortfalls {
+		fmt.Fprintf(output, "Warning: %s\n", shortfall)
+	}
+
+	if flags.unconstrained == internal.UnconstrainedDrop && len(unconstrained) > 0 {
+		fmt.Fprintln(output, "Dropped dependencies not covered by any dependency-constraint:")
+		for _, d := range unconstrained {
+			fmt.Fprintf(output, "  %s\n", internal.DescribeDependency(d))
+		}
+	}
+
+	if flags.report != "" {
+		err = writeReport(flags.report, flags.reportFile, internal.UpdateReport{
+			HighestBump: highestChangeBump(changes),
+			Changes:     changes,
+			Shortfalls:  shortfalls,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readDependencyConfig parses the buildpack.toml or, when extensionFile is
+// set, the extension.toml. Extensions only differ from buildpacks in how their
+// config is read and written, so only the metadata of an extension is parsed.
+func readDependencyConfig(buildpackFile, extensionFile string) (string, string, cargo.Config, error) {
+	kind, configFile := "buildpack", buildpackFile
+	var (
+		config cargo.Config
+		err    error
+	)
+	if extensionFile != "" {
+		kind, configFile = "extension", extensionFile
+		config.Metadata, err = internal.ParseExtensionMetadata(configFile)
+	} else {
+		config, err = cargo.NewBuildpackParser().Parse(configFile)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to open buildpack config file: %w", err)
+		return "", "", cargo.Config{}, fmt.Errorf("failed to parse %s.toml: %s", kind, err)
+	}
+
+	return kind, configFile, config, nil
+}
+
+// writeDependencyConfig writes the config, with the given `cpes` of its
+// dependencies, to the buildpack.toml or extension.toml, keeping the layout
+// of the unchanged lines.
+func writeDependencyConfig(kind, configFile string, config cargo.Config, cpes map[int][]string, output io.Writer) (err error) {
+	var content []byte
+	if kind == "extension" {
+		content, err = internal.RenderExtensionMetadata(configFile, config.Metadata, cpes, output)
+		if err != nil {
+			return err
+		}
+	} else {
+		buffer := bytes.NewBuffer(nil)
+		err = cargo.EncodeConfig(buffer, config)
+		if err != nil {
+			return fmt.Errorf("failed to write buildpack config: %w", err)
+		}
+
+		content, err = internal.SetDependencyCPEs(buffer.Bytes(), cpes)
+		if err != nil {
+			return err
+		}
+
+		content = internal.PreserveTOMLLayout(configFile, content, output, func(content []byte) (interface{}, error) {
+			var config cargo.Config
+			err := cargo.DecodeConfig(bytes.NewReader(content), &config)
+			return config, err
+		})
+	}
+
+	file, err := os.OpenFile(configFile, os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s config file: %w", kind, err)
 	}
 	defer func() {
 		if err2 := file.Close(); err2 != nil && err == nil {
@@ -107,12 +406,130 @@ func updateDependenciesRun(flags updateDependenciesFlags) error {
 		}
 	}()
 
-	err = cargo.EncodeConfig(file, config)
+	_, err = file.Write(content)
 	if err != nil {
-		return fmt.Errorf("failed to write buildpack config: %w", err)
+		return fmt.Errorf("failed to write %s config: %w", kind, err)
 	}
 
-	fmt.Println("Updating buildpack.toml with new versions: ", reflect.ValueOf(newVersionsFound).MapKeys())
-
 	return err // err should be nil here, but return err to catch deferred error
 }
+
+// printEnrichments prints the identifiers that were computed for the
+// dependencies.
+func printEnrichments(w io.Writer, dependencies []cargo.ConfigMetadataDependency, enrichments []internal.DependencyEnrichment) {
+	for _, enrichment := range enrichments {
+		description := internal.DescribeDependency(dependencies[enrichment.Index])
+		if enrichment.PURL != "" {
+			fmt.Fprintf(w, "Computed purl of %s: %s\n", description, enrichment.PURL)
+		}
+
+		if len(enrichment.CPEs) > 0 {
+			fmt.Fprintf(w, "Computed cpes of %s: %s\n", description, strings.Join(enrichment.CPEs, ", "))
+		}
+	}
+}
+
+// validateMetadataFiles prints the warnings of the dependency metadata and
+// returns an error that lists every other problem. In strict mode, warnings
+// are problems too. Only the entries within the constraints, or without an
+// id, are validated, as the others are never written, and the purls and cpes
+// that the enrichment templates compute are not reported missing.
+func validateMetadataFiles(w io.Writer, files []internal.MetadataFile, constraints []cargo.ConfigMetadataDependencyConstraint, stacks []cargo.ConfigStack, templates internal.EnrichmentTemplates, strict bool) error {
+	var problems []string
+	for _, file := range files {
+		dependencies := internal.DependenciesWithinConstraints(file.Dependencies, constraints)
+		for _, problem := range internal.ValidateMetadataDependencies(dependencies, stacks, file.CPEs, templates) {
+			if problem.Warning && !strict {
+				fmt.Fprintf(w, "Warning: %s: %s\n", file.Path, problem)
+				continue
+			}
+
+			problems = append(problems, fmt.Sprintf("%s: %s", file.Path, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid dependency metadata:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// dependencyAPIMetadata queries the dependency API for the versions of every
+// dependency that has a constraint, and returns those within the constraints
+// as a metadata file per dependency.
+func dependencyAPIMetadata(api string, config cargo.Config) ([]internal.MetadataFile, error) {
+	var files []internal.MetadataFile
+	queried := map[string][]internal.Dependency{}
+	for _, constraint := range config.Metadata.DependencyConstraints {
+		dependencies, ok := queried[constraint.ID]
+		if !ok {
+			var err error
+			dependencies, err = internal.GetAllDependencies(api, constraint.ID)
+			if err != nil {
+				return nil, err
+			}
+			queried[constraint.ID] = dependencies
+		}
+
+		matching, err := internal.GetDependenciesWithinConstraint(dependencies, constraint, internal.FindDependencyName(constraint.ID, config))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply constraint %s for %s: %w", constraint.Constraint, constraint.ID, err)
+		}
+
+		files = append(files, internal.MetadataFile{
+			Path:         internal.DependencyAPIURL(api, constraint.ID),
+			Dependencies: matching,
+		})
+	}
+
+	return files, nil
+}
+
+// highestDependencyVersion returns the highest version of the dependencies
+// matching the constraint, or an empty string if there are none.
+func highestDependencyVersion(dependencies []cargo.ConfigMetadataDependency, constraint cargo.ConfigMetadataDependencyConstraint) string {
+	c, err := semver.NewConstraint(constraint.Constraint)
+	if err != nil {
+		return ""
+	}
+
+	var highest *semver.Version
+	for _, d := range dependencies {
+		version, err := semver.NewVersion(d.Version)
+		if err != nil || d.ID != constraint.ID || !c.Check(version) {
+			continue
+		}
+
+		if highest == nil || version.GreaterThan(highest) {
+			highest = version
+		}
+	}
+
+	if highest == nil {
+		return ""
+	}
+
+	return highest.Original()
+}
+
+// describeDependencies returns a comma-separated list of the descriptions of
+// the dependencies.
+func describeDependencies(dependencies []cargo.ConfigMetadataDependency) string {
+	var descriptions []string
+	for _, d := range dependencies {
+		descriptions = append(descriptions, internal.DescribeDependency(d))
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+func containsDependencyVersion(dependencies []cargo.ConfigMetadataDependency, id, version string) bool {
+	for _, d := range dependencies {
+		if d.ID == id && d.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultConcurrency is the default number of images that are looked up in
+// parallel by the update commands.
+const defaultConcurrency = 8
+
+// resolvedImage is the latest allowed version of a buildpack, extension or
+// dependency image, along with the reference that should be written for it.
+type resolvedImage struct {
+	ID        string
+	Image     internal.Image
+	URI       string
+	Platforms []internal.Platform
+}
+
+// resolveConcurrently calls resolve for each index in [0, n) with at most
+// concurrency calls in flight, and returns the results in index order.
+func resolveConcurrently(n, concurrency int, resolve func(i int) (resolvedImage, error)) ([]resolvedImage, error) {
+	resolved := make([]resolvedImage, n)
+
+	var group errgroup.Group
+	group.SetLimit(concurrency)
+	for i := range n {
+		group.Go(func() error {
+			var err error
+			resolved[i], err = resolve(i)
+			return err
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+func validateConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d: must be at least 1", concurrency)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/distribution/reference"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+)
+
+// printFileDiff prints a unified diff between the current contents of the
+// file at path and the given content.
+func printFileDiff(w io.Writer, path string, content []byte) error {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	diff := internal.UnifiedDiff(fmt.Sprintf("a/%s", path), fmt.Sprintf("b/%s", path), original, content)
+	if diff == "" {
+		_, err = fmt.Fprintf(w, "No changes to %s\n", path)
+		return err
+	}
+
+	_, err = fmt.Fprint(w, diff)
+	return err
+}
+
+// imageTag returns the tag of the given image URI, or an empty string if the
+// URI is not tagged.
+func imageTag(uri string) string {
+	_, tag, err := internal.ParseImageURI(uri)
+	if err != nil {
+		return ""
+	}
+
+	return tag
+}
+
+// imageReference returns the reference to write for the given image version,
+// pinned to the digest of its tag when requested.
+func imageReference(lookup *internal.RegistryLookup, image internal.Image, pinDigest bool) (string, error) {
+	if pinDigest {
+		return lookup.PinnedImageReference(image)
+	}
+
+	return fmt.Sprintf("%s:%s", image.Name, image.Version), nil
+}
+
+// imageSource returns the registry domain of the given image URI.
+func imageSource(uri string) string {
+	named, err := reference.ParseNormalizedNamed(uri)
+	if err != nil {
+		return ""
+	}
+
+	return reference.Domain(named)
+}
+
+// imageKeys returns the names by which an image may be referred to in a
+// per-entry override: the fully qualified name, the familiar name and the
+// repository path.
+func imageKeys(uri string) []string {
+	named, err := reference.ParseNormalizedNamed(uri)
+	if err != nil {
+		return nil
+	}
+
+	return []string{named.Name(), reference.FamiliarName(named), reference.Path(named)}
+}
+
+// versionChange builds a VersionChange, filling in the bump type when both
+// versions are semantic versions.
+func versionChange(file, path, id, image, oldVersion, newVersion, source string) internal.VersionChange {
+	bump, err := semverBump(oldVersion, newVersion)
+	if err != nil || bump == "<none>" {
+		bump = ""
+	}
+
+	return internal.VersionChange{
+		File:       file,
+		Path:       path,
+		ID:         id,
+		Image:      image,
+		OldVersion: oldVersion,
+		NewVersion: newVersion,
+		Bump:       bump,
+		Source:     source,
+	}
+}
+
+// highestChangeBump returns the highest semver bump across all changes.
+func highestChangeBump(changes []internal.VersionChange) string {
+	highest := "<none>"
+	for _, change := range changes {
+		if change.Bump != "" {
+			highest = highestSemverBump(highest, change.Bump)
+		}
+	}
+
+	return highest
+}
+
+// validateReportFormat returns an error when the --report flag is set to an
+// unsupported format.
+func validateReportFormat(format string) error {
+	if format != "" && format != internal.ReportFormatJSON {
+		return fmt.Errorf("unsupported report format %q: must be %q", format, internal.ReportFormatJSON)
+	}
+
+	return nil
+}
+
+// humanOutput returns the writer for human-readable output. When a report is
+// being written to stdout, everything else is written to stderr so that the
+// report can be parsed.
+func humanOutput(reportFormat, reportFile string) io.Writer {
+	if reportFormat != "" && reportFile == "" {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+// writeReport writes the update report to the given file, or to stdout when
+// no file is given.
+func writeReport(format, file string, report internal.UpdateReport) (err error) {
+	if file == "" {
+		return internal.WriteUpdateReport(os.Stdout, format, report)
+	}
+
+	output, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer func() {
+		if err2 := output.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	err = internal.WriteUpdateReport(output, format, report)
+	if err != nil {
+		return err
+	}
+
+	return err // err should be nil here, but return err to catch deferred error
+}
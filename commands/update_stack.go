@@ -0,0 +1,246 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/jam/v2/internal/ihop"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(updateStack())
+}
+
+type updateStackFlags struct {
+	config     string
+	dryRun     bool
+	report     string
+	reportFile string
+}
+
+func updateStack() *cobra.Command {
+	flags := &updateStackFlags{}
+	cmd := &cobra.Command{
+		Use:   "update-stack",
+		Short: "update the base image digests of a stack",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return updateStackRun(*flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.config, "config", "", "path to a stack descriptor file (required)")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "print a diff of the changes and a table of the digest updates without writing the Dockerfiles and stack descriptor")
+	cmd.Flags().StringVar(&flags.report, "report", "", "write a machine-readable report of every change in the given format (supported: json)")
+	cmd.Flags().StringVar(&flags.reportFile, "report-file", "", "path to write the report to (default: stdout)")
+
+	err := cmd.MarkFlagRequired("config")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to mark config flag as required")
+	}
+
+	return cmd
+}
+
+func updateStackRun(flags updateStackFlags) error {
+	err := validateReportFormat(flags.report)
+	if err != nil {
+		return err
+	}
+
+	definition, err := ihop.NewDefinitionFromFile(flags.config)
+	if err != nil {
+		return err
+	}
+
+	config, err := os.ReadFile(flags.config)
+	if err != nil {
+		return fmt.Errorf("failed to read stack descriptor: %w", err)
+	}
+	updatedConfig := config
+
+	lookup := internal.NewRegistryLookup(insecureRegistries(), humanOutput(flags.report, flags.reportFile))
+
+	var (
+		changes     []internal.VersionChange
+		dockerfiles []string
+		updated     = map[string][]byte{}
+	)
+
+	for _, stackImage := range []struct {
+		key   string
+		image ihop.DefinitionImage
+	}{
+		{key: "build", image: definition.Build},
+		{key: "run", image: definition.Run},
+	} {
+		dockerfile := stackImage.image.Dockerfile
+		content, ok := updated[dockerfile]
+		if !ok {
+			content, err = os.ReadFile(dockerfile)
+			if err != nil {
+				return fmt.Errorf("failed to read Dockerfile: %w", err)
+			}
+			dockerfiles = append(dockerfiles, dockerfile)
+		}
+
+		var pins []internal.DockerfilePin
+		references := map[string]string{}
+
+		// the base images can differ per platform through the platform build arguments
+		for _, platform := range definition.Platforms {
+			args, err := stackImage.image.Arguments(platform)
+			if err != nil {
+				return err
+			}
+
+			platformPins, err := internal.FindDockerfilePins(content, args)
+			if err != nil {
+				return fmt.Errorf("failed to read base images of %s: %w", dockerfile, err)
+			}
+
+			for _, pin := range platformPins {
+				newReference, err := latestStackPin(lookup, pin, platform)
+				if err != nil {
+					return err
+				}
+				references[pin.Reference] = newReference
+
+				change := stackPinChange(flags.config, stackImage.key, stackImage.image, dockerfile, platform, pin, newReference)
+				if !slices.Contains(changes, change) {
+					changes = append(changes, change)
+				}
+
+				if pin.BuildArg {
+					updatedConfig = replaceQuoted(updatedConfig, pin.Reference, newReference)
+				} else {
+					pins = append(pins, pin)
+				}
+			}
+		}
+
+		updated[dockerfile], err = internal.RewriteDockerfilePins(content, pins, references)
+		if err != nil {
+			return fmt.Errorf("failed to update base images of %s: %w", dockerfile, err)
+		}
+	}
+
+	output := humanOutput(flags.report, flags.reportFile)
+
+	for _, dockerfile := range dockerfiles {
+		err = writeStackFile(output, flags.dryRun, dockerfile, updated[dockerfile])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeStackFile(output, flags.dryRun, flags.config, updatedConfig)
+	if err != nil {
+		return err
+	}
+
+	err = internal.PrintVersionChanges(output, changes)
+	if err != nil {
+		return err
+	}
+
+	if flags.report != "" {
+		return writeReport(flags.report, flags.reportFile, internal.UpdateReport{Changes: changes})
+	}
+
+	return nil
+}
+
+// latestStackPin returns the reference of the image pinned to the current
+// digest of its tag. Unless the stage is built for another platform,
+// the image must be available for the given platform of the stack.
+func latestStackPin(lookup *internal.RegistryLookup, pin internal.DockerfilePin, platform string) (string, error) {
+	digest, err := lookup.Digest(pin.Image)
+	if err != nil {
+		return "", fmt.Errorf("failed to get digest of %s: %w", pin.Image, err)
+	}
+
+	if pin.Platform == "" {
+		target, err := internal.ParsePlatform(platform)
+		if err != nil {
+			return "", err
+		}
+
+		platforms, err := lookup.Platforms(pin.Image)
+		if err != nil {
+			return "", err
+		}
+
+		if !slices.Contains(platforms, target) {
+			return "", fmt.Errorf("%s is not available for %s", pin.Image, target)
+		}
+	}
+
+	return internal.PinReference(pin.Image, digest), nil
+}
+
+// stackPinChange records the change of the pinned digest of a base image,
+// located in the Dockerfile or, for build arguments, in the stack descriptor.
+func stackPinChange(config, key string, image ihop.DefinitionImage, dockerfile, platform string, pin internal.DockerfilePin, newReference string) internal.VersionChange {
+	change := internal.VersionChange{
+		File:       dockerfile,
+		Image:      pin.Image,
+		OldVersion: pin.Digest,
+		NewVersion: strings.TrimPrefix(newReference, pin.Image+"@"),
+		Source:     imageSource(pin.Image),
+	}
+
+	switch {
+	case pin.BuildArg:
+		change.File = config
+		change.Path = fmt.Sprintf("%s.args.%s", key, pin.Arg)
+		if _, ok := image.Platforms[platform].Args[pin.Arg]; ok {
+			change.Path = fmt.Sprintf("%s.platforms.%q.args.%s", key, platform, pin.Arg)
+		}
+	case pin.Arg != "":
+		change.Path = fmt.Sprintf("ARG %s (line %d)", pin.Arg, pin.StartLine)
+	default:
+		change.Path = fmt.Sprintf("FROM (line %d)", pin.StartLine)
+	}
+
+	return change
+}
+
+// replaceQuoted replaces the quoted occurrences of the old value in the given
+// TOML content.
+func replaceQuoted(content []byte, oldValue, newValue string) []byte {
+	for _, quote := range []string{`"`, `'`} {
+		content = bytes.ReplaceAll(content, []byte(quote+oldValue+quote), []byte(quote+newValue+quote))
+	}
+
+	return content
+}
+
+// writeStackFile writes the updated content of the file, or prints a diff of
+// the changes when it is a dry run.
+func writeStackFile(w io.Writer, dryRun bool, path string, content []byte) error {
+	if dryRun {
+		return printFileDiff(w, path, content)
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if bytes.Equal(original, content) {
+		return nil
+	}
+
+	err = os.WriteFile(path, content, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
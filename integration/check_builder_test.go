@@ -0,0 +1,182 @@
+package integration_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCheckBuilder(t *testing.T, context spec.G, it spec.S) {
+	var (
+		withT      = NewWithT(t)
+		Expect     = withT.Expect
+		Eventually = withT.Eventually
+
+		server     *httptest.Server
+		host       string
+		builderDir string
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New())
+		host = strings.TrimPrefix(server.URL, "http://")
+
+		for uri, labels := range map[string]map[string]string{
+			"some-repository/lifecycle:0.17.0": {
+				"io.buildpacks.lifecycle.apis": `{"buildpack":{"deprecated":[],"supported":["0.7","0.8"]},"platform":{"deprecated":[],"supported":["0.13"]}}`,
+			},
+			"some-repository/some-buildpack:1.2.3": {
+				"io.buildpacks.buildpack.layers": `{"some-repository/some-buildpack":{"1.2.3":{"api":"0.8"}}}`,
+			},
+			"some-repository/old-buildpack:0.1.0": {
+				"io.buildpacks.buildpack.layers": `{"some-repository/old-buildpack":{"0.1.0":{"api":"0.2"}}}`,
+			},
+		} {
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := image.ConfigFile()
+			Expect(err).NotTo(HaveOccurred())
+
+			config.OS = "linux"
+			config.Architecture = "amd64"
+			config.Config.Labels = labels
+
+			image, err = mutate.ConfigFile(image, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(fmt.Sprintf("%s/%s", host, uri))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image)).To(Succeed())
+		}
+
+		builderDir = t.TempDir()
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	writeBuilder := func(buildpacks ...string) {
+		content := bytes.NewBufferString(`description = "Some description"
+`)
+		for _, buildpack := range buildpacks {
+			_, err := fmt.Fprintf(content, `
+[[buildpacks]]
+  uri = "docker://%s/%s"
+`, host, buildpack)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		_, err := fmt.Fprint(content, `
+[lifecycle]
+  version = "0.17.0"
+
+[[targets]]
+  os = "linux"
+  arch = "amd64"
+`)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(builderDir, "builder.toml"), content.Bytes(), 0600)).To(Succeed())
+	}
+
+	it("prints the API compatibility and target availability of the builder", func() {
+		writeBuilder("some-repository/some-buildpack:1.2.3")
+
+		command := exec.Command(
+			path,
+			"check-builder",
+			"--builder-file", filepath.Join(builderDir, "builder.toml"),
+			"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", host),
+		)
+
+		buffer := gbytes.NewBuffer()
+		session, err := gexec.Start(command, buffer, buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+		Expect(string(buffer.Contents())).To(ContainSubstring("Lifecycle 0.17.0 supports buildpack APIs 0.7, 0.8 and platform APIs 0.13"))
+		Expect(string(buffer.Contents())).To(MatchRegexp(`some-repository/some-buildpack\s+1\.2\.3\s+0\.8\s+supported`))
+		Expect(string(buffer.Contents())).To(MatchRegexp(`IMAGE\s+VERSION\s+LINUX/AMD64`))
+	})
+
+	context("failure cases", func() {
+		context("when the lifecycle does not support the API of a buildpack", func() {
+			it("prints an error and exits non-zero", func() {
+				writeBuilder("some-repository/some-buildpack:1.2.3", "some-repository/old-buildpack:0.1.0")
+
+				command := exec.Command(
+					path,
+					"check-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", host),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+
+				Expect(string(buffer.Contents())).To(MatchRegexp(`some-repository/old-buildpack\s+0\.1\.0\s+0\.2\s+UNSUPPORTED`))
+				Expect(string(buffer.Contents())).To(ContainSubstring("lifecycle 0.17.0 does not support the buildpack API of some-repository/old-buildpack 0.1.0 (buildpack API 0.2)"))
+			})
+		})
+
+		context("when the lifecycle does not support a platform API", func() {
+			it("prints an error and exits non-zero", func() {
+				writeBuilder("some-repository/some-buildpack:1.2.3")
+
+				command := exec.Command(
+					path,
+					"check-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", host),
+					"--platform-api", "0.13",
+					"--platform-api", "0.14",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+
+				Expect(string(buffer.Contents())).To(MatchRegexp(`0\.14\s+UNSUPPORTED`))
+				Expect(string(buffer.Contents())).To(ContainSubstring("lifecycle 0.17.0 does not support platform API 0.14"))
+			})
+		})
+
+		context("when the --builder-file flag is missing", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(path, "check-builder")
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+
+				Expect(string(buffer.Contents())).To(ContainSubstring(`required flag(s) "builder-file" not set`))
+			})
+		})
+	})
+}
@@ -0,0 +1,99 @@
+package integration_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/jam/v2/integration/matchers"
+	"github.com/paketo-buildpacks/occam"
+)
+
+func testEnrichDependencies(t *testing.T, context spec.G, it spec.S) {
+	var (
+		withT      = NewWithT(t)
+		Expect     = withT.Expect
+		Eventually = withT.Eventually
+
+		source string
+		err    error
+	)
+
+	it.Before(func() {
+		source, err = occam.Source(filepath.Join("testdata", "enrich-dependencies"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(source)).To(Succeed())
+	})
+
+	it("fills in the missing purls and cpes without overwriting explicit values", func() {
+		command := exec.Command(
+			path,
+			"enrich-dependencies",
+			"--buildpack-file", filepath.Join(source, "buildpack.toml"),
+			"--templates", filepath.Join(source, "templates.json"),
+		)
+
+		buffer := gbytes.NewBuffer()
+		session, err := gexec.Start(command, buffer, buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+		Expect(string(buffer.Contents())).To(ContainSubstring("Computed cpes of node 18.18.0 [some-stack]: cpe:2.3:a:nodejs:node.js:18.18.0:*:*:*:*:*:*:*"))
+		Expect(string(buffer.Contents())).To(ContainSubstring("Computed purl of yarn 1.22.19 [some-stack]: pkg:generic/yarn@1.22.19"))
+		Expect(string(buffer.Contents())).NotTo(ContainSubstring("node 20.9.0"))
+
+		Expect(filepath.Join(source, "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "expected.toml")))
+
+		content, err := os.ReadFile(filepath.Join(source, "buildpack.toml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("  # computed identifiers are only added where they are missing"))
+	})
+
+	context("failure cases", func() {
+		context("the --templates flag is missing", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"enrich-dependencies",
+					"--buildpack-file", filepath.Join(source, "buildpack.toml"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(`required flag(s) "templates" not set`))
+			})
+		})
+
+		context("a template refers to an unknown field", func() {
+			it("prints an error and exits non-zero", func() {
+				Expect(os.WriteFile(filepath.Join(source, "templates.json"), []byte(`{"*": {"purl": "pkg:generic/{{.Release}}"}}`), 0600)).To(Succeed())
+
+				command := exec.Command(
+					path,
+					"enrich-dependencies",
+					"--buildpack-file", filepath.Join(source, "buildpack.toml"),
+					"--templates", filepath.Join(source, "templates.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to compute purl of node 18.18.0 [some-stack]"))
+			})
+		})
+	})
+}
@@ -27,13 +27,18 @@ func TestJam(t *testing.T) {
 
 	suite := spec.New("jam", spec.Report(report.Terminal{}))
 	suite("Errors", testErrors)
+	suite("check-builder", testCheckBuilder)
 	suite("create-stack", testCreateStack)
+	suite("enrich-dependencies", testEnrichDependencies)
 	suite("publish-image", testPublishImage)
 	suite("pack", testPack)
+	suite("schema", testSchema)
 	suite("summarize", testSummarize)
 	suite("update-builder", testUpdateBuilder)
 	suite("update-buildpack", testUpdateBuildpack)
+	suite("update-stack", testUpdateStack)
 	suite("update-dependencies-from-metadata", testUpdateDependenciesFromMetadata)
+	suite("update-extension-dependencies-from-metadata", testUpdateExtensionDependenciesFromMetadata)
 	suite("version", testVersion)
 	suite("pack extension", testPackExtension)
 
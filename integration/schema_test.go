@@ -0,0 +1,58 @@
+package integration_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"testing"
+
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSchema(t *testing.T, context spec.G, it spec.S) {
+	var (
+		withT      = NewWithT(t)
+		Expect     = withT.Expect
+		Eventually = withT.Eventually
+
+		buffer *bytes.Buffer
+	)
+
+	it.Before(func() {
+		buffer = bytes.NewBuffer(nil)
+	})
+
+	context("running the schema metadata command", func() {
+		it("the JSON Schema of the metadata files is printed to standard out", func() {
+			command := exec.Command(
+				path, "schema", "metadata",
+			)
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0), func() string { return buffer.String() })
+
+			var schema map[string]interface{}
+			Expect(json.Unmarshal(session.Out.Contents(), &schema)).To(Succeed())
+			Expect(schema).To(HaveKeyWithValue("$schema", "https://json-schema.org/draft/2020-12/schema"))
+			Expect(schema).To(HaveKeyWithValue("required", []interface{}{"schema-version", "dependencies"}))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when an argument is given", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path, "schema", "metadata", "extra",
+				)
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1), func() string { return buffer.String() })
+
+				Expect(buffer.String()).To(ContainSubstring(`unknown command "extra" for "jam schema metadata"`))
+			})
+		})
+	})
+}
@@ -2,6 +2,8 @@ package integration_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -52,8 +54,9 @@ func testUpdateBuilder(t *testing.T, context spec.G, it spec.S) {
 		Expect     = withT.Expect
 		Eventually = withT.Eventually
 
-		server     *httptest.Server
-		builderDir string
+		server           *httptest.Server
+		builderDir       string
+		buildIndexDigest string
 	)
 
 	it.Before(func() {
@@ -81,6 +84,8 @@ func testUpdateBuilder(t *testing.T, context spec.G, it spec.S) {
 		extensionManifestPath := "/v2/paketocommunity/ubi-nodejs-extension/manifests/0.0.3"
 		extensionConfigPath := fmt.Sprintf("/v2/paketocommunity/ubi-nodejs-extension/blobs/%s", mustConfigName(t, extensionImg))
 		extensionManifestReqCount := 0
+		buildIndexManifest := `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json", "manifests": []}`
+		buildIndexDigest = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(buildIndexManifest)))
 		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 
 			if req.Method == http.MethodHead {
@@ -227,7 +232,29 @@ func testUpdateBuilder(t *testing.T, context spec.G, it spec.S) {
 			case "/v2/some-repository/nonexistent-labels-id/manifests/0.2.0":
 				w.WriteHeader(http.StatusBadRequest)
 
+			case "/v2/somerepository/build/manifests/0.20.1":
+				w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+				_, err = fmt.Fprint(w, buildIndexManifest)
+				Expect(err).NotTo(HaveOccurred())
+
 			default:
+				// every version of the buildpack and extension images is served so that
+				// their platforms can be verified against the builder targets
+				if repository, _, ok := strings.Cut(req.URL.Path, "/manifests/"); ok {
+					if img, ok := map[string]v1.Image{
+						"/v2/paketo-buildpacks/go":                 goImg,
+						"/v2/paketobuildpacks/nodejs":              nodeImg,
+						"/v2/paketocommunity/ubi-nodejs-extension": extensionImg,
+					}[repository]; ok {
+						mediaType, err := img.MediaType()
+						Expect(err).NotTo(HaveOccurred())
+
+						w.Header().Set("Content-Type", string(mediaType))
+						_, _ = w.Write(mustRawManifest(t, img))
+						return
+					}
+				}
+
 				t.Fatalf("unknown path: %s", req.URL.Path)
 			}
 		}))
@@ -354,6 +381,239 @@ description = "Some description"
 			`, "REGISTRY-URI", strings.TrimPrefix(server.URL, "http://"))))
 	})
 
+	context("when the --dry-run flag is set", func() {
+		it("prints a diff and a version table without changing the builder file", func() {
+			originalContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+				"--dry-run",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			registry := strings.TrimPrefix(server.URL, "http://")
+			Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("--- a/%s", filepath.Join(builderDir, "builder.toml"))))
+			Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("+++ b/%s", filepath.Join(builderDir, "builder.toml"))))
+			Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf(`+  uri = "docker://%s/paketo-buildpacks/go:0.20.12"`, registry)))
+			Expect(string(buffer.Contents())).To(MatchRegexp(`IMAGE\s+OLD VERSION\s+NEW VERSION`))
+			Expect(string(buffer.Contents())).To(MatchRegexp(fmt.Sprintf(`%s/paketo-buildpacks/go\s+0\.0\.10\s+0\.20\.12`, registry)))
+			Expect(string(buffer.Contents())).To(MatchRegexp(fmt.Sprintf(`%s/some-repository/lifecycle\s+0\.10\.2\s+0\.21\.1`, registry)))
+
+			builderContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(builderContents).To(Equal(originalContents))
+		})
+	})
+
+	context("when the --report flag is set", func() {
+		it("writes a JSON report of every change to the report file", func() {
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+				"--report", "json",
+				"--report-file", filepath.Join(builderDir, "report.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			report, err := os.ReadFile(filepath.Join(builderDir, "report.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(report)).To(MatchJSON(strings.ReplaceAll(strings.ReplaceAll(`{
+				"highest_bump": "minor",
+				"changes": [
+					{
+						"file": "BUILDER-FILE",
+						"path": "buildpacks[0].uri",
+						"id": "paketo-buildpacks/go",
+						"image": "REGISTRY-URI/paketo-buildpacks/go",
+						"old_version": "0.0.10",
+						"new_version": "0.20.12",
+						"bump": "minor",
+						"source": "REGISTRY-URI"
+					},
+					{
+						"file": "BUILDER-FILE",
+						"path": "order[1].group[0].version",
+						"id": "paketo-buildpacks/go",
+						"image": "REGISTRY-URI/paketo-buildpacks/go",
+						"old_version": "0.0.10",
+						"new_version": "0.20.12",
+						"bump": "minor",
+						"source": "REGISTRY-URI"
+					},
+					{
+						"file": "BUILDER-FILE",
+						"path": "extensions[0].uri",
+						"id": "paketo-community/ubi-nodejs-extension",
+						"image": "REGISTRY-URI/paketocommunity/ubi-nodejs-extension",
+						"old_version": "0.0.3",
+						"new_version": "0.0.4",
+						"bump": "patch",
+						"source": "REGISTRY-URI"
+					},
+					{
+						"file": "BUILDER-FILE",
+						"path": "order-extensions[0].group[0].version",
+						"id": "paketo-community/ubi-nodejs-extension",
+						"image": "REGISTRY-URI/paketocommunity/ubi-nodejs-extension",
+						"old_version": "0.0.3",
+						"new_version": "0.0.4",
+						"bump": "patch",
+						"source": "REGISTRY-URI"
+					},
+					{
+						"file": "BUILDER-FILE",
+						"path": "lifecycle.version",
+						"image": "REGISTRY-URI/some-repository/lifecycle",
+						"old_version": "0.10.2",
+						"new_version": "0.21.1",
+						"bump": "minor",
+						"source": "REGISTRY-URI"
+					},
+					{
+						"file": "BUILDER-FILE",
+						"path": "stack.build-image",
+						"image": "REGISTRY-URI/somerepository/build",
+						"old_version": "0.0.10-some-cnb",
+						"new_version": "0.20.12-some-cnb",
+						"bump": "minor",
+						"source": "REGISTRY-URI"
+					}
+				]
+			}`, "REGISTRY-URI", strings.TrimPrefix(server.URL, "http://")), "BUILDER-FILE", filepath.Join(builderDir, "builder.toml"))))
+		})
+
+		context("when no --report-file is set", func() {
+			it("writes only the report to stdout, and the other messages to stderr", func() {
+				command := exec.Command(
+					path,
+					"update-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+					"--report", "json",
+				)
+
+				stdout := gbytes.NewBuffer()
+				stderr := gbytes.NewBuffer()
+				session, err := gexec.Start(command, stdout, stderr)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(stderr.Contents()) })
+
+				var report struct {
+					Changes []struct {
+						Path string `json:"path"`
+					} `json:"changes"`
+				}
+				Expect(json.Unmarshal(stdout.Contents(), &report)).To(Succeed(), string(stdout.Contents()))
+				Expect(report.Changes).To(ContainElement(HaveField("Path", "stack.build-image")))
+				Expect(string(stderr.Contents())).To(ContainSubstring("Skipping build image version: 0.20.12-other-cnb"))
+			})
+		})
+	})
+
+	context("when semver policy flags are set", func() {
+		it("limits each entry to the allowed version bump", func() {
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+				"--patch-only",
+				"--lifecycle-max-bump", "major",
+				"--max-bump-override", "paketo-community/ubi-nodejs-extension=none",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			builderContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(builderContents)).To(MatchTOML(strings.ReplaceAll(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.0.10"
+  version = "0.0.10"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketobuildpacks/nodejs:0.20.22"
+  version = "0.20.22"
+
+[[extensions]]
+  id = "paketo-community/ubi-nodejs-extension"
+  version = "0.0.3"
+  uri = "docker://REGISTRY-URI/paketocommunity/ubi-nodejs-extension:0.0.3"
+
+[lifecycle]
+  version = "0.21.1"
+
+[[order]]
+
+  [[order.group]]
+    id = "paketo-buildpacks/nodejs"
+
+[[order]]
+
+  [[order.group]]
+    id = "paketo-buildpacks/go"
+    optional = true
+    version = "0.0.10"
+
+[[order-extensions]]
+  [[order-extensions.group]]
+    id = "paketo-community/ubi-nodejs-extension"
+    version = "0.0.3"
+
+[stack]
+  build-image = "REGISTRY-URI/somerepository/build:0.0.10-some-cnb"
+  id = "io.paketo.stacks.some-stack"
+  run-image = "REGISTRY-URI/somerepository/run:some-cnb"
+  run-image-mirrors = ["REGISTRY-URI/some-repository/run:some-cnb"]
+
+[[targets]]
+	os = "linux"
+	arch = "amd64"
+			`, "REGISTRY-URI", strings.TrimPrefix(server.URL, "http://"))))
+		})
+
+		context("when an invalid bump is given", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--max-bump", "huge",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(`invalid bump "huge": must be one of major, minor, patch or none`))
+			})
+		})
+	})
+
 	context("when the run image is set to latest", func() {
 		it.Before(func() {
 			err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
@@ -667,6 +927,102 @@ description = "Some description"
 		})
 	})
 
+	context("when the --pin-digest flag is set", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
+description = "Some description"
+
+[lifecycle]
+  version = "0.10.2"
+
+[build]
+  image = "REGISTRY-URI/somerepository/build:0.0.10@sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+			`), []byte(`REGISTRY-URI`), []byte(strings.TrimPrefix(server.URL, "http://"))), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("pins the updated image to the digest of its index", func() {
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+				"--pin-digest",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			builderContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(builderContents)).To(MatchTOML(strings.NewReplacer("REGISTRY-URI", strings.TrimPrefix(server.URL, "http://"), "BUILD-DIGEST", buildIndexDigest).Replace(`
+description = "Some description"
+
+[build]
+	image = "REGISTRY-URI/somerepository/build:0.20.1@BUILD-DIGEST"
+
+[lifecycle]
+	version = "0.21.1"
+			`)))
+		})
+	})
+
+	context("when version holds are set", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.0.10"
+  version = "0.0.10"
+  jam-constraint = "< 0.20.0"
+
+[lifecycle]
+  version = "0.10.2"
+
+[metadata.jam.constraints]
+  lifecycle = "< 0.21.0"
+			`), []byte(`REGISTRY-URI`), []byte(strings.TrimPrefix(server.URL, "http://"))), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("holds the entries at versions that satisfy the constraints and keeps the holds", func() {
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			builderContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(builderContents)).To(MatchTOML(strings.ReplaceAll(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.0.10"
+  version = "0.0.10"
+  jam-constraint = "< 0.20.0"
+
+[lifecycle]
+  version = "0.20.1"
+
+[metadata.jam.constraints]
+  lifecycle = "< 0.21.0"
+			`, "REGISTRY-URI", strings.TrimPrefix(server.URL, "http://"))))
+		})
+	})
+
 	context("when only run images are specified with multiple tags", func() {
 		it.Before(func() {
 			err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
@@ -719,6 +1075,76 @@ description = "Some description"
 		})
 	})
 
+	context("when system buildpacks and run image mirrors are specified", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.0.10"
+  version = "0.0.10"
+
+[lifecycle]
+  version = "0.10.2"
+
+[[system.pre.buildpacks]]
+  id = "paketo-buildpacks/go"
+  version = "0.0.10"
+
+[[system.post.buildpacks]]
+  id = "paketo-buildpacks/nodejs"
+  version = "0.20.22"
+  optional = true
+
+[[run.images]]
+  image = "REGISTRY-URI/somerepository/run:0.0.10-some-cnb"
+  mirrors = ["other-registry.example.com/somerepository/run:0.0.10-some-cnb", "other-registry.example.com/somerepository/run:some-cnb"]
+			`), []byte(`REGISTRY-URI`), []byte(strings.TrimPrefix(server.URL, "http://"))), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("updates the system buildpacks with their buildpacks and the mirrors with their run image", func() {
+			command := exec.Command(
+				path,
+				"update-builder",
+				"--builder-file", filepath.Join(builderDir, "builder.toml"),
+				"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			builderContents, err := os.ReadFile(filepath.Join(builderDir, "builder.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(builderContents)).To(MatchTOML(strings.ReplaceAll(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.20.12"
+  version = "0.20.12"
+
+[lifecycle]
+  version = "0.21.1"
+
+[[system.pre.buildpacks]]
+  id = "paketo-buildpacks/go"
+  version = "0.20.12"
+
+[[system.post.buildpacks]]
+  id = "paketo-buildpacks/nodejs"
+  version = "0.20.22"
+  optional = true
+
+[[run.images]]
+  image = "REGISTRY-URI/somerepository/run:0.20.1"
+  mirrors = ["other-registry.example.com/somerepository/run:0.20.1", "other-registry.example.com/somerepository/run:some-cnb"]
+			`, "REGISTRY-URI", strings.TrimPrefix(server.URL, "http://"))))
+		})
+	})
+
 	context("when build-run images are specified and stack id", func() {
 		context("with different tags on the run images", func() {
 			it.Before(func() {
@@ -820,6 +1246,108 @@ description = "Some description"
 			})
 		})
 
+		context("when a buildpack is not available for every builder target", func() {
+			it.Before(func() {
+				err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://REGISTRY-URI/paketo-buildpacks/go:0.0.10"
+  version = "0.0.10"
+
+[lifecycle]
+  version = "0.10.2"
+
+[[targets]]
+  os = "linux"
+  arch = "amd64"
+
+[[targets]]
+  os = "linux"
+  arch = "arm64"
+				`), []byte(`REGISTRY-URI`), []byte(strings.TrimPrefix(server.URL, "http://"))), 0600)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			it("prints the target matrix and an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+
+				Expect(string(buffer.Contents())).To(MatchRegexp(`IMAGE\s+VERSION\s+LINUX/AMD64\s+LINUX/ARM64`))
+				Expect(string(buffer.Contents())).To(MatchRegexp(`paketo-buildpacks/go\s+0.20.12\s+yes\s+MISSING`))
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to verify builder targets"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("paketo-buildpacks/go:0.20.12 is not available for linux/arm64"))
+			})
+
+			context("when the --skip-missing-targets flag is set", func() {
+				it("prints an error when no version is available for every target", func() {
+					command := exec.Command(
+						path,
+						"update-builder",
+						"--builder-file", filepath.Join(builderDir, "builder.toml"),
+						"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+						"--skip-missing-targets",
+					)
+
+					buffer := gbytes.NewBuffer()
+					session, err := gexec.Start(command, buffer, buffer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+
+					Expect(string(buffer.Contents())).To(ContainSubstring("paketo-buildpacks/go that is available for every target"))
+				})
+			})
+		})
+
+		context("when the --concurrency flag is less than one", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+					"--concurrency", "0",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to execute: invalid concurrency 0: must be at least 1"))
+			})
+		})
+
+		context("when the --channel flag is not a valid regular expression", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-builder",
+					"--builder-file", filepath.Join(builderDir, "builder.toml"),
+					"--lifecycle-uri", fmt.Sprintf("%s/some-repository/lifecycle", strings.TrimPrefix(server.URL, "http://")),
+					"--channel", "(",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(`failed to execute: invalid channel "(": must be "stable", "rc", "all" or a prerelease regular expression`))
+			})
+		})
+
 		context("when the latest buildpack image cannot be found", func() {
 			it.Before(func() {
 				err := os.WriteFile(filepath.Join(builderDir, "builder.toml"), bytes.ReplaceAll([]byte(`
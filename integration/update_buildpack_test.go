@@ -297,6 +297,83 @@ func testUpdateBuildpack(t *testing.T, context spec.G, it spec.S) {
 			`))
 		})
 
+		context("the --dry-run flag is set", func() {
+			it("prints a diff and a version table without changing the buildpack.toml and package.toml files", func() {
+				originalBuildpackContents, err := os.ReadFile(filepath.Join(buildpackDir, "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				originalPackageContents, err := os.ReadFile(filepath.Join(buildpackDir, "package.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				command := exec.Command(
+					path,
+					"update-buildpack",
+					"--buildpack-file", filepath.Join(buildpackDir, "buildpack.toml"),
+					"--package-file", filepath.Join(buildpackDir, "package.toml"),
+					"--api", server.URL,
+					"--dry-run",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("--- a/%s", filepath.Join(buildpackDir, "buildpack.toml"))))
+				Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("--- a/%s", filepath.Join(buildpackDir, "package.toml"))))
+				Expect(string(buffer.Contents())).To(ContainSubstring(`+  uri = "urn:cnb:registry:paketo-buildpacks/go-dist@0.21.0"`))
+				Expect(string(buffer.Contents())).To(MatchRegexp(`urn:cnb:registry:paketo-buildpacks/go-dist\s+0\.20\.1\s+0\.21\.0`))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Highest semver bump: minor"))
+
+				buildpackContents, err := os.ReadFile(filepath.Join(buildpackDir, "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(buildpackContents).To(Equal(originalBuildpackContents))
+
+				packageContents, err := os.ReadFile(filepath.Join(buildpackDir, "package.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(packageContents).To(Equal(originalPackageContents))
+			})
+		})
+
+		context("the --registry-index flag is set", func() {
+			it("updates the versions from the local registry index instead of the api", func() {
+				command := exec.Command(
+					path,
+					"update-buildpack",
+					"--buildpack-file", filepath.Join(buildpackDir, "buildpack.toml"),
+					"--package-file", filepath.Join(buildpackDir, "package.toml"),
+					"--api", "http://localhost:0",
+					"--registry-index", filepath.Join("testdata", "registry-index"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+				packageContents, err := os.ReadFile(filepath.Join(buildpackDir, "package.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(packageContents)).To(MatchTOML(`
+				[buildpack]
+				uri = "build/buildpack.tgz"
+
+				[[dependencies]]
+				uri = "urn:cnb:registry:paketo-buildpacks/mri@0.2.0"
+
+				[[dependencies]]
+				uri = "urn:cnb:registry:paketo-buildpacks/go-dist@0.21.1"
+
+				[[dependencies]]
+				uri = "urn:cnb:registry:paketo-buildpacks/node-engine@0.20.22"
+			`))
+
+				buildpackContents, err := os.ReadFile(filepath.Join(buildpackDir, "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(buildpackContents)).To(ContainSubstring(`version = "0.21.1"`))
+			})
+		})
+
 		context("the --patch-only flag is set", func() {
 			it("updates ONLY patch-level changes in the buildpack.toml and package.toml files", func() {
 				command := exec.Command(
@@ -1,9 +1,14 @@
 package integration_test
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/onsi/gomega/gbytes"
@@ -53,6 +58,43 @@ func testUpdateDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S)
 		})
 	})
 
+	context("when the --report flag is set", func() {
+		it("writes a JSON report of the new dependency versions to stdout", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+				"--report", "json",
+			)
+
+			stdout := gbytes.NewBuffer()
+			stderr := gbytes.NewBuffer()
+			session, err := gexec.Start(command, stdout, stderr)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(stderr.Contents()) })
+
+			Expect(string(stderr.Contents())).To(ContainSubstring("Updating buildpack.toml with new versions:"))
+			Expect(string(stdout.Contents())).To(MatchJSON(fmt.Sprintf(`{
+				"highest_bump": "minor",
+				"changes": [
+					{
+						"file": %q,
+						"path": "metadata.dependencies[0]",
+						"id": "some-dependency",
+						"old_version": "1.2.3",
+						"new_version": "1.9.9",
+						"bump": "minor",
+						"source": %q
+					}
+				]
+			}`, filepath.Join(source, "basic-buildpack.toml"), filepath.Join(source, "one-dependency-example", "metadata.json"))))
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "one-dependency-example", "expected.toml")))
+		})
+	})
+
 	context("when there is one new version with two variants for different stacks", func() {
 		it("updates the buildpack.toml dependencies from a metadata file", func() {
 			command := exec.Command(
@@ -167,7 +209,773 @@ func testUpdateDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S)
 		})
 	})
 
+	context("the buildpack has dependencies not covered by any dependency-constraint", func() {
+		it("keeps those dependencies by default", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "unconstrained-example", "buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "unconstrained-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "unconstrained-example", "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "unconstrained-example", "expected-keep.toml")))
+		})
+
+		context("when --unconstrained is drop", func() {
+			it("drops those dependencies and prints them", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "unconstrained-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "unconstrained-example", "metadata.json"),
+					"--unconstrained", "drop",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+				Expect(buffer).To(gbytes.Say("Dropped dependencies not covered by any dependency-constraint:"))
+				Expect(buffer).To(gbytes.Say("  other-dependency 2.0.0"))
+
+				Expect(filepath.Join(source, "unconstrained-example", "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "unconstrained-example", "expected-drop.toml")))
+			})
+		})
+
+		context("when --unconstrained is error", func() {
+			it("prints an error, exits non-zero and leaves the buildpack.toml unchanged", func() {
+				original, err := os.ReadFile(filepath.Join(source, "unconstrained-example", "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "unconstrained-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "unconstrained-example", "metadata.json"),
+					"--unconstrained", "error",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("found dependencies not covered by any dependency-constraint: other-dependency 2.0.0"))
+
+				Expect(os.ReadFile(filepath.Join(source, "unconstrained-example", "buildpack.toml"))).To(Equal(original))
+			})
+		})
+	})
+
+	context("the new versions are split across metadata files", func() {
+		it("merges the metadata files given multiple times", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "multi-source-example", "metadata", "another-stack.json"),
+				"--metadata-file", filepath.Join(source, "multi-source-example", "metadata", "other-stack.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "multi-source-example", "expected.toml")))
+		})
+
+		it("merges the metadata files of a directory", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "multi-source-example", "metadata"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "multi-source-example", "expected.toml")))
+		})
+
+		it("merges the metadata files matching a glob pattern", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "multi-source-example", "metadata", "*-stack.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "multi-source-example", "expected.toml")))
+		})
+	})
+
+	context("the --metadata-url flag is set", func() {
+		var (
+			server  *httptest.Server
+			queries []string
+		)
+
+		it.Before(func() {
+			queries = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				queries = append(queries, req.URL.String())
+				if req.URL.Path != "/v1/dependency" || req.URL.Query().Get("name") != "some-dependency" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				http.ServeFile(w, req, filepath.Join(source, "dependency-api-example", "dependencies.json"))
+			}))
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("updates the buildpack.toml dependencies from the dependency API", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-url", server.URL,
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(queries).To(Equal([]string{"/v1/dependency?name=some-dependency"}))
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "dependency-api-example", "expected.toml")))
+		})
+
+		context("when the dependency API fails", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-url", server.URL+"/missing",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("unexpected response status: 404 Not Found"))
+			})
+		})
+	})
+
+	context("when the metadata is validated", func() {
+		var tmpDir string
+
+		it.Before(func() {
+			tmpDir = t.TempDir()
+		})
+
+		context("the metadata has malformed entries", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(`[
+					{"id": "some-dependency", "version": "1.latest", "uri": "https://example.com/some-dependency.tgz", "checksum": "sha256:some-sha"}
+				]`), 0644)).To(Succeed())
+			})
+
+			it("prints every problem, exits non-zero and leaves the buildpack.toml unchanged", func() {
+				original, err := os.ReadFile(filepath.Join(source, "basic-buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("invalid dependency metadata:"))
+				Expect(string(buffer.Contents())).To(ContainSubstring(`some-dependency 1.latest: invalid version "1.latest": must be a semantic version`))
+				Expect(string(buffer.Contents())).To(ContainSubstring(`some-dependency 1.latest: invalid checksum "sha256:some-sha"`))
+
+				Expect(os.ReadFile(filepath.Join(source, "basic-buildpack.toml"))).To(Equal(original))
+			})
+		})
+
+		context("the metadata has malformed entries that are not within any constraint", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(fmt.Sprintf(`[
+					{"id": "some-dependency", "version": "1.9.9", "uri": "https://example.com/some-dependency.tgz", "checksum": "sha256:%s", "stacks": ["some-stack"], "purl": "pkg:generic/some-dependency@1.9.9", "cpes": ["cpe:2.3:a:some:dependency:1.9.9:*:*:*:*:*:*:*"]},
+					{"id": "some-dependency", "version": "2.0.0", "uri": "https://example.com/some-dependency.tgz", "checksum": "sha256:some-sha", "stacks": ["unknown-stack"]},
+					{"id": "unconstrained-dependency", "version": "latest", "uri": "some-dependency.tgz"}
+				]`, strings.Repeat("a", 64))), 0644)).To(Succeed())
+			})
+
+			it("ignores them and updates the buildpack.toml, even with --strict", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+					"--strict",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).NotTo(ContainSubstring("Warning: "))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Updating buildpack.toml with new versions:  [1.9.9]"))
+			})
+		})
+
+		context("the metadata has an entry without an id", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(fmt.Sprintf(`[
+					{"version": "1.9.9", "uri": "https://example.com/some-dependency.tgz", "checksum": "sha256:%s", "stacks": ["some-stack"], "purl": "pkg:generic/some-dependency@1.9.9", "cpes": ["cpe:2.3:a:some:dependency:1.9.9:*:*:*:*:*:*:*"]}
+				]`, strings.Repeat("a", 64))), 0644)).To(Succeed())
+			})
+
+			it("prints the entry and exits non-zero with --strict", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+					"--strict",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("invalid dependency metadata:"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("1.9.9 [some-stack]: missing id"))
+			})
+		})
+
+		context("the metadata has incomplete entries", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(fmt.Sprintf(`[
+					{"id": "some-dependency", "version": "1.9.9", "uri": "https://example.com/some-dependency.tgz", "checksum": "sha256:%s", "stacks": ["another-stack"]}
+				]`, strings.Repeat("a", 64))), 0644)).To(Succeed())
+			})
+
+			it("prints warnings and updates the buildpack.toml", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("Warning: "))
+				Expect(string(buffer.Contents())).To(ContainSubstring("some-dependency 1.9.9 [another-stack]: missing purl"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Updating buildpack.toml with new versions:  [1.9.9]"))
+			})
+
+			context("when --strict is set", func() {
+				it("prints the warnings as errors and exits non-zero", func() {
+					command := exec.Command(
+						path,
+						"update-dependencies",
+						"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+						"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+						"--strict",
+					)
+
+					buffer := gbytes.NewBuffer()
+					session, err := gexec.Start(command, buffer, buffer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+					Expect(string(buffer.Contents())).To(ContainSubstring("invalid dependency metadata:"))
+					Expect(string(buffer.Contents())).To(ContainSubstring("some-dependency 1.9.9 [another-stack]: missing purl"))
+					Expect(string(buffer.Contents())).To(ContainSubstring("some-dependency 1.9.9 [another-stack]: missing cpe"))
+				})
+
+				context("when --enrichment-templates computes the missing fields", func() {
+					it.Before(func() {
+						Expect(os.WriteFile(filepath.Join(tmpDir, "enrichment-templates.json"), []byte(`{
+							"some-dependency": {
+								"purl": "pkg:generic/some-dependency@{{.Version}}",
+								"cpes": ["cpe:2.3:a:some-vendor:some-dependency:{{.Version}}:*:*:*:*:*:*:*"]
+							}
+						}`), 0644)).To(Succeed())
+					})
+
+					it("updates the buildpack.toml with the computed fields", func() {
+						command := exec.Command(
+							path,
+							"update-dependencies",
+							"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+							"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+							"--enrichment-templates", filepath.Join(tmpDir, "enrichment-templates.json"),
+							"--strict",
+						)
+
+						buffer := gbytes.NewBuffer()
+						session, err := gexec.Start(command, buffer, buffer)
+						Expect(err).NotTo(HaveOccurred())
+
+						Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+						Expect(string(buffer.Contents())).To(ContainSubstring("Computed purl of some-dependency 1.9.9 [another-stack]: pkg:generic/some-dependency@1.9.9"))
+
+						content, err := os.ReadFile(filepath.Join(source, "basic-buildpack.toml"))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(content)).To(ContainSubstring(`cpes = ["cpe:2.3:a:some-vendor:some-dependency:1.9.9:*:*:*:*:*:*:*"]`))
+					})
+				})
+			})
+		})
+	})
+
+	context("when --verify-checksums is set", func() {
+		var (
+			server  *httptest.Server
+			tmpDir  string
+			content = "some-dependency-content"
+		)
+
+		it.Before(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprint(w, content)
+			}))
+
+			tmpDir = t.TempDir()
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		writeMetadata := func(checksum string) {
+			Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(fmt.Sprintf(`[
+				{"id": "some-dependency", "version": "1.9.9", "uri": "%s/some-dependency.tgz", "checksum": "%s", "purl": "some-purl", "cpe": "some-cpe"}
+			]`, server.URL, checksum)), 0644)).To(Succeed())
+		}
+
+		it("updates the buildpack.toml when the artifacts match their checksums", func() {
+			sum := sha256.Sum256([]byte(content))
+			writeMetadata(fmt.Sprintf("sha256:%x", sum))
+
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+				"--verify-checksums",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring("Updating buildpack.toml with new versions:  [1.9.9]"))
+		})
+
+		it("prints an error and exits non-zero when an artifact does not match its checksum", func() {
+			writeMetadata("sha256:" + strings.Repeat("a", 64))
+
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+				"--verify-checksums",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("failed to verify some-dependency 1.9.9: the artifact at %s/some-dependency.tgz does not match", server.URL)))
+		})
+	})
+
+	context("the metadata files use the versioned schema", func() {
+		it("reads YAML metadata files", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "versioned-schema-example", "metadata.yaml"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "versioned-schema-example", "expected.toml")))
+		})
+
+		it("reads TOML metadata files", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "versioned-schema-example", "metadata.toml"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "versioned-schema-example", "expected.toml")))
+		})
+
+		context("when a metadata file does not match the schema", func() {
+			it("prints the file, index and field, and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "versioned-schema-example", "invalid.yaml"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("invalid.yaml: dependencies[0].stacks: expected a list of strings, got a string"))
+			})
+		})
+	})
+
+	context("the builds of the newest version lag behind for some targets", func() {
+		it("counts the patches across targets by default", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "per-target-example", "buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "per-target-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			content, err := os.ReadFile(filepath.Join(source, "per-target-example", "buildpack.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).NotTo(ContainSubstring("some-dependency-1.0.0-arm64.tgz"))
+		})
+
+		context("when --per-target-patches is set", func() {
+			it("keeps the newest versions of every target and warns about targets that fall short", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "per-target-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "per-target-example", "metadata.json"),
+					"--per-target-patches",
+					"--report", "json",
+				)
+
+				stdout := gbytes.NewBuffer()
+				stderr := gbytes.NewBuffer()
+				session, err := gexec.Start(command, stdout, stderr)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(stderr.Contents()) })
+				Expect(string(stderr.Contents())).To(ContainSubstring("Warning: some-dependency 1.* on linux/ppc64le has 1 of 2 patches available"))
+				Expect(string(stdout.Contents())).To(ContainSubstring(`"shortfalls": [`))
+
+				Expect(filepath.Join(source, "per-target-example", "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "per-target-example", "expected.toml")))
+			})
+		})
+	})
+
+	context("the dependencies lack purls and cpes", func() {
+		context("when --enrichment-templates is set", func() {
+			it("fills in the missing purls and cpes of the written dependencies", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "per-target-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "per-target-example", "metadata.json"),
+					"--enrichment-templates", filepath.Join(source, "per-target-example", "enrichment-templates.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("Computed purl of some-dependency 1.1.0 (linux/arm64): pkg:generic/some-dependency@1.1.0?arch=arm64"))
+				Expect(string(buffer.Contents())).NotTo(ContainSubstring("Computed purl of some-dependency 1.2.0"))
+
+				content, err := os.ReadFile(filepath.Join(source, "per-target-example", "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`purl = "pkg:generic/some-dependency@1.1.0?arch=amd64"`))
+				Expect(string(content)).To(ContainSubstring(`cpes = ["cpe:2.3:a:some-vendor:some-dependency:1.1.0:*:*:*:*:*:*:*"]`))
+				Expect(string(content)).NotTo(ContainSubstring(`cpe = "cpe:2.3:a:some-vendor:some-dependency:1.1.0:*:*:*:*:*:*:*"`))
+				Expect(string(content)).To(ContainSubstring(`purl = "some-purl"`))
+			})
+		})
+	})
+
+	context("a version among the newest patches is past its deprecation date", func() {
+		it("keeps the deprecated version by default", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "deprecation-example", "buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "deprecation-example", "metadata.json"),
+				"--deprecation-grace-days", "30",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring("Warning: some-dependency 1.1.0 [some-stack] was deprecated on 2021-01-01"))
+
+			content, err := os.ReadFile(filepath.Join(source, "deprecation-example", "buildpack.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`version = "1.1.0"`))
+			Expect(string(content)).NotTo(ContainSubstring(`version = "1.0.0"`))
+		})
+
+		context("when --drop-deprecated and --eol-calendar are set", func() {
+			it("drops the deprecated version, fills in missing deprecation dates and warns within the grace period", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "deprecation-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "deprecation-example", "metadata.json"),
+					"--drop-deprecated",
+					"--eol-calendar", filepath.Join(source, "deprecation-example", "eol.json"),
+					"--deprecation-grace-days", "36500",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("Setting deprecation date of some-dependency 1.2.0 [some-stack] to 2099-12-31"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Dropped deprecated dependencies:\n  some-dependency 1.1.0 [some-stack] (deprecated on 2021-01-01)"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Warning: some-dependency 1.2.0 [some-stack] will be deprecated on 2099-12-31"))
+
+				Expect(filepath.Join(source, "deprecation-example", "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "deprecation-example", "expected.toml")))
+			})
+		})
+
+		context("when the EOL calendar is invalid", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "deprecation-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "deprecation-example", "metadata.json"),
+					"--eol-calendar", filepath.Join(source, "deprecation-example", "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to decode EOL calendar"))
+			})
+		})
+	})
+
+	context("the patch window moves past a default version", func() {
+		it("keeps the default version and prints a warning by default", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--buildpack-file", filepath.Join(source, "default-versions-example", "buildpack.toml"),
+				"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring("Warning: default version some-dependency 1.2.3 matches no dependency version"))
+
+			content, err := os.ReadFile(filepath.Join(source, "default-versions-example", "buildpack.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`some-dependency = "1.2.3"`))
+		})
+
+		context("when --default-versions is update", func() {
+			it("updates the default version and reports the change", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "default-versions-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+					"--default-versions", "update",
+					"--report", "json",
+				)
+
+				stdout := gbytes.NewBuffer()
+				stderr := gbytes.NewBuffer()
+				session, err := gexec.Start(command, stdout, stderr)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(0), func() string { return string(stderr.Contents()) })
+				Expect(string(stderr.Contents())).To(ContainSubstring("Updating default version of some-dependency: 1.2.3 -> 1.9.9"))
+				Expect(string(stdout.Contents())).To(ContainSubstring(`"path": "metadata.default-versions.some-dependency"`))
+
+				Expect(filepath.Join(source, "default-versions-example", "buildpack.toml")).To(MatchTomlContent(filepath.Join(source, "default-versions-example", "expected.toml")))
+			})
+		})
+
+		context("when --default-versions is error", func() {
+			it("prints an error, exits non-zero and leaves the buildpack.toml unchanged", func() {
+				original, err := os.ReadFile(filepath.Join(source, "default-versions-example", "buildpack.toml"))
+				Expect(err).NotTo(HaveOccurred())
+
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "default-versions-example", "buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+					"--default-versions", "error",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("found default versions that match no dependency version: some-dependency 1.2.3"))
+
+				Expect(os.ReadFile(filepath.Join(source, "default-versions-example", "buildpack.toml"))).To(Equal(original))
+			})
+		})
+	})
+
 	context("failure cases", func() {
+		context("neither --metadata-file nor --metadata-url is set", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("at least one of the flags in the group [metadata-file metadata-url] is required"))
+			})
+		})
+
+		context("the metadata files have different checksums for the same dependency", func() {
+			var tmpDir string
+
+			it.Before(func() {
+				tmpDir = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(tmpDir, "metadata.json"), []byte(`[
+					{"id": "some-dependency", "version": "1.9.9", "uri": "another-dep-uri", "stacks": ["another-stack"], "checksum": "sha256:a7dfc881def4074a310bf1a134a23ba902e7b45c93d10a2464423b8ca54baba7"}
+				]`), 0644)).To(Succeed())
+			})
+
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "multi-source-example", "metadata"),
+					"--metadata-file", filepath.Join(tmpDir, "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("conflicting checksums for some-dependency 1.9.9 [another-stack]: sha256:98daf4814ce7f3f08a3761d6bd5d516bdef7b34cb772ce5fa5bdc93554bb30f9 in"))
+			})
+		})
+
+		context("the metadata file pattern matches no files", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "multi-source-example", "metadata", "*.yml"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("no metadata files match"))
+			})
+		})
+
+		context("the --unconstrained flag is invalid", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-buildpack.toml"),
+					"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+					"--unconstrained", "ignore",
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(`invalid unconstrained policy "ignore"`))
+			})
+		})
+
 		context("the --buildpack-file flag is missing", func() {
 			it("prints an error and exits non-zero", func() {
 				command := exec.Command(
@@ -181,7 +989,7 @@ func testUpdateDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S)
 				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
-				Expect(string(buffer.Contents())).To(ContainSubstring("Error: required flag(s) \"buildpack-file\" not set"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("Error: at least one of the flags in the group [buildpack-file extension-file] is required"))
 			})
 		})
 
@@ -217,7 +1025,7 @@ func testUpdateDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S)
 				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
-				Expect(string(buffer.Contents())).To(ContainSubstring("failed to open metadata.json file"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to open metadata file"))
 			})
 		})
 
@@ -244,7 +1052,7 @@ func testUpdateDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S)
 				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
-				Expect(string(buffer.Contents())).To(ContainSubstring("failed decode metadata.json"))
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to decode metadata file"))
 			})
 		})
 
@@ -0,0 +1,157 @@
+package integration_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/jam/v2/integration/matchers"
+	"github.com/paketo-buildpacks/occam"
+)
+
+func testUpdateExtensionDependenciesFromMetadata(t *testing.T, context spec.G, it spec.S) {
+	var (
+		withT      = NewWithT(t)
+		Expect     = withT.Expect
+		Eventually = withT.Eventually
+
+		source string
+		err    error
+	)
+
+	it.Before(func() {
+		source, err = occam.Source(filepath.Join("testdata", "update-extension-dependency-from-metadata"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(source)).To(Succeed())
+	})
+
+	context("when there is one new dependency to add", func() {
+		it("updates the extension.toml dependencies from a metadata file", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--extension-file", filepath.Join(source, "basic-extension.toml"),
+				"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring("Updating extension.toml with new versions:  [1.9.9]"))
+
+			Expect(filepath.Join(source, "basic-extension.toml")).To(MatchTomlContent(filepath.Join(source, "one-dependency-example", "expected.toml")))
+		})
+
+		it("keeps the layout and the tables that are not dependencies", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--extension-file", filepath.Join(source, "basic-extension.toml"),
+				"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			content, err := os.ReadFile(filepath.Join(source, "basic-extension.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring(`[extension]
+  id = "some-extension"`))
+			Expect(string(content)).To(ContainSubstring(`  [[metadata.configurations]]
+    build = true`))
+			Expect(string(content)).To(ContainSubstring(`[[metadata.dependency-constraints]]
+  constraint = "1.*"`))
+		})
+	})
+
+	context("when there is one new version with two variants for different stacks", func() {
+		it("updates the extension.toml dependencies from a metadata file", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--extension-file", filepath.Join(source, "basic-extension.toml"),
+				"--metadata-file", filepath.Join(source, "stack-variant-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-extension.toml")).To(MatchTomlContent(filepath.Join(source, "stack-variant-example", "expected.toml")))
+		})
+	})
+
+	context("the new dependencies are out of constraints", func() {
+		it("the extension.toml is not updated", func() {
+			command := exec.Command(
+				path,
+				"update-dependencies",
+				"--extension-file", filepath.Join(source, "basic-extension.toml"),
+				"--metadata-file", filepath.Join(source, "out-of-constraint-example", "metadata.json"),
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+			Expect(filepath.Join(source, "basic-extension.toml")).To(MatchTomlContent(filepath.Join(source, "out-of-constraint-example", "expected.toml")))
+		})
+	})
+
+	context("failure cases", func() {
+		context("both the --buildpack-file and --extension-file flags are set", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--buildpack-file", filepath.Join(source, "basic-extension.toml"),
+					"--extension-file", filepath.Join(source, "basic-extension.toml"),
+					"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("if any flags in the group [buildpack-file extension-file] are set none of the others can be"))
+			})
+		})
+
+		context("the extension file does not exist", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(
+					path,
+					"update-dependencies",
+					"--extension-file", "/no/such/file",
+					"--metadata-file", filepath.Join(source, "one-dependency-example", "metadata.json"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring("failed to parse extension.toml"))
+			})
+		})
+	})
+}
@@ -0,0 +1,213 @@
+package integration_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testUpdateStack(t *testing.T, context spec.G, it spec.S) {
+	var (
+		withT      = NewWithT(t)
+		Expect     = withT.Expect
+		Eventually = withT.Eventually
+
+		server    *httptest.Server
+		host      string
+		stackDir  string
+		oldDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digests   map[string]string
+	)
+
+	it.Before(func() {
+		server = httptest.NewServer(registry.New())
+		host = strings.TrimPrefix(server.URL, "http://")
+
+		digests = map[string]string{}
+		for uri, platforms := range map[string][]v1.Platform{
+			"some-org/base:jammy":     {{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}},
+			"some-org/run-base:jammy": {{OS: "linux", Architecture: "amd64"}, {OS: "linux", Architecture: "arm64"}},
+			"some-org/amd64-only:1.0": {{OS: "linux", Architecture: "amd64"}},
+			"some-org/arm64-base:2.0": {{OS: "linux", Architecture: "arm64"}},
+		} {
+			var index v1.ImageIndex = empty.Index
+			for _, platform := range platforms {
+				image, err := random.Image(1024, 1)
+				Expect(err).NotTo(HaveOccurred())
+
+				index = mutate.AppendManifests(index, mutate.IndexAddendum{
+					Add:        image,
+					Descriptor: v1.Descriptor{Platform: &platform},
+				})
+			}
+
+			ref, err := name.ParseReference(fmt.Sprintf("%s/%s", host, uri))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.WriteIndex(ref, index)).To(Succeed())
+
+			digest, err := index.Digest()
+			Expect(err).NotTo(HaveOccurred())
+			digests[uri] = digest.String()
+		}
+
+		stackDir = t.TempDir()
+
+		Expect(os.WriteFile(filepath.Join(stackDir, "build.Dockerfile"), []byte(fmt.Sprintf(`ARG base_image=%[1]s/some-org/base:jammy@%[2]s
+
+FROM ${base_image}
+RUN echo build
+`, host, oldDigest)), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(stackDir, "run.Dockerfile"), []byte(fmt.Sprintf(`ARG base_image
+
+FROM --platform=$BUILDPLATFORM %[1]s/some-org/amd64-only:1.0@%[2]s AS tools
+
+FROM $base_image
+COPY --from=tools /some-file /some-file
+`, host, oldDigest)), 0600)).To(Succeed())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	writeStack := func(runArgs string) {
+		Expect(os.WriteFile(filepath.Join(stackDir, "stack.toml"), []byte(fmt.Sprintf(`id = "some-stack-id"
+platforms = ["linux/amd64", "linux/arm64"]
+
+[build]
+  dockerfile = "./build.Dockerfile"
+  uid = 1000
+  gid = 1000
+
+[run]
+  dockerfile = "./run.Dockerfile"
+  uid = 1001
+  gid = 1000
+
+%s
+`, runArgs)), 0600)).To(Succeed())
+	}
+
+	it("updates the pinned base image digests in the Dockerfiles and stack descriptor", func() {
+		writeStack(fmt.Sprintf(`  [run.args]
+    base_image = "%[1]s/some-org/run-base:jammy@%[2]s"
+
+  [run.platforms."linux/arm64".args]
+    base_image = "%[1]s/some-org/arm64-base:2.0@%[2]s"
+`, host, oldDigest))
+
+		command := exec.Command(
+			path,
+			"update-stack",
+			"--config", filepath.Join(stackDir, "stack.toml"),
+		)
+
+		buffer := gbytes.NewBuffer()
+		session, err := gexec.Start(command, buffer, buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+
+		Expect(string(buffer.Contents())).To(MatchRegexp(fmt.Sprintf(`%s/some-org/base:jammy\s+%s\s+%s`, host, oldDigest, digests["some-org/base:jammy"])))
+
+		build, err := os.ReadFile(filepath.Join(stackDir, "build.Dockerfile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(build)).To(Equal(fmt.Sprintf(`ARG base_image=%[1]s/some-org/base:jammy@%[2]s
+
+FROM ${base_image}
+RUN echo build
+`, host, digests["some-org/base:jammy"])))
+
+		run, err := os.ReadFile(filepath.Join(stackDir, "run.Dockerfile"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(run)).To(ContainSubstring(fmt.Sprintf("FROM --platform=$BUILDPLATFORM %s/some-org/amd64-only:1.0@%s AS tools", host, digests["some-org/amd64-only:1.0"])))
+
+		stack, err := os.ReadFile(filepath.Join(stackDir, "stack.toml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(stack)).To(ContainSubstring(fmt.Sprintf(`base_image = "%s/some-org/run-base:jammy@%s"`, host, digests["some-org/run-base:jammy"])))
+		Expect(string(stack)).To(ContainSubstring(fmt.Sprintf(`base_image = "%s/some-org/arm64-base:2.0@%s"`, host, digests["some-org/arm64-base:2.0"])))
+	})
+
+	context("the --dry-run flag is set", func() {
+		it("prints a diff without changing the files", func() {
+			writeStack(fmt.Sprintf(`  [run.args]
+    base_image = "%s/some-org/run-base:jammy@%s"
+`, host, oldDigest))
+
+			original, err := os.ReadFile(filepath.Join(stackDir, "build.Dockerfile"))
+			Expect(err).NotTo(HaveOccurred())
+
+			command := exec.Command(
+				path,
+				"update-stack",
+				"--config", filepath.Join(stackDir, "stack.toml"),
+				"--dry-run",
+			)
+
+			buffer := gbytes.NewBuffer()
+			session, err := gexec.Start(command, buffer, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), func() string { return string(buffer.Contents()) })
+			Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("+ARG base_image=%s/some-org/base:jammy@%s", host, digests["some-org/base:jammy"])))
+
+			build, err := os.ReadFile(filepath.Join(stackDir, "build.Dockerfile"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(build).To(Equal(original))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when a base image is not available for a platform of the stack", func() {
+			it("prints an error and exits non-zero", func() {
+				writeStack(fmt.Sprintf(`  [run.args]
+    base_image = "%s/some-org/amd64-only:1.0@%s"
+`, host, oldDigest))
+
+				command := exec.Command(
+					path,
+					"update-stack",
+					"--config", filepath.Join(stackDir, "stack.toml"),
+				)
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(fmt.Sprintf("%s/some-org/amd64-only:1.0 is not available for linux/arm64", host)))
+			})
+		})
+
+		context("when the --config flag is missing", func() {
+			it("prints an error and exits non-zero", func() {
+				command := exec.Command(path, "update-stack")
+
+				buffer := gbytes.NewBuffer()
+				session, err := gexec.Start(command, buffer, buffer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(session).Should(gexec.Exit(1), func() string { return string(buffer.Contents()) })
+				Expect(string(buffer.Contents())).To(ContainSubstring(`required flag(s) "config" not set`))
+			})
+		})
+	})
+}
@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+const (
+	lifecycleAPIsLabel   = "io.buildpacks.lifecycle.apis"
+	buildpackLayersLabel = "io.buildpacks.buildpack.layers"
+	extensionLayersLabel = "io.buildpacks.extension.layers"
+)
+
+// LifecycleAPIs are the buildpack and platform APIs that a lifecycle
+// supports, as declared by the io.buildpacks.lifecycle.apis label of its
+// image.
+type LifecycleAPIs struct {
+	Buildpack APIVersions `json:"buildpack"`
+	Platform  APIVersions `json:"platform"`
+}
+
+type APIVersions struct {
+	Deprecated []string `json:"deprecated"`
+	Supported  []string `json:"supported"`
+}
+
+// Status returns whether the given API is supported, deprecated or
+// unsupported.
+func (v APIVersions) Status(api string) string {
+	switch {
+	case slices.Contains(v.Deprecated, api):
+		return APIStatusDeprecated
+	case slices.Contains(v.Supported, api):
+		return APIStatusSupported
+	default:
+		return APIStatusUnsupported
+	}
+}
+
+const (
+	APIStatusSupported   = "supported"
+	APIStatusDeprecated  = "deprecated"
+	APIStatusUnsupported = "UNSUPPORTED"
+)
+
+// BuildpackAPI is the buildpack API implemented by a buildpack or extension
+// that is packaged in an image.
+type BuildpackAPI struct {
+	ID      string
+	Version string
+	API     string
+}
+
+// APICompatibility is the result of checking the buildpack APIs of the
+// buildpacks and extensions of a builder, and the platform APIs that the
+// builder is used with, against its lifecycle.
+type APICompatibility struct {
+	LifecycleVersion string
+	Lifecycle        LifecycleAPIs
+	Buildpacks       []BuildpackAPI
+	Platforms        []string
+}
+
+// Unsupported returns the buildpacks whose API is not supported by the
+// lifecycle.
+func (c APICompatibility) Unsupported() []BuildpackAPI {
+	var unsupported []BuildpackAPI
+	for _, buildpack := range c.Buildpacks {
+		if c.Lifecycle.Buildpack.Status(buildpack.API) == APIStatusUnsupported {
+			unsupported = append(unsupported, buildpack)
+		}
+	}
+
+	return unsupported
+}
+
+// UnsupportedPlatforms returns the platform APIs that are not supported by the
+// lifecycle.
+func (c APICompatibility) UnsupportedPlatforms() []string {
+	var unsupported []string
+	for _, platform := range c.Platforms {
+		if c.Lifecycle.Platform.Status(platform) == APIStatusUnsupported {
+			unsupported = append(unsupported, platform)
+		}
+	}
+
+	return unsupported
+}
+
+// Print writes a table of the buildpack API of every buildpack and its
+// status for the lifecycle, followed by a table of the status of every
+// platform API.
+func (c APICompatibility) Print(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Lifecycle %s supports buildpack APIs %s and platform APIs %s\n",
+		c.LifecycleVersion, strings.Join(c.Lifecycle.Buildpack.Supported, ", "), strings.Join(c.Lifecycle.Platform.Supported, ", "))
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, err = fmt.Fprintln(tw, "BUILDPACK\tVERSION\tAPI\tSTATUS")
+	if err != nil {
+		return err
+	}
+
+	for _, buildpack := range c.Buildpacks {
+		_, err = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", buildpack.ID, buildpack.Version, buildpack.API, c.Lifecycle.Buildpack.Status(buildpack.API))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if len(c.Platforms) == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprintln(tw, "PLATFORM API\tSTATUS")
+	if err != nil {
+		return err
+	}
+
+	for _, platform := range c.Platforms {
+		_, err = fmt.Fprintf(tw, "%s\t%s\n", platform, c.Lifecycle.Platform.Status(platform))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// Check returns an error listing the buildpacks and platform APIs that are not
+// supported by the lifecycle, or nil when every API is supported.
+func (c APICompatibility) Check() error {
+	unsupported := c.Unsupported()
+	unsupportedPlatforms := c.UnsupportedPlatforms()
+	if len(unsupported) == 0 && len(unsupportedPlatforms) == 0 {
+		return nil
+	}
+
+	var descriptions []string
+	if len(unsupported) > 0 {
+		var buildpacks []string
+		for _, buildpack := range unsupported {
+			buildpacks = append(buildpacks, fmt.Sprintf("%s %s (buildpack API %s)", buildpack.ID, buildpack.Version, buildpack.API))
+		}
+		descriptions = append(descriptions, fmt.Sprintf("the buildpack API of %s", strings.Join(buildpacks, ", ")))
+	}
+
+	if len(unsupportedPlatforms) > 0 {
+		descriptions = append(descriptions, fmt.Sprintf("platform API %s", strings.Join(unsupportedPlatforms, ", ")))
+	}
+
+	return fmt.Errorf("lifecycle %s does not support %s", c.LifecycleVersion, strings.Join(descriptions, " or "))
+}
+
+// ParseLifecycleAPIs reads the supported APIs from the labels of a lifecycle
+// image.
+func ParseLifecycleAPIs(labels map[string]string) (LifecycleAPIs, error) {
+	label, ok := labels[lifecycleAPIsLabel]
+	if !ok {
+		return LifecycleAPIs{}, fmt.Errorf("image has no label '%s'", lifecycleAPIsLabel)
+	}
+
+	var apis LifecycleAPIs
+	err := json.Unmarshal([]byte(label), &apis)
+	if err != nil {
+		return LifecycleAPIs{}, fmt.Errorf("failed to parse label '%s': %w", lifecycleAPIsLabel, err)
+	}
+
+	return apis, nil
+}
+
+// ParseBuildpackAPIs reads the buildpack API of every buildpack and extension
+// that is packaged in an image from its layers labels, sorted by ID and
+// version.
+func ParseBuildpackAPIs(labels map[string]string) ([]BuildpackAPI, error) {
+	var apis []BuildpackAPI
+	for _, key := range []string{buildpackLayersLabel, extensionLayersLabel} {
+		label, ok := labels[key]
+		if !ok {
+			continue
+		}
+
+		var layers map[string]map[string]struct {
+			API string `json:"api"`
+		}
+		err := json.Unmarshal([]byte(label), &layers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse label '%s': %w", key, err)
+		}
+
+		for id, versions := range layers {
+			for version, layer := range versions {
+				apis = append(apis, BuildpackAPI{ID: id, Version: version, API: layer.API})
+			}
+		}
+	}
+
+	if len(apis) == 0 {
+		return nil, fmt.Errorf("image has no label '%s' or '%s'", buildpackLayersLabel, extensionLayersLabel)
+	}
+
+	sort.Slice(apis, func(i, j int) bool {
+		if apis[i].ID != apis[j].ID {
+			return apis[i].ID < apis[j].ID
+		}
+		return apis[i].Version < apis[j].Version
+	})
+
+	return apis, nil
+}
+
+// GetImageLabels returns the labels of the config of the given image.
+func GetImageLabels(uri string) (map[string]string, error) {
+	return getImageLabels(uri, nil)
+}
+
+func getImageLabels(uri string, insecure InsecureRegistries, options ...remote.Option) (map[string]string, error) {
+	ref, err := insecure.ParseReference(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %w", uri, err)
+	}
+
+	image, err := remote.Image(ref, append(insecure.RemoteOptions(ref.Context().Registry), options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels for %s: %w", uri, err)
+	}
+
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels for %s: %w", uri, err)
+	}
+
+	return config.Config.Labels, nil
+}
@@ -0,0 +1,287 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testAPICompatibility(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		compatibility internal.APICompatibility
+	)
+
+	it.Before(func() {
+		compatibility = internal.APICompatibility{
+			LifecycleVersion: "0.17.0",
+			Lifecycle: internal.LifecycleAPIs{
+				Buildpack: internal.APIVersions{
+					Deprecated: []string{"0.7"},
+					Supported:  []string{"0.7", "0.8", "0.9"},
+				},
+				Platform: internal.APIVersions{
+					Supported: []string{"0.12", "0.13"},
+				},
+			},
+			Buildpacks: []internal.BuildpackAPI{
+				{ID: "some-buildpack", Version: "1.2.3", API: "0.8"},
+				{ID: "other-buildpack", Version: "2.0.0", API: "0.7"},
+				{ID: "old-buildpack", Version: "0.1.0", API: "0.2"},
+			},
+		}
+	})
+
+	context("APIVersions.Status", func() {
+		it("returns the status of the API", func() {
+			Expect(compatibility.Lifecycle.Buildpack.Status("0.9")).To(Equal(internal.APIStatusSupported))
+			Expect(compatibility.Lifecycle.Buildpack.Status("0.7")).To(Equal(internal.APIStatusDeprecated))
+			Expect(compatibility.Lifecycle.Buildpack.Status("0.10")).To(Equal(internal.APIStatusUnsupported))
+		})
+	})
+
+	context("Check", func() {
+		it("returns an error listing the buildpacks with an unsupported API", func() {
+			Expect(compatibility.Unsupported()).To(Equal([]internal.BuildpackAPI{
+				{ID: "old-buildpack", Version: "0.1.0", API: "0.2"},
+			}))
+
+			err := compatibility.Check()
+			Expect(err).To(MatchError("lifecycle 0.17.0 does not support the buildpack API of old-buildpack 0.1.0 (buildpack API 0.2)"))
+		})
+
+		context("when a platform API is not supported", func() {
+			it("returns an error listing the buildpacks and platform APIs that are not supported", func() {
+				compatibility.Platforms = []string{"0.13", "0.9", "0.14"}
+				Expect(compatibility.UnsupportedPlatforms()).To(Equal([]string{"0.9", "0.14"}))
+
+				err := compatibility.Check()
+				Expect(err).To(MatchError("lifecycle 0.17.0 does not support the buildpack API of old-buildpack 0.1.0 (buildpack API 0.2) or platform API 0.9, 0.14"))
+
+				compatibility.Buildpacks = compatibility.Buildpacks[:2]
+				err = compatibility.Check()
+				Expect(err).To(MatchError("lifecycle 0.17.0 does not support platform API 0.9, 0.14"))
+			})
+		})
+
+		context("when every API is supported", func() {
+			it("returns nil", func() {
+				compatibility.Buildpacks = compatibility.Buildpacks[:2]
+				compatibility.Platforms = []string{"0.13"}
+				Expect(compatibility.Check()).To(Succeed())
+			})
+		})
+	})
+
+	context("Print", func() {
+		it("prints the lifecycle APIs and the status of every buildpack API", func() {
+			buffer := bytes.NewBuffer(nil)
+			Expect(compatibility.Print(buffer)).To(Succeed())
+			Expect(buffer.String()).To(Equal(strings.Join([]string{
+				"Lifecycle 0.17.0 supports buildpack APIs 0.7, 0.8, 0.9 and platform APIs 0.12, 0.13",
+				"BUILDPACK        VERSION  API  STATUS",
+				"some-buildpack   1.2.3    0.8  supported",
+				"other-buildpack  2.0.0    0.7  deprecated",
+				"old-buildpack    0.1.0    0.2  UNSUPPORTED",
+				"",
+			}, "\n")))
+		})
+
+		context("when platform APIs are given", func() {
+			it("also prints the status of every platform API", func() {
+				compatibility.Buildpacks = compatibility.Buildpacks[:1]
+				compatibility.Platforms = []string{"0.13", "0.9"}
+
+				buffer := bytes.NewBuffer(nil)
+				Expect(compatibility.Print(buffer)).To(Succeed())
+				Expect(buffer.String()).To(Equal(strings.Join([]string{
+					"Lifecycle 0.17.0 supports buildpack APIs 0.7, 0.8, 0.9 and platform APIs 0.12, 0.13",
+					"BUILDPACK       VERSION  API  STATUS",
+					"some-buildpack  1.2.3    0.8  supported",
+					"PLATFORM API  STATUS",
+					"0.13          supported",
+					"0.9           UNSUPPORTED",
+					"",
+				}, "\n")))
+			})
+		})
+	})
+
+	context("FindLatestImage WithSupportedAPIs", func() {
+		var (
+			server *httptest.Server
+			host   string
+		)
+
+		it.Before(func() {
+			server = httptest.NewServer(registry.New())
+			host = strings.TrimPrefix(server.URL, "http://")
+
+			for version, label := range map[string]string{
+				"0.17.0": `{"buildpack":{"deprecated":[],"supported":["0.7","0.8","0.9"]},"platform":{"deprecated":[],"supported":["0.12","0.13"]}}`,
+				"0.18.0": `{"buildpack":{"deprecated":[],"supported":["0.9","0.10"]},"platform":{"deprecated":[],"supported":["0.13","0.14"]}}`,
+			} {
+				image, err := random.Image(1024, 1)
+				Expect(err).NotTo(HaveOccurred())
+
+				config, err := image.ConfigFile()
+				Expect(err).NotTo(HaveOccurred())
+
+				config.Config.Labels = map[string]string{"io.buildpacks.lifecycle.apis": label}
+
+				image, err = mutate.ConfigFile(image, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/lifecycle:%s", host, version))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(remote.Write(ref, image)).To(Succeed())
+			}
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("returns the highest version that supports every buildpack and platform API", func() {
+			image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/lifecycle", host), "",
+				internal.WithSupportedAPIs([]internal.BuildpackAPI{{ID: "some-buildpack", Version: "1.2.3", API: "0.9"}}, []string{"0.13"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(image.Version).To(Equal("0.18.0"))
+
+			image, err = internal.FindLatestImage(fmt.Sprintf("%s/some-org/lifecycle", host), "",
+				internal.WithRegistryLookup(internal.NewRegistryLookup(nil, io.Discard)),
+				internal.WithSupportedAPIs([]internal.BuildpackAPI{{ID: "some-buildpack", Version: "1.2.3", API: "0.8"}}, nil))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(image.Version).To(Equal("0.17.0"))
+		})
+
+		context("when no version supports every API", func() {
+			it("returns an error", func() {
+				_, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/lifecycle", host), "",
+					internal.WithSupportedAPIs([]internal.BuildpackAPI{{ID: "some-buildpack", Version: "1.2.3", API: "0.8"}}, []string{"0.14"}))
+				Expect(err).To(MatchError(ContainSubstring("that supports every buildpack and platform API")))
+			})
+		})
+	})
+
+	context("ParseLifecycleAPIs", func() {
+		it("parses the lifecycle APIs label", func() {
+			apis, err := internal.ParseLifecycleAPIs(map[string]string{
+				"io.buildpacks.lifecycle.apis": `{"buildpack":{"deprecated":["0.7"],"supported":["0.7","0.8"]},"platform":{"deprecated":[],"supported":["0.13"]}}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apis).To(Equal(internal.LifecycleAPIs{
+				Buildpack: internal.APIVersions{Deprecated: []string{"0.7"}, Supported: []string{"0.7", "0.8"}},
+				Platform:  internal.APIVersions{Deprecated: []string{}, Supported: []string{"0.13"}},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the label is missing", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseLifecycleAPIs(map[string]string{})
+					Expect(err).To(MatchError("image has no label 'io.buildpacks.lifecycle.apis'"))
+				})
+			})
+
+			context("when the label cannot be parsed", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseLifecycleAPIs(map[string]string{"io.buildpacks.lifecycle.apis": "%%%"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse label 'io.buildpacks.lifecycle.apis'")))
+				})
+			})
+		})
+	})
+
+	context("ParseBuildpackAPIs", func() {
+		it("parses the buildpack and extension layers labels", func() {
+			apis, err := internal.ParseBuildpackAPIs(map[string]string{
+				"io.buildpacks.buildpack.layers": `{"some-buildpack":{"1.2.3":{"api":"0.8"}},"other-buildpack":{"2.0.0":{"api":"0.7"},"1.0.0":{"api":"0.7"}}}`,
+				"io.buildpacks.extension.layers": `{"some-extension":{"0.1.0":{"api":"0.9"}}}`,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(apis).To(Equal([]internal.BuildpackAPI{
+				{ID: "other-buildpack", Version: "1.0.0", API: "0.7"},
+				{ID: "other-buildpack", Version: "2.0.0", API: "0.7"},
+				{ID: "some-buildpack", Version: "1.2.3", API: "0.8"},
+				{ID: "some-extension", Version: "0.1.0", API: "0.9"},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when there are no layers labels", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseBuildpackAPIs(map[string]string{})
+					Expect(err).To(MatchError("image has no label 'io.buildpacks.buildpack.layers' or 'io.buildpacks.extension.layers'"))
+				})
+			})
+
+			context("when a label cannot be parsed", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseBuildpackAPIs(map[string]string{"io.buildpacks.buildpack.layers": "%%%"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse label 'io.buildpacks.buildpack.layers'")))
+				})
+			})
+		})
+	})
+
+	context("GetImageLabels", func() {
+		var (
+			server *httptest.Server
+			host   string
+		)
+
+		it.Before(func() {
+			server = httptest.NewServer(registry.New())
+			host = strings.TrimPrefix(server.URL, "http://")
+
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := image.ConfigFile()
+			Expect(err).NotTo(HaveOccurred())
+
+			config.Config.Labels = map[string]string{"some-label": "some-value"}
+
+			image, err = mutate.ConfigFile(image, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/some-image:1.0.0", host))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image)).To(Succeed())
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("returns the labels of the image config", func() {
+			labels, err := internal.GetImageLabels(fmt.Sprintf("%s/some-org/some-image:1.0.0", host))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(labels).To(Equal(map[string]string{"some-label": "some-value"}))
+		})
+
+		context("failure cases", func() {
+			context("when the image does not exist", func() {
+				it("returns an error", func() {
+					_, err := internal.GetImageLabels(fmt.Sprintf("%s/some-org/some-image:9.9.9", host))
+					Expect(err).To(MatchError(ContainSubstring("failed to get labels for")))
+				})
+			})
+		})
+	})
+}
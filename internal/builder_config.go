@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/pelletier/go-toml"
@@ -19,12 +23,19 @@ type BuilderConfig struct {
 	Build          Build                         `toml:"build,omitempty"`
 	Run            Run                           `toml:"run,omitempty"`
 	Stack          BuilderConfigStack            `toml:"stack,omitempty"`
+	System         BuilderConfigSystem           `toml:"system,omitempty"`
 	Targets        []BuilderConfigTarget         `toml:"targets"`
+	Metadata       ConfigMetadata                `toml:"metadata,omitempty"`
+
+	// Unrecognized holds the tables and keys of the parsed builder.toml that
+	// are not modeled above so that they are written back unchanged.
+	Unrecognized *toml.Tree `toml:"-"`
 }
 
 type BuilderConfigBuildpack struct {
-	URI     string `toml:"uri"`
-	Version string `toml:"version"`
+	URI        string `toml:"uri"`
+	Version    string `toml:"version"`
+	Constraint string `toml:"jam-constraint,omitempty"`
 }
 
 type Run struct {
@@ -32,7 +43,8 @@ type Run struct {
 }
 
 type ImageRegistry struct {
-	Image string `toml:"image"`
+	Image   string   `toml:"image"`
+	Mirrors []string `toml:"mirrors,omitempty"`
 }
 
 type Build struct {
@@ -40,9 +52,10 @@ type Build struct {
 }
 
 type BuilderConfigExtension struct {
-	ID      string `toml:"id"`
-	URI     string `toml:"uri"`
-	Version string `toml:"version"`
+	ID         string `toml:"id"`
+	URI        string `toml:"uri"`
+	Version    string `toml:"version"`
+	Constraint string `toml:"jam-constraint,omitempty"`
 }
 
 type BuilderConfigLifecycle struct {
@@ -76,6 +89,21 @@ type BuilderConfigStack struct {
 	RunImageMirrors []string `toml:"run-image-mirrors,omitempty"`
 }
 
+type BuilderConfigSystem struct {
+	Pre  BuilderConfigSystemBuildpacks `toml:"pre,omitempty"`
+	Post BuilderConfigSystemBuildpacks `toml:"post,omitempty"`
+}
+
+type BuilderConfigSystemBuildpacks struct {
+	Buildpacks []BuilderConfigSystemBuildpack `toml:"buildpacks,omitempty"`
+}
+
+type BuilderConfigSystemBuildpack struct {
+	ID       string `toml:"id"`
+	Version  string `toml:"version,omitempty"`
+	Optional bool   `toml:"optional,omitempty"`
+}
+
 type BuilderConfigTarget struct {
 	OS   string `toml:"os"`
 	Arch string `toml:"arch"`
@@ -95,6 +123,10 @@ func (b *BuilderConfigBuildpack) UnmarshalTOML(v interface{}) error {
 		if version, ok := m["version"].(string); ok {
 			b.Version = version
 		}
+
+		if constraint, ok := m["jam-constraint"].(string); ok {
+			b.Constraint = constraint
+		}
 	}
 
 	if b.URI != "" {
@@ -128,6 +160,10 @@ func (b *BuilderConfigExtension) UnmarshalTOML(v interface{}) error {
 		if id, ok := m["id"].(string); ok {
 			b.ID = id
 		}
+
+		if constraint, ok := m["jam-constraint"].(string); ok {
+			b.Constraint = constraint
+		}
 	}
 
 	if b.URI != "" {
@@ -155,37 +191,84 @@ func ParseBuilderConfig(path string) (BuilderConfig, error) {
 		}
 	}()
 
+	tree, err := toml.LoadReader(file)
+	if err != nil {
+		return BuilderConfig{}, fmt.Errorf("failed to parse builder config: %w", err)
+	}
+
 	var config BuilderConfig
-	err = toml.NewDecoder(file).Decode(&config)
+	err = tree.Unmarshal(&config)
 	if err != nil {
 		return BuilderConfig{}, fmt.Errorf("failed to parse builder config: %w", err)
 	}
 
+	config.Unrecognized = unrecognizedTOML(tree, reflect.TypeOf(config))
+
 	return config, err // err should be nil here, but return err to catch deferred error
 }
 
-func OverwriteBuilderConfig(path string, config BuilderConfig) error {
+// MarshalBuilderConfig encodes the whole of the given builder config.
+func MarshalBuilderConfig(config BuilderConfig) ([]byte, error) {
+	config.Buildpacks = slices.Clone(config.Buildpacks)
 	for i, buildpack := range config.Buildpacks {
 		if !strings.HasPrefix(buildpack.URI, "docker://") {
 			config.Buildpacks[i].URI = fmt.Sprintf("docker://%s", buildpack.URI)
 		}
 	}
 
+	config.Extensions = slices.Clone(config.Extensions)
 	for i, extension := range config.Extensions {
 		if !strings.HasPrefix(extension.URI, "docker://") {
 			config.Extensions[i].URI = fmt.Sprintf("docker://%s", extension.URI)
 		}
 	}
 
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC, 0600)
+	buffer := bytes.NewBuffer(nil)
+	err := toml.NewEncoder(buffer).Encode(config)
 	if err != nil {
-		return fmt.Errorf("failed to open builder config file: %w", err)
+		return nil, fmt.Errorf("failed to write builder config: %w", err)
 	}
 
-	err = toml.NewEncoder(file).Encode(config)
+	if config.Unrecognized == nil {
+		return buffer.Bytes(), nil
+	}
+
+	tree, err := toml.LoadBytes(buffer.Bytes())
 	if err != nil {
-		return fmt.Errorf("failed to write builder config: %w", err)
+		return nil, fmt.Errorf("failed to write builder config: %w", err)
 	}
 
-	return nil
+	mergeTOML(tree, config.Unrecognized)
+
+	content, err := tree.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write builder config: %w", err)
+	}
+
+	return content, nil
+}
+
+// RenderBuilderConfig returns the content that OverwriteBuilderConfig writes to
+// the file at path. Only the values that changed are edited in the existing
+// file, see PreserveTOMLLayout, which writes its warnings to output.
+func RenderBuilderConfig(path string, config BuilderConfig, output io.Writer) ([]byte, error) {
+	content, err := MarshalBuilderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return PreserveTOMLLayout(path, content, output, func(content []byte) (interface{}, error) {
+		var config BuilderConfig
+		err := toml.Unmarshal(content, &config)
+		return config, err
+	}), nil
+}
+
+func OverwriteBuilderConfig(path string, config BuilderConfig, output io.Writer) error {
+	content, err := RenderBuilderConfig(path, config, output)
+	if err != nil {
+		return err
+	}
+
+	return overwriteFile(path, content, "builder config")
 }
@@ -1,6 +1,7 @@
 package internal_test
 
 import (
+	"io"
 	"os"
 	"testing"
 
@@ -160,6 +161,134 @@ description = "Some description"
 			}))
 		})
 
+		context("when version holds are set", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[[buildpacks]]
+	uri = "docker://some-registry/some-repository/some-buildpack-id:1.2.3"
+  version = "1.2.3"
+  jam-constraint = "< 2.0.0"
+
+[[extensions]]
+  id = "some-repository/some-extension"
+  version = "0.0.3"
+  uri = "some-registry/some-repository/some-extension:0.0.3"
+  jam-constraint = "~0.0.3"
+
+[metadata.jam.constraints]
+  lifecycle = "< 0.21.0"
+  "some-repository/other-buildpack-id" = "0.x"
+					`), 0600)).To(Succeed())
+			})
+
+			it("parses the constraints", func() {
+				config, err := internal.ParseBuilderConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Buildpacks).To(Equal([]internal.BuilderConfigBuildpack{
+					{
+						URI:        "some-registry/some-repository/some-buildpack-id:1.2.3",
+						Version:    "1.2.3",
+						Constraint: "< 2.0.0",
+					},
+				}))
+				Expect(config.Extensions).To(Equal([]internal.BuilderConfigExtension{
+					{
+						ID:         "some-repository/some-extension",
+						URI:        "some-registry/some-repository/some-extension:0.0.3",
+						Version:    "0.0.3",
+						Constraint: "~0.0.3",
+					},
+				}))
+				Expect(config.Metadata).To(Equal(internal.ConfigMetadata{
+					Jam: internal.JamMetadata{
+						Constraints: map[string]string{
+							"lifecycle":                          "< 0.21.0",
+							"some-repository/other-buildpack-id": "0.x",
+						},
+					},
+				}))
+			})
+		})
+
+		context("when system buildpacks and run image mirrors are set", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[[system.pre.buildpacks]]
+  id = "some-repository/some-system-buildpack"
+  version = "1.2.3"
+
+[[system.post.buildpacks]]
+  id = "some-repository/other-system-buildpack"
+  optional = true
+
+[[run.images]]
+  image = "some-registry/some-repository/run:1.2.3"
+  mirrors = ["other-registry/some-repository/run:1.2.3"]
+					`), 0600)).To(Succeed())
+			})
+
+			it("parses them", func() {
+				config, err := internal.ParseBuilderConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.System).To(Equal(internal.BuilderConfigSystem{
+					Pre: internal.BuilderConfigSystemBuildpacks{
+						Buildpacks: []internal.BuilderConfigSystemBuildpack{
+							{ID: "some-repository/some-system-buildpack", Version: "1.2.3"},
+						},
+					},
+					Post: internal.BuilderConfigSystemBuildpacks{
+						Buildpacks: []internal.BuilderConfigSystemBuildpack{
+							{ID: "some-repository/other-system-buildpack", Optional: true},
+						},
+					},
+				}))
+				Expect(config.Run.Images).To(Equal([]internal.ImageRegistry{
+					{
+						Image:   "some-registry/some-repository/run:1.2.3",
+						Mirrors: []string{"other-registry/some-repository/run:1.2.3"},
+					},
+				}))
+				Expect(config.Unrecognized).To(BeNil())
+			})
+		})
+
+		context("when the file has fields that are not modeled", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:0.0.10"
+  version = "0.0.10"
+  some-key = "some-value"
+
+[lifecycle]
+  uri = "https://example.com/lifecycle.tgz"
+  version = "0.10.2"
+
+[[build.env]]
+  name = "SOME_ENV"
+  value = "some-value"
+					`), 0600)).To(Succeed())
+			})
+
+			it("keeps them as unrecognized fields", func() {
+				config, err := internal.ParseBuilderConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Lifecycle.Version).To(Equal("0.10.2"))
+				Expect(config.Unrecognized).NotTo(BeNil())
+				Expect(config.Unrecognized.String()).To(MatchTOML(`
+[[buildpacks]]
+  some-key = "some-value"
+
+[lifecycle]
+  uri = "https://example.com/lifecycle.tgz"
+
+[[build.env]]
+  name = "SOME_ENV"
+  value = "some-value"
+				`))
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the file cannot be opened", func() {
 				it.Before(func() {
@@ -297,7 +426,7 @@ description = "Some description"
 						Arch: "arm64",
 					},
 				},
-			})
+			}, io.Discard)
 			Expect(err).NotTo(HaveOccurred())
 
 			contents, err := os.ReadFile(path)
@@ -355,6 +484,156 @@ description = "Some description"
 				`))
 		})
 
+		it("preserves version holds", func() {
+			err := internal.OverwriteBuilderConfig(path, internal.BuilderConfig{
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{
+						URI:        "some-registry/some-repository/some-buildpack-id:1.2.3",
+						Version:    "1.2.3",
+						Constraint: "< 2.0.0",
+					},
+				},
+				Metadata: internal.ConfigMetadata{
+					Jam: internal.JamMetadata{
+						Constraints: map[string]string{
+							"lifecycle": "< 0.21.0",
+						},
+					},
+				},
+			}, io.Discard)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(MatchTOML(`
+description = ""
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:1.2.3"
+  version = "1.2.3"
+  jam-constraint = "< 2.0.0"
+
+[lifecycle]
+  version = ""
+
+[metadata.jam.constraints]
+  lifecycle = "< 0.21.0"
+			`))
+		})
+
+		context("when the file is a builder.toml with comments", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`# some comment
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:0.0.9" # some other comment
+  version = "0.0.9"
+
+[lifecycle]
+  version = "0.10.2"
+`), 0600)).To(Succeed())
+			})
+
+			it("keeps the comments and layout of the unchanged lines", func() {
+				err := internal.OverwriteBuilderConfig(path, internal.BuilderConfig{
+					Description: "Some description",
+					Buildpacks: []internal.BuilderConfigBuildpack{
+						{
+							URI:     "some-registry/some-repository/some-buildpack-id:0.0.10",
+							Version: "0.0.10",
+						},
+					},
+					Lifecycle: internal.BuilderConfigLifecycle{
+						Version: "0.10.2",
+					},
+				}, io.Discard)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(`# some comment
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:0.0.10" # some other comment
+  version = "0.0.10"
+
+[lifecycle]
+  version = "0.10.2"
+`))
+			})
+		})
+
+		context("when the config was parsed with fields that are not modeled", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:0.0.9"
+  version = "0.0.9"
+  some-key = "some-value"
+
+[lifecycle]
+  uri = "https://example.com/lifecycle.tgz"
+  version = "0.10.2"
+
+[[build.env]]
+  name = "SOME_ENV"
+  value = "some-value"
+
+[[system.pre.buildpacks]]
+  id = "some-repository/some-buildpack-id"
+  version = "0.0.9"
+
+[[run.images]]
+  image = "some-registry/some-repository/run:1.2.3"
+  mirrors = ["other-registry/some-repository/run:1.2.3"]
+`), 0600)).To(Succeed())
+			})
+
+			it("writes them back", func() {
+				config, err := internal.ParseBuilderConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+
+				config.Buildpacks[0].URI = "some-registry/some-repository/some-buildpack-id:0.0.10"
+				config.Buildpacks[0].Version = "0.0.10"
+				config.System.Pre.Buildpacks[0].Version = "0.0.10"
+
+				// replace the file so that the config is written from scratch
+				Expect(os.WriteFile(path, []byte("previous contents of the file"), 0600)).To(Succeed())
+
+				err = internal.OverwriteBuilderConfig(path, config, io.Discard)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(MatchTOML(`
+description = ""
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:0.0.10"
+  version = "0.0.10"
+  some-key = "some-value"
+
+[lifecycle]
+  uri = "https://example.com/lifecycle.tgz"
+  version = "0.10.2"
+
+[[build.env]]
+  name = "SOME_ENV"
+  value = "some-value"
+
+[[system.pre.buildpacks]]
+  id = "some-repository/some-buildpack-id"
+  version = "0.0.10"
+
+[[run.images]]
+  image = "some-registry/some-repository/run:1.2.3"
+  mirrors = ["other-registry/some-repository/run:1.2.3"]
+				`))
+			})
+		})
+
 		it("overwrites the package.toml configuration without Extension", func() {
 			err := internal.OverwriteBuilderConfig(path, internal.BuilderConfig{
 				Description: "Some description",
@@ -405,7 +684,7 @@ description = "Some description"
 						Arch: "arm64",
 					},
 				},
-			})
+			}, io.Discard)
 			Expect(err).NotTo(HaveOccurred())
 
 			contents, err := os.ReadFile(path)
@@ -459,7 +738,7 @@ description = "Some description"
 				})
 
 				it("returns an error", func() {
-					err := internal.OverwriteBuilderConfig(path, internal.BuilderConfig{})
+					err := internal.OverwriteBuilderConfig(path, internal.BuilderConfig{}, io.Discard)
 					Expect(err).To(MatchError(ContainSubstring("failed to open builder config file:")))
 					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
 				})
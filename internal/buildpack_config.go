@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/pelletier/go-toml"
@@ -56,10 +58,48 @@ func ParseBuildpackConfig(path string) (BuildpackConfig, error) {
 	return config, err // err should be nil here, but return err to catch deferred error
 }
 
-func OverwriteBuildpackConfig(path string, config BuildpackConfig) error {
+// MarshalBuildpackConfig encodes the whole of the given buildpack config.
+func MarshalBuildpackConfig(config BuildpackConfig) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	err := toml.NewEncoder(buffer).Encode(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write buildpack config: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// RenderBuildpackConfig returns the content that OverwriteBuildpackConfig writes to
+// the file at path. Only the values that changed are edited in the existing
+// file, see PreserveTOMLLayout, which writes its warnings to output.
+func RenderBuildpackConfig(path string, config BuildpackConfig, output io.Writer) ([]byte, error) {
+	content, err := MarshalBuildpackConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return PreserveTOMLLayout(path, content, output, func(content []byte) (interface{}, error) {
+		var config BuildpackConfig
+		err := toml.Unmarshal(content, &config)
+		return config, err
+	}), nil
+}
+
+func OverwriteBuildpackConfig(path string, config BuildpackConfig, output io.Writer) error {
+	content, err := RenderBuildpackConfig(path, config, output)
+	if err != nil {
+		return err
+	}
+
+	return overwriteFile(path, content, "buildpack config")
+}
+
+// overwriteFile truncates and rewrites an existing file with the given
+// content. The file is not created if it does not already exist.
+func overwriteFile(path string, content []byte, description string) error {
 	file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC, 0600)
 	if err != nil {
-		return fmt.Errorf("failed to open buildpack config file: %w", err)
+		return fmt.Errorf("failed to open %s file: %w", description, err)
 	}
 	defer func() {
 		if err2 := file.Close(); err2 != nil && err == nil {
@@ -67,10 +107,10 @@ func OverwriteBuildpackConfig(path string, config BuildpackConfig) error {
 		}
 	}()
 
-	err = toml.NewEncoder(file).Encode(config)
+	_, err = file.Write(content)
 	if err != nil {
-		return fmt.Errorf("failed to write buildpack config: %w", err)
+		return fmt.Errorf("failed to write %s: %w", description, err)
 	}
 
-	return nil
+	return err // err should be nil here, but return err to catch deferred error
 }
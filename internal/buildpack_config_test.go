@@ -1,6 +1,7 @@
 package internal_test
 
 import (
+	"io"
 	"os"
 	"testing"
 
@@ -228,7 +229,7 @@ func testBuildpackConfig(t *testing.T, context spec.G, it spec.S) {
 						},
 					},
 				},
-			})
+			}, io.Discard)
 			Expect(err).NotTo(HaveOccurred())
 
 			contents, err := os.ReadFile(path)
@@ -275,7 +276,7 @@ func testBuildpackConfig(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					err := internal.OverwriteBuildpackConfig(path, internal.BuildpackConfig{})
+					err := internal.OverwriteBuildpackConfig(path, internal.BuildpackConfig{}, io.Discard)
 					Expect(err).To(MatchError(ContainSubstring("failed to open buildpack config file:")))
 					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
 				})
@@ -285,7 +286,7 @@ func testBuildpackConfig(t *testing.T, context spec.G, it spec.S) {
 				it("returns an error", func() {
 					err := internal.OverwriteBuildpackConfig(path, internal.BuildpackConfig{
 						API: func() {},
-					})
+					}, io.Discard)
 					Expect(err).To(MatchError(ContainSubstring("failed to write buildpack config:")))
 					Expect(err).To(MatchError(ContainSubstring("Marshal can't handle func()(func)")))
 				})
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	ChannelStable = "stable"
+	ChannelRC     = "rc"
+	ChannelAll    = "all"
+)
+
+// rcPrerelease matches the prerelease component of release candidates, ex.
+// 1.2.3-rc, 1.2.3-rc.1 or 1.2.3-rc1.
+var rcPrerelease = regexp.MustCompile(`^rc([.-]?\d+)*$`)
+
+// A Channel selects the prerelease versions that are considered when finding
+// the latest version of an image. Versions without a prerelease component are
+// in every channel. The zero value is the stable channel, which has no
+// prerelease versions.
+type Channel struct {
+	name       string
+	prerelease *regexp.Regexp
+}
+
+// ParseChannel returns the channel with the given name: stable, rc or all.
+// Any other value is a regular expression that the prerelease component of a
+// version must match, ex. "^beta\.\d+$".
+func ParseChannel(value string) (Channel, error) {
+	switch value {
+	case "", ChannelStable:
+		return Channel{}, nil
+	case ChannelRC:
+		return Channel{name: ChannelRC, prerelease: rcPrerelease}, nil
+	case ChannelAll:
+		return Channel{name: ChannelAll, prerelease: regexp.MustCompile(`.*`)}, nil
+	}
+
+	prerelease, err := regexp.Compile(value)
+	if err != nil {
+		return Channel{}, fmt.Errorf("invalid channel %q: must be %q, %q, %q or a prerelease regular expression: %w", value, ChannelStable, ChannelRC, ChannelAll, err)
+	}
+
+	return Channel{name: value, prerelease: prerelease}, nil
+}
+
+// Allows returns true when the version is in the channel.
+func (c Channel) Allows(version *semver.Version) bool {
+	if version.Prerelease() == "" {
+		return true
+	}
+
+	return c.prerelease != nil && c.prerelease.MatchString(version.Prerelease())
+}
+
+// Stable returns true when the channel has no prerelease versions.
+func (c Channel) Stable() bool {
+	return c.prerelease == nil
+}
+
+func (c Channel) String() string {
+	if c.name == "" {
+		return ChannelStable
+	}
+
+	return c.name
+}
@@ -0,0 +1,74 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testChannel(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	allows := func(channel internal.Channel, versions ...string) []string {
+		var allowed []string
+		for _, v := range versions {
+			if channel.Allows(semver.MustParse(v)) {
+				allowed = append(allowed, v)
+			}
+		}
+
+		return allowed
+	}
+
+	versions := []string{"1.2.3", "1.2.4-rc", "1.2.4-rc.1", "1.2.4-rc2", "1.2.4-beta.1", "1.2.4-some-cnb"}
+
+	context("ParseChannel", func() {
+		it("parses the stable channel", func() {
+			channel, err := internal.ParseChannel("stable")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channel.Stable()).To(BeTrue())
+			Expect(channel.String()).To(Equal("stable"))
+			Expect(allows(channel, versions...)).To(Equal([]string{"1.2.3"}))
+		})
+
+		it("parses an empty value as the stable channel", func() {
+			channel, err := internal.ParseChannel("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channel).To(Equal(internal.Channel{}))
+		})
+
+		it("parses the rc channel", func() {
+			channel, err := internal.ParseChannel("rc")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channel.Stable()).To(BeFalse())
+			Expect(channel.String()).To(Equal("rc"))
+			Expect(allows(channel, versions...)).To(Equal([]string{"1.2.3", "1.2.4-rc", "1.2.4-rc.1", "1.2.4-rc2"}))
+		})
+
+		it("parses the all channel", func() {
+			channel, err := internal.ParseChannel("all")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allows(channel, versions...)).To(Equal(versions))
+		})
+
+		it("parses a prerelease regular expression", func() {
+			channel, err := internal.ParseChannel(`^beta\.\d+$`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channel.String()).To(Equal(`^beta\.\d+$`))
+			Expect(allows(channel, versions...)).To(Equal([]string{"1.2.3", "1.2.4-beta.1"}))
+		})
+
+		context("failure cases", func() {
+			context("when the regular expression is invalid", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseChannel("(")
+					Expect(err).To(MatchError(ContainSubstring(`invalid channel "(": must be "stable", "rc", "all" or a prerelease regular expression`)))
+				})
+			})
+		})
+	})
+}
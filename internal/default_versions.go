@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+const (
+	DefaultVersionsKeep   = "keep"
+	DefaultVersionsUpdate = "update"
+	DefaultVersionsError  = "error"
+)
+
+// ValidateDefaultVersionsPolicy returns an error if the given policy for the
+// default versions is not one of keep, update or error.
+func ValidateDefaultVersionsPolicy(policy string) error {
+	if !slices.Contains([]string{DefaultVersionsKeep, DefaultVersionsUpdate, DefaultVersionsError}, policy) {
+		return fmt.Errorf("invalid default versions policy %q: must be one of %s, %s or %s", policy, DefaultVersionsKeep, DefaultVersionsUpdate, DefaultVersionsError)
+	}
+
+	return nil
+}
+
+// A DefaultVersionUpdate is a default version of a dependency that changes, or
+// that matches none of the versions of the dependency.
+type DefaultVersionUpdate struct {
+	ID         string
+	OldVersion string
+	// NewVersion is empty when the default version does not change.
+	NewVersion string
+	// Dangling is true when the resulting default version matches none of the
+	// versions of the dependency.
+	Dangling bool
+}
+
+// UpdateDefaultVersions checks the default versions against the versions of
+// the dependencies, ordered by dependency ID. When recompute is true, each
+// default that is an exact version is replaced by the highest version within
+// the same dependency-constraint, ex. 18.17.0 becomes 18.17.1 for a constraint
+// of 18.*. Defaults that are themselves constraints, ex. 18.*, are kept.
+func UpdateDefaultVersions(defaults map[string]string, dependencies []cargo.ConfigMetadataDependency, constraints []cargo.ConfigMetadataDependencyConstraint, recompute bool) []DefaultVersionUpdate {
+	var ids []string
+	for id := range defaults {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var updates []DefaultVersionUpdate
+	for _, id := range ids {
+		update := DefaultVersionUpdate{ID: id, OldVersion: defaults[id]}
+
+		versions := dependencyVersions(dependencies, id)
+		if recompute {
+			if highest := highestInDefaultConstraint(update.OldVersion, versions, constraints, id); highest != "" && highest != update.OldVersion {
+				update.NewVersion = highest
+			}
+		}
+
+		current := update.OldVersion
+		if update.NewVersion != "" {
+			current = update.NewVersion
+		}
+		update.Dangling = !matchesAnyVersion(current, versions)
+
+		if update.NewVersion != "" || update.Dangling {
+			updates = append(updates, update)
+		}
+	}
+
+	return updates
+}
+
+// highestInDefaultConstraint returns the highest of the versions that is
+// within the dependency-constraint of the given default version, or an empty
+// string if the default is not an exact version within a constraint.
+func highestInDefaultConstraint(defaultVersion string, versions []*semver.Version, constraints []cargo.ConfigMetadataDependencyConstraint, id string) string {
+	version, err := semver.StrictNewVersion(defaultVersion)
+	if err != nil {
+		return ""
+	}
+
+	for _, constraint := range constraints {
+		if constraint.ID != id {
+			continue
+		}
+
+		c, err := semver.NewConstraint(constraint.Constraint)
+		if err != nil || !c.Check(version) {
+			continue
+		}
+
+		var highest *semver.Version
+		for _, v := range versions {
+			if c.Check(v) && (highest == nil || v.GreaterThan(highest)) {
+				highest = v
+			}
+		}
+
+		if highest != nil {
+			return highest.Original()
+		}
+	}
+
+	return ""
+}
+
+// matchesAnyVersion returns true when the default version, which can be a
+// version or a constraint, matches one of the versions.
+func matchesAnyVersion(defaultVersion string, versions []*semver.Version) bool {
+	c, err := semver.NewConstraint(defaultVersion)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(versions, c.Check)
+}
+
+func dependencyVersions(dependencies []cargo.ConfigMetadataDependency, id string) []*semver.Version {
+	var versions []*semver.Version
+	for _, dependency := range dependencies {
+		if dependency.ID != id {
+			continue
+		}
+
+		version, err := semver.NewVersion(dependency.Version)
+		if err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
@@ -0,0 +1,92 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDefaultVersions(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dependencies []cargo.ConfigMetadataDependency
+		constraints  []cargo.ConfigMetadataDependencyConstraint
+	)
+
+	it.Before(func() {
+		dependencies = []cargo.ConfigMetadataDependency{
+			{ID: "node", Version: "18.17.1"},
+			{ID: "node", Version: "18.18.0"},
+			{ID: "node", Version: "20.9.0"},
+			{ID: "python", Version: "3.12.1"},
+		}
+		constraints = []cargo.ConfigMetadataDependencyConstraint{
+			{ID: "node", Constraint: "18.*", Patches: 2},
+			{ID: "node", Constraint: "20.*", Patches: 2},
+			{ID: "python", Constraint: "3.12.*", Patches: 1},
+		}
+	})
+
+	context("ValidateDefaultVersionsPolicy", func() {
+		it("accepts keep, update and error", func() {
+			Expect(internal.ValidateDefaultVersionsPolicy(internal.DefaultVersionsKeep)).To(Succeed())
+			Expect(internal.ValidateDefaultVersionsPolicy(internal.DefaultVersionsUpdate)).To(Succeed())
+			Expect(internal.ValidateDefaultVersionsPolicy(internal.DefaultVersionsError)).To(Succeed())
+		})
+
+		it("returns an error for any other policy", func() {
+			err := internal.ValidateDefaultVersionsPolicy("latest")
+			Expect(err).To(MatchError(`invalid default versions policy "latest": must be one of keep, update or error`))
+		})
+	})
+
+	context("UpdateDefaultVersions", func() {
+		it("returns the default versions that match no dependency version", func() {
+			updates := internal.UpdateDefaultVersions(map[string]string{
+				"node":   "18.17.0",
+				"python": "3.12.*",
+				"ruby":   "3.2.*",
+			}, dependencies, constraints, false)
+
+			Expect(updates).To(Equal([]internal.DefaultVersionUpdate{
+				{ID: "node", OldVersion: "18.17.0", Dangling: true},
+				{ID: "ruby", OldVersion: "3.2.*", Dangling: true},
+			}))
+		})
+
+		context("when the default versions are recomputed", func() {
+			it("updates exact versions to the highest version within their constraint", func() {
+				updates := internal.UpdateDefaultVersions(map[string]string{
+					"node":   "18.17.0",
+					"python": "3.12.*",
+				}, dependencies, constraints, true)
+
+				Expect(updates).To(Equal([]internal.DefaultVersionUpdate{
+					{ID: "node", OldVersion: "18.17.0", NewVersion: "18.18.0"},
+				}))
+			})
+
+			it("keeps exact versions that are already the highest within their constraint", func() {
+				updates := internal.UpdateDefaultVersions(map[string]string{"node": "20.9.0"}, dependencies, constraints, true)
+				Expect(updates).To(BeEmpty())
+			})
+
+			it("returns the default versions that cannot be recomputed", func() {
+				updates := internal.UpdateDefaultVersions(map[string]string{
+					"node": "16.20.2",
+					"ruby": "3.2.2",
+				}, dependencies, constraints, true)
+
+				Expect(updates).To(Equal([]internal.DefaultVersionUpdate{
+					{ID: "node", OldVersion: "16.20.2", Dangling: true},
+					{ID: "ruby", OldVersion: "3.2.2", Dangling: true},
+				}))
+			})
+		})
+	})
+}
@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 	"slices"
 	"sort"
 	"strings"
@@ -46,6 +50,39 @@ type Distro struct {
 	Version string `json:"version"`
 }
 
+// GetAllDependencies queries the dep-server compatible API at the given base
+// URL for every version of the dependency with the given ID.
+func GetAllDependencies(api, dependencyID string) ([]Dependency, error) {
+	uri := DependencyAPIURL(api, dependencyID)
+	resp, err := http.Get(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependency API: %w", err)
+	}
+	defer func() {
+		if err2 := resp.Body.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query dependency API at %s: unexpected response status: %s", uri, resp.Status)
+	}
+
+	var dependencies []Dependency
+	err = json.NewDecoder(resp.Body).Decode(&dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode dependency API response from %s: %w", uri, err)
+	}
+
+	return dependencies, err // err should be nil here, but return err to catch deferred error
+}
+
+// DependencyAPIURL returns the URL of the dep-server compatible API at the
+// given base URL that lists every version of the dependency.
+func DependencyAPIURL(api, dependencyID string) string {
+	return fmt.Sprintf("%s/v1/dependency?%s", strings.TrimSuffix(api, "/"), url.Values{"name": {dependencyID}}.Encode())
+}
+
 // GetDependenciesWithinConstraint reaches out to the given API to search for all
 // dependencies that match the ID and version constraint of a cargo
 // DependencyConstraint. It returns a filtered list of dependencies that match the
@@ -155,6 +192,112 @@ func GetCargoDependenciesWithinConstraint(dependencies []cargo.ConfigMetadataDep
 	return returnSet, nil
 }
 
+// A TargetShortfall is an OS/Arch target of a dependency-constraint that has
+// fewer versions available than the patches of the constraint.
+type TargetShortfall struct {
+	ID         string `json:"id"`
+	Constraint string `json:"constraint"`
+	OS         string `json:"os,omitempty"`
+	Arch       string `json:"arch,omitempty"`
+	Patches    int    `json:"patches"`
+	Available  int    `json:"available"`
+}
+
+func (s TargetShortfall) String() string {
+	target := "any target"
+	if s.OS != "" || s.Arch != "" {
+		target = fmt.Sprintf("%s/%s", s.OS, s.Arch)
+	}
+
+	return fmt.Sprintf("%s %s on %s has %d of %d patches available", s.ID, s.Constraint, target, s.Available, s.Patches)
+}
+
+// GetCargoDependenciesWithinConstraintPerTarget behaves like
+// GetCargoDependenciesWithinConstraint, but counts the patches of the
+// constraint per OS/Arch target rather than across all targets. Each target
+// keeps its n highest available versions, even when the builds of the newest
+// versions lag behind for some targets. It also returns the targets that have
+// fewer than n versions available.
+func GetCargoDependenciesWithinConstraintPerTarget(dependencies []cargo.ConfigMetadataDependency, constraint cargo.ConfigMetadataDependencyConstraint) ([]cargo.ConfigMetadataDependency, []TargetShortfall, error) {
+	type target struct{ os, arch string }
+
+	c, err := semver.NewConstraint(constraint.Constraint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		targets  []target
+		matching []cargo.ConfigMetadataDependency
+	)
+	versions := map[target][]*semver.Version{}
+	for _, dependency := range dependencies {
+		depVersion, err := semver.NewVersion(dependency.Version)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if dependency.ID != constraint.ID || !c.Check(depVersion) {
+			continue
+		}
+
+		// skip duplicates of the same version and target, unless their stacks
+		// differ
+		if slices.ContainsFunc(matching, func(d cargo.ConfigMetadataDependency) bool {
+			return d.Version == dependency.Version && d.OS == dependency.OS && d.Arch == dependency.Arch && slices.Equal(d.Stacks, dependency.Stacks)
+		}) {
+			continue
+		}
+		matching = append(matching, dependency)
+
+		t := target{dependency.OS, dependency.Arch}
+		if _, ok := versions[t]; !ok {
+			targets = append(targets, t)
+		}
+		if !slices.ContainsFunc(versions[t], func(v *semver.Version) bool { return v.Original() == dependency.Version }) {
+			versions[t] = append(versions[t], depVersion)
+		}
+	}
+
+	var shortfalls []TargetShortfall
+	kept := map[target][]string{}
+	for _, t := range targets {
+		sort.Slice(versions[t], func(i, j int) bool {
+			return versions[t][i].LessThan(versions[t][j])
+		})
+
+		i := len(versions[t]) - constraint.Patches
+		if i < 0 {
+			shortfalls = append(shortfalls, TargetShortfall{
+				ID:         constraint.ID,
+				Constraint: constraint.Constraint,
+				OS:         t.os,
+				Arch:       t.arch,
+				Patches:    constraint.Patches,
+				Available:  len(versions[t]),
+			})
+			i = 0
+		}
+
+		for _, v := range versions[t][i:] {
+			kept[t] = append(kept[t], v.Original())
+		}
+	}
+
+	returnSet := []cargo.ConfigMetadataDependency{}
+	for _, dependency := range matching {
+		if slices.Contains(kept[target{dependency.OS, dependency.Arch}], dependency.Version) {
+			returnSet = append(returnSet, dependency)
+		}
+	}
+
+	sort.SliceStable(returnSet, func(i, j int) bool {
+		return semver.MustParse(returnSet[i].Version).LessThan(semver.MustParse(returnSet[j].Version))
+	})
+
+	return returnSet, shortfalls, nil
+}
+
 // FindDependencyName returns the name of a Dependency in a cargo.Config that
 // has a matching ID with a given dependency ID.
 func FindDependencyName(dependencyID string, config cargo.Config) string {
@@ -183,6 +326,8 @@ func convertToCargoDependency(dependency Dependency, dependencyName string) carg
 	cargoDependency.PURL = dependency.PURL
 	cargoDependency.ID = dependency.ID
 	cargoDependency.Name = dependencyName
+	cargoDependency.OS = dependency.OS
+	cargoDependency.Arch = dependency.Arch
 	cargoDependency.SHA256 = dependency.SHA256
 	cargoDependency.Source = dependency.Source
 	cargoDependency.SourceSHA256 = dependency.SourceSHA256
@@ -195,6 +340,10 @@ func convertToCargoDependency(dependency Dependency, dependencyName string) carg
 		cargoDependency.Stacks = append(cargoDependency.Stacks, stack.ID)
 	}
 
+	for _, distro := range dependency.Distros {
+		cargoDependency.Distros = append(cargoDependency.Distros, cargo.ConfigDistro{Name: distro.Name, Version: distro.Version})
+	}
+
 	for _, l := range dependency.Licenses {
 		cargoDependency.Licenses = append(cargoDependency.Licenses, l)
 	}
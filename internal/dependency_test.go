@@ -1,6 +1,9 @@
 package internal_test
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/paketo-buildpacks/jam/v2/internal"
@@ -154,6 +157,67 @@ func testDependency(t *testing.T, context spec.G, it spec.S) {
 		}
 	})
 
+	context("GetAllDependencies", func() {
+		var server *httptest.Server
+
+		it.Before(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Path != "/v1/dependency" {
+					http.NotFound(w, req)
+					return
+				}
+
+				switch req.URL.Query().Get("name") {
+				case "some-dep":
+					fmt.Fprintln(w, `[
+						{"name": "some-dep", "version": "v1.2.3", "os": "linux", "arch": "amd64", "checksum": "sha256:some-sha", "distros": [{"name": "ubuntu", "version": "22.04"}]},
+						{"name": "some-dep", "version": "1.3.0", "stacks": [{"id": "some-stack"}]}
+					]`)
+				case "bad-json":
+					fmt.Fprintln(w, "not JSON")
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}))
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("returns every version of the dependency", func() {
+			dependencies, err := internal.GetAllDependencies(server.URL+"/", "some-dep")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]internal.Dependency{
+				{ID: "some-dep", Version: "v1.2.3", OS: "linux", Arch: "amd64", Checksum: "sha256:some-sha", Distros: []internal.Distro{{Name: "ubuntu", Version: "22.04"}}},
+				{ID: "some-dep", Version: "1.3.0", Stacks: []internal.Stack{{ID: "some-stack"}}},
+			}))
+
+			matching, err := internal.GetDependenciesWithinConstraint(dependencies, cargo.ConfigMetadataDependencyConstraint{ID: "some-dep", Constraint: "1.2.*", Patches: 1}, "Some Dep")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matching).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "some-dep", Name: "Some Dep", Version: "1.2.3", OS: "linux", Arch: "amd64", Checksum: "sha256:some-sha", Distros: []cargo.ConfigDistro{{Name: "ubuntu", Version: "22.04"}}},
+			}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the API cannot be reached", func() {
+				_, err := internal.GetAllDependencies("http://%%%%", "some-dep")
+				Expect(err).To(MatchError(ContainSubstring("failed to query dependency API")))
+			})
+
+			it("returns an error when the API responds with an unexpected status", func() {
+				_, err := internal.GetAllDependencies(server.URL, "other-dep")
+				Expect(err).To(MatchError(ContainSubstring("unexpected response status: 500 Internal Server Error")))
+			})
+
+			it("returns an error when the response is not a list of dependencies", func() {
+				_, err := internal.GetAllDependencies(server.URL, "bad-json")
+				Expect(err).To(MatchError(ContainSubstring("failed to decode dependency API response")))
+			})
+		})
+	})
+
 	context("GetDependenciesWithinConstraint", func() {
 		context("given a valid api and constraint", func() {
 			it("returns a sorted list of dependencies that match the constraint", func() {
@@ -649,6 +713,80 @@ func testDependency(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("GetCargoDependenciesWithinConstraintPerTarget", func() {
+		var (
+			dependencies []cargo.ConfigMetadataDependency
+			constraint   cargo.ConfigMetadataDependencyConstraint
+		)
+
+		it.Before(func() {
+			dependencies = []cargo.ConfigMetadataDependency{
+				{ID: "some-dep", Version: "1.0.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.0.0"},
+				{ID: "some-dep", Version: "1.0.0", OS: "linux", Arch: "arm64", URI: "arm64-uri-1.0.0"},
+				{ID: "some-dep", Version: "1.1.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.1.0"},
+				{ID: "some-dep", Version: "1.1.0", OS: "linux", Arch: "arm64", URI: "arm64-uri-1.1.0"},
+				{ID: "some-dep", Version: "1.2.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.2.0"},
+				{ID: "some-dep", Version: "1.2.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.2.0-duplicate"},
+				{ID: "some-dep", Version: "2.0.0", OS: "linux", Arch: "arm64", URI: "arm64-uri-2.0.0"},
+				{ID: "other-dep", Version: "1.3.0", OS: "linux", Arch: "arm64", URI: "other-uri-1.3.0"},
+			}
+
+			constraint = cargo.ConfigMetadataDependencyConstraint{
+				Constraint: "1.*",
+				ID:         "some-dep",
+				Patches:    2,
+			}
+		})
+
+		it("keeps the n highest versions of every target, in order of lowest version to highest", func() {
+			matching, shortfalls, err := internal.GetCargoDependenciesWithinConstraintPerTarget(dependencies, constraint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matching).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "some-dep", Version: "1.0.0", OS: "linux", Arch: "arm64", URI: "arm64-uri-1.0.0"},
+				{ID: "some-dep", Version: "1.1.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.1.0"},
+				{ID: "some-dep", Version: "1.1.0", OS: "linux", Arch: "arm64", URI: "arm64-uri-1.1.0"},
+				{ID: "some-dep", Version: "1.2.0", OS: "linux", Arch: "amd64", URI: "amd64-uri-1.2.0"},
+			}))
+			Expect(shortfalls).To(BeEmpty())
+		})
+
+		context("when a target has fewer versions available than the patches", func() {
+			it.Before(func() {
+				constraint.Patches = 3
+			})
+
+			it("keeps every version of that target and returns the shortfall", func() {
+				matching, shortfalls, err := internal.GetCargoDependenciesWithinConstraintPerTarget(dependencies, constraint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(matching).To(HaveLen(5))
+				Expect(shortfalls).To(Equal([]internal.TargetShortfall{
+					{ID: "some-dep", Constraint: "1.*", OS: "linux", Arch: "arm64", Patches: 3, Available: 2},
+				}))
+				Expect(shortfalls[0].String()).To(Equal("some-dep 1.* on linux/arm64 has 2 of 3 patches available"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("given an invalid constraint", func() {
+				it("returns an error", func() {
+					constraint.Constraint = "abc"
+
+					_, _, err := internal.GetCargoDependenciesWithinConstraintPerTarget(dependencies, constraint)
+					Expect(err).To(MatchError("improper constraint: abc"))
+				})
+			})
+
+			context("given a malformed dependency version", func() {
+				it("returns an error", func() {
+					dependencies = append(dependencies, cargo.ConfigMetadataDependency{ID: "some-dep", Version: "v1.xx"})
+
+					_, _, err := internal.GetCargoDependenciesWithinConstraintPerTarget(dependencies, constraint)
+					Expect(err).To(MatchError("invalid semantic version"))
+				})
+			})
+		})
+	})
+
 	context("FindDependencyName", func() {
 		var cargoConfig cargo.Config
 		it.Before(func() {
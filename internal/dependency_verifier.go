@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+type DependencyVerifier struct {
+	downloader Downloader
+}
+
+func NewDependencyVerifier(downloader Downloader) DependencyVerifier {
+	return DependencyVerifier{
+		downloader: downloader,
+	}
+}
+
+// Verify downloads the artifact of the dependency and returns an error when
+// it does not match the checksum of the dependency.
+func (v DependencyVerifier) Verify(dependency cargo.ConfigMetadataDependency) error {
+	checksum := dependencyChecksum(dependency)
+	if checksum == "" {
+		return fmt.Errorf("failed to verify %s: no sha256 or checksum provided", DescribeDependency(dependency))
+	}
+
+	source, err := v.downloader.Drop("", dependency.URI)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", DescribeDependency(dependency), err)
+	}
+	defer func() {
+		if err2 := source.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	valid, err := cargo.NewValidatedReader(source, checksum).Valid()
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", DescribeDependency(dependency), err)
+	}
+
+	if !valid {
+		return fmt.Errorf("failed to verify %s: the artifact at %s does not match %s", DescribeDependency(dependency), dependency.URI, checksum)
+	}
+
+	return err // err should be nil here, but return err to catch deferred error
+}
@@ -0,0 +1,76 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/jam/v2/internal/fakes"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDependencyVerifier(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		downloader *fakes.Downloader
+		verifier   internal.DependencyVerifier
+	)
+
+	it.Before(func() {
+		downloader = &fakes.Downloader{}
+		downloader.DropCall.Returns.ReadCloser = io.NopCloser(bytes.NewBufferString("some-content"))
+
+		verifier = internal.NewDependencyVerifier(downloader)
+	})
+
+	context("Verify", func() {
+		it("downloads the artifact and checks its checksum", func() {
+			err := verifier.Verify(cargo.ConfigMetadataDependency{
+				ID:      "some-dependency",
+				Version: "1.2.3",
+				URI:     "https://example.com/some-dependency.tgz",
+				SHA256:  "0a8cac771ca188eacc57e2c96c31f5611925c5ecedccb16b8c236d6c0d325112",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(downloader.DropCall.Receives.Root).To(Equal(""))
+			Expect(downloader.DropCall.Receives.Uri).To(Equal("https://example.com/some-dependency.tgz"))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the dependency has no checksum", func() {
+				err := verifier.Verify(cargo.ConfigMetadataDependency{ID: "some-dependency", Version: "1.2.3"})
+				Expect(err).To(MatchError("failed to verify some-dependency 1.2.3: no sha256 or checksum provided"))
+				Expect(downloader.DropCall.CallCount).To(Equal(0))
+			})
+
+			it("returns an error when the artifact cannot be downloaded", func() {
+				downloader.DropCall.Returns.Error = errors.New("failed to download")
+
+				err := verifier.Verify(cargo.ConfigMetadataDependency{ID: "some-dependency", Version: "1.2.3", Checksum: "sha256:some-sha"})
+				Expect(err).To(MatchError("failed to download some-dependency 1.2.3: failed to download"))
+			})
+
+			it("returns an error when the artifact does not match the checksum", func() {
+				err := verifier.Verify(cargo.ConfigMetadataDependency{
+					ID:       "some-dependency",
+					Version:  "1.2.3",
+					URI:      "https://example.com/some-dependency.tgz",
+					Checksum: "sha256:f0ff5b5e8e0a7f4e1c52ab1f4cfbc6b7a2e4cb1d8c5f03a7dd8b5dfa0bd26ef3",
+				})
+				Expect(err).To(MatchError("failed to verify some-dependency 1.2.3: the artifact at https://example.com/some-dependency.tgz does not match sha256:f0ff5b5e8e0a7f4e1c52ab1f4cfbc6b7a2e4cb1d8c5f03a7dd8b5dfa0bd26ef3"))
+			})
+
+			it("returns an error when the checksum algorithm is not supported", func() {
+				err := verifier.Verify(cargo.ConfigMetadataDependency{ID: "some-dependency", Version: "1.2.3", Checksum: "md5:some-md5"})
+				Expect(err).To(MatchError(ContainSubstring("unsupported algorithm")))
+			})
+		})
+	})
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+var versionLinePattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// An EOLCalendar holds the deprecation dates of the version lines of
+// dependencies, keyed by dependency ID and version line, ex. "18" or "3.12".
+type EOLCalendar map[string]map[string]time.Time
+
+// ReadEOLCalendar reads an EOL calendar from a JSON file of the form
+// {"node": {"18": "2025-04-30", "20": "2026-04-30"}}. Dates are either plain
+// dates or RFC 3339 timestamps.
+func ReadEOLCalendar(path string) (EOLCalendar, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EOL calendar: %w", err)
+	}
+
+	var entries map[string]map[string]string
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode EOL calendar %s: %w", path, err)
+	}
+
+	calendar := EOLCalendar{}
+	for id, lines := range entries {
+		calendar[id] = map[string]time.Time{}
+		for line, date := range lines {
+			if !versionLinePattern.MatchString(line) {
+				return nil, fmt.Errorf("invalid version line %q for %s in EOL calendar %s", line, id, path)
+			}
+
+			deprecationDate, err := parseDeprecationDate(date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid deprecation date %q for %s %s in EOL calendar %s", date, id, line, path)
+			}
+
+			calendar[id][line] = deprecationDate
+		}
+	}
+
+	return calendar, nil
+}
+
+// DeprecationDate returns the deprecation date of the most specific version
+// line in the calendar that contains the version of the dependency, ex. 3.12
+// rather than 3 for 3.12.1.
+func (c EOLCalendar) DeprecationDate(id, version string) (time.Time, bool) {
+	version = strings.TrimPrefix(version, "v")
+
+	var (
+		match string
+		found bool
+	)
+	for line := range c[id] {
+		if (version == line || strings.HasPrefix(version, line+".")) && len(line) > len(match) {
+			match, found = line, true
+		}
+	}
+
+	return c[id][match], found
+}
+
+// ApplyEOLCalendar sets the deprecation date of every dependency that has
+// none to the date of its version line in the calendar. It returns the
+// dependencies, in order, and the subset of them that were given a date.
+func ApplyEOLCalendar(dependencies []cargo.ConfigMetadataDependency, calendar EOLCalendar) ([]cargo.ConfigMetadataDependency, []cargo.ConfigMetadataDependency) {
+	var applied, populated []cargo.ConfigMetadataDependency
+	for _, dependency := range dependencies {
+		if dependency.DeprecationDate == nil {
+			if date, ok := calendar.DeprecationDate(dependency.ID, dependency.Version); ok {
+				dependency.DeprecationDate = &date
+				populated = append(populated, dependency)
+			}
+		}
+
+		applied = append(applied, dependency)
+	}
+
+	return applied, populated
+}
+
+// SplitDeprecatedDependencies splits the dependencies into those that are
+// still supported at the given time and those whose deprecation date has
+// passed, keeping their order.
+func SplitDeprecatedDependencies(dependencies []cargo.ConfigMetadataDependency, now time.Time) ([]cargo.ConfigMetadataDependency, []cargo.ConfigMetadataDependency) {
+	var supported, deprecated []cargo.ConfigMetadataDependency
+	for _, dependency := range dependencies {
+		if dependency.DeprecationDate != nil && !dependency.DeprecationDate.After(now) {
+			deprecated = append(deprecated, dependency)
+		} else {
+			supported = append(supported, dependency)
+		}
+	}
+
+	return supported, deprecated
+}
+
+// DeprecatedWithin returns the dependencies whose deprecation date has passed
+// at the given time, or will pass within the grace period.
+func DeprecatedWithin(dependencies []cargo.ConfigMetadataDependency, now time.Time, grace time.Duration) []cargo.ConfigMetadataDependency {
+	var deprecated []cargo.ConfigMetadataDependency
+	for _, dependency := range dependencies {
+		if dependency.DeprecationDate != nil && dependency.DeprecationDate.Before(now.Add(grace)) {
+			deprecated = append(deprecated, dependency)
+		}
+	}
+
+	return deprecated
+}
+
+func parseDeprecationDate(date string) (time.Time, error) {
+	deprecationDate, err := time.Parse(time.DateOnly, date)
+	if err == nil {
+		return deprecationDate, nil
+	}
+
+	return time.Parse(time.RFC3339, date)
+}
@@ -0,0 +1,150 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDeprecation(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		now time.Time
+	)
+
+	it.Before(func() {
+		now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	date := func(value string) *time.Time {
+		d, err := time.Parse(time.DateOnly, value)
+		Expect(err).NotTo(HaveOccurred())
+		return &d
+	}
+
+	context("ReadEOLCalendar", func() {
+		var path string
+
+		it.Before(func() {
+			path = filepath.Join(t.TempDir(), "eol.json")
+		})
+
+		it("reads the deprecation dates of every version line", func() {
+			Expect(os.WriteFile(path, []byte(`{
+				"node": {"18": "2025-04-30", "20": "2026-04-30T00:00:00Z"},
+				"python": {"3.12": "2028-10-31"}
+			}`), 0600)).To(Succeed())
+
+			calendar, err := internal.ReadEOLCalendar(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calendar).To(Equal(internal.EOLCalendar{
+				"node": {
+					"18": *date("2025-04-30"),
+					"20": *date("2026-04-30"),
+				},
+				"python": {
+					"3.12": *date("2028-10-31"),
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the file does not exist", func() {
+				_, err := internal.ReadEOLCalendar(filepath.Join(t.TempDir(), "missing.json"))
+				Expect(err).To(MatchError(ContainSubstring("failed to open EOL calendar")))
+			})
+
+			it("returns an error when the file is not valid JSON", func() {
+				Expect(os.WriteFile(path, []byte(`%%%`), 0600)).To(Succeed())
+
+				_, err := internal.ReadEOLCalendar(path)
+				Expect(err).To(MatchError(ContainSubstring("failed to decode EOL calendar")))
+			})
+
+			it("returns an error when a version line is invalid", func() {
+				Expect(os.WriteFile(path, []byte(`{"node": {"18.x": "2025-04-30"}}`), 0600)).To(Succeed())
+
+				_, err := internal.ReadEOLCalendar(path)
+				Expect(err).To(MatchError(ContainSubstring(`invalid version line "18.x" for node`)))
+			})
+
+			it("returns an error when a date is invalid", func() {
+				Expect(os.WriteFile(path, []byte(`{"node": {"18": "April 2025"}}`), 0600)).To(Succeed())
+
+				_, err := internal.ReadEOLCalendar(path)
+				Expect(err).To(MatchError(ContainSubstring(`invalid deprecation date "April 2025" for node 18`)))
+			})
+		})
+	})
+
+	context("ApplyEOLCalendar", func() {
+		it("sets missing deprecation dates from the most specific version line", func() {
+			calendar := internal.EOLCalendar{
+				"python": {
+					"3":    *date("2030-01-01"),
+					"3.12": *date("2028-10-31"),
+				},
+			}
+
+			dependencies, populated := internal.ApplyEOLCalendar([]cargo.ConfigMetadataDependency{
+				{ID: "python", Version: "3.12.1"},
+				{ID: "python", Version: "3.11.2", DeprecationDate: date("2027-10-31")},
+				{ID: "python", Version: "3.10.0"},
+				{ID: "node", Version: "18.17.1"},
+			}, calendar)
+
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "python", Version: "3.12.1", DeprecationDate: date("2028-10-31")},
+				{ID: "python", Version: "3.11.2", DeprecationDate: date("2027-10-31")},
+				{ID: "python", Version: "3.10.0", DeprecationDate: date("2030-01-01")},
+				{ID: "node", Version: "18.17.1"},
+			}))
+			Expect(populated).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "python", Version: "3.12.1", DeprecationDate: date("2028-10-31")},
+				{ID: "python", Version: "3.10.0", DeprecationDate: date("2030-01-01")},
+			}))
+		})
+	})
+
+	context("SplitDeprecatedDependencies", func() {
+		it("splits the dependencies whose deprecation date has passed from the others", func() {
+			supported, deprecated := internal.SplitDeprecatedDependencies([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "16.20.2", DeprecationDate: date("2023-09-11")},
+				{ID: "node", Version: "18.17.1", DeprecationDate: date("2025-04-30")},
+				{ID: "node", Version: "20.9.0"},
+			}, now)
+
+			Expect(supported).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "18.17.1", DeprecationDate: date("2025-04-30")},
+				{ID: "node", Version: "20.9.0"},
+			}))
+			Expect(deprecated).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "16.20.2", DeprecationDate: date("2023-09-11")},
+			}))
+		})
+	})
+
+	context("DeprecatedWithin", func() {
+		it("returns the dependencies that are deprecated before the end of the grace period", func() {
+			deprecated := internal.DeprecatedWithin([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "16.20.2", DeprecationDate: date("2023-09-11")},
+				{ID: "node", Version: "18.17.1", DeprecationDate: date("2024-06-15")},
+				{ID: "node", Version: "20.9.0", DeprecationDate: date("2026-04-30")},
+				{ID: "node", Version: "22.1.0"},
+			}, now, 30*24*time.Hour)
+
+			Expect(deprecated).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "16.20.2", DeprecationDate: date("2023-09-11")},
+				{ID: "node", Version: "18.17.1", DeprecationDate: date("2024-06-15")},
+			}))
+		})
+	})
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// UnifiedDiff returns a unified diff between the old and new contents of a
+// file, using three lines of context around each change. An empty string is
+// returned when the contents are identical.
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte) string {
+	oldLines := splitLines(string(oldContent))
+	newLines := splitLines(string(newContent))
+
+	ops := diffLines(oldLines, newLines)
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", oldName)
+	fmt.Fprintf(&b, "+++ %s\n", newName)
+
+	// oldIndex and newIndex hold the line number (0-based) in each file at
+	// which the op with the same index starts
+	oldIndex := make([]int, len(ops)+1)
+	newIndex := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldIndex[i+1] = oldIndex[i]
+		newIndex[i+1] = newIndex[i]
+		if op.kind != '+' {
+			oldIndex[i+1]++
+		}
+		if op.kind != '-' {
+			newIndex[i+1]++
+		}
+	}
+
+	for i := 0; i < len(changes); {
+		start := max(changes[i]-diffContextLines, 0)
+		end := changes[i]
+
+		// merge changes whose context would overlap into a single hunk
+		for i < len(changes) && changes[i]-end <= 2*diffContextLines {
+			end = changes[i]
+			i++
+		}
+		end = min(end+diffContextLines+1, len(ops))
+
+		oldStart, oldCount := oldIndex[start], oldIndex[end]-oldIndex[start]
+		newStart, newCount := newIndex[start], newIndex[end]-newIndex[start]
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.line)
+		}
+	}
+
+	return b.String()
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines computes the shortest edit script between two sets of lines using
+// their longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{kind: '-', line: a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{kind: '+', line: b[j]})
+	}
+
+	return ops
+}
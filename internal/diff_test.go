@@ -0,0 +1,100 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDiff(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("UnifiedDiff", func() {
+		it("returns a unified diff of the changed lines with context", func() {
+			oldContent := []byte(`one
+two
+three
+four
+five
+six
+seven
+eight
+nine
+ten
+eleven
+twelve
+`)
+			newContent := []byte(`one
+two
+three
+four
+5
+six
+seven
+eight
+nine
+ten
+eleven
+twelve
+thirteen
+`)
+
+			Expect(internal.UnifiedDiff("a/file.toml", "b/file.toml", oldContent, newContent)).To(Equal(`--- a/file.toml
++++ b/file.toml
+@@ -2,7 +2,7 @@
+ two
+ three
+ four
+-five
++5
+ six
+ seven
+ eight
+@@ -10,3 +10,4 @@
+ ten
+ eleven
+ twelve
++thirteen
+`))
+		})
+
+		context("when changes are close together", func() {
+			it("merges them into a single hunk", func() {
+				oldContent := []byte("a\nb\nc\nd\ne\n")
+				newContent := []byte("A\nb\nc\nd\nE\n")
+
+				Expect(internal.UnifiedDiff("old", "new", oldContent, newContent)).To(Equal(`--- old
++++ new
+@@ -1,5 +1,5 @@
+-a
++A
+ b
+ c
+ d
+-e
++E
+`))
+			})
+		})
+
+		context("when the old content is empty", func() {
+			it("returns a diff that adds every line", func() {
+				Expect(internal.UnifiedDiff("old", "new", nil, []byte("a\nb\n"))).To(Equal(`--- old
++++ new
+@@ -0,0 +1,2 @@
++a
++b
+`))
+			})
+		})
+
+		context("when the contents are identical", func() {
+			it("returns an empty string", func() {
+				Expect(internal.UnifiedDiff("old", "new", []byte("a\nb\n"), []byte("a\nb\n"))).To(BeEmpty())
+			})
+		})
+	})
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+)
+
+// A DockerfilePin is a base image of a Dockerfile stage that is pinned to a
+// digest, ex. FROM ubuntu:jammy@sha256:<digest>. The reference can be written
+// in the FROM line, given as the default of a global ARG, or given as a build
+// argument.
+type DockerfilePin struct {
+	// Reference is the pinned image reference, ex. ubuntu:jammy@sha256:<digest>.
+	Reference string
+	// Image is the reference without its digest, ex. ubuntu:jammy.
+	Image string
+	// Digest is the digest the image is pinned to.
+	Digest string
+
+	// Arg is the name of the ARG that gives the reference, or empty when the
+	// reference is written in the FROM line.
+	Arg string
+	// BuildArg is true when the value of the ARG is given as a build argument
+	// rather than by its default in the Dockerfile.
+	BuildArg bool
+
+	// StartLine and EndLine are the lines of the FROM or ARG instruction that
+	// contains the reference. They are zero for build arguments.
+	StartLine int
+	EndLine   int
+
+	// Platform is the value of the --platform flag of the FROM instruction.
+	Platform string
+}
+
+// FindDockerfilePins returns the pinned base images of the stages of the given
+// Dockerfile when it is built with the given build arguments, of the form
+// key=value. References that are not pinned to a digest, or that have no tag
+// to find a newer digest with, are skipped.
+func FindDockerfilePins(content []byte, buildArgs []string) ([]DockerfilePin, error) {
+	result, err := parser.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Dockerfile: %w", err)
+	}
+
+	overrides := map[string]string{}
+	for _, buildArg := range buildArgs {
+		key, value, _ := strings.Cut(buildArg, "=")
+		overrides[key] = value
+	}
+
+	lex := shell.NewLex(result.EscapeToken)
+
+	// only the ARG instructions before the first FROM can be used in FROM lines
+	args := map[string]dockerfileArg{}
+	var pins []DockerfilePin
+	seenFrom := false
+	for _, node := range result.AST.Children {
+		switch {
+		case strings.EqualFold(node.Value, "arg") && !seenFrom:
+			for n := node.Next; n != nil; n = n.Next {
+				key, value, hasValue := strings.Cut(n.Value, "=")
+				if override, ok := overrides[key]; ok {
+					args[key] = dockerfileArg{value: override, hasValue: true, buildArg: true}
+					continue
+				}
+
+				if hasValue {
+					value, _, err = lex.ProcessWord(value, argsEnv(args))
+					if err != nil {
+						return nil, fmt.Errorf("failed to expand ARG %s at line %d: %w", key, node.StartLine, err)
+					}
+				}
+				args[key] = dockerfileArg{value: value, hasValue: hasValue, node: node}
+			}
+
+		case strings.EqualFold(node.Value, "from"):
+			seenFrom = true
+			if node.Next == nil {
+				continue
+			}
+
+			word := node.Next.Value
+			expanded, _, err := lex.ProcessWord(word, argsEnv(args))
+			if err != nil {
+				return nil, fmt.Errorf("failed to expand FROM at line %d: %w", node.StartLine, err)
+			}
+
+			pin := DockerfilePin{Reference: expanded, StartLine: node.StartLine, EndLine: node.EndLine}
+			for _, flag := range node.Flags {
+				if platform, ok := strings.CutPrefix(flag, "--platform="); ok {
+					pin.Platform = platform
+				}
+			}
+
+			// when the reference is given by a single ARG, it is pinned in the ARG
+			if name, ok := singleArg(word); ok {
+				arg, found := args[name]
+				if !found || !arg.hasValue {
+					continue
+				}
+
+				pin.Arg = name
+				pin.BuildArg = arg.buildArg
+				pin.StartLine, pin.EndLine = 0, 0
+				if arg.node != nil {
+					pin.StartLine, pin.EndLine = arg.node.StartLine, arg.node.EndLine
+				}
+			}
+
+			image, digest, ok := splitPinnedReference(pin.Reference)
+			if !ok {
+				continue
+			}
+			pin.Image, pin.Digest = image, digest
+
+			pins = append(pins, pin)
+		}
+	}
+
+	return pins, nil
+}
+
+// RewriteDockerfilePins replaces the pinned references in the FROM and ARG
+// instructions of the given Dockerfile with the new references, keyed by the
+// old reference. When the image of a reference is parameterized, ex. FROM
+// ubuntu:${TAG}@sha256:<digest>, and the new reference only changes the
+// digest, only the digest is replaced. Pins given as build arguments are not
+// in the Dockerfile and are left to the caller.
+func RewriteDockerfilePins(content []byte, pins []DockerfilePin, references map[string]string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	for _, pin := range pins {
+		newReference, ok := references[pin.Reference]
+		if !ok || pin.BuildArg || pin.StartLine == 0 {
+			continue
+		}
+
+		old, replacement := pin.Reference, newReference
+
+		instruction := strings.Join(lines[pin.StartLine-1:min(pin.EndLine, len(lines))], "")
+		if !strings.Contains(instruction, old) && !strings.Contains(instruction, replacement) {
+			image, digest, ok := splitPinnedReference(newReference)
+			if !ok || image != pin.Image || !strings.Contains(instruction, "@"+pin.Digest) {
+				return nil, fmt.Errorf("failed to update %s at line %d: neither the reference nor its digest is written out in the Dockerfile", pin.Reference, pin.StartLine)
+			}
+
+			old, replacement = "@"+pin.Digest, "@"+digest
+		}
+
+		for i := pin.StartLine - 1; i < pin.EndLine && i < len(lines); i++ {
+			lines[i] = strings.ReplaceAll(lines[i], old, replacement)
+		}
+	}
+
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// PinReference returns the reference of the image pinned to the given digest.
+func PinReference(image, digest string) string {
+	return fmt.Sprintf("%s@%s", image, digest)
+}
+
+// splitPinnedReference returns the tagged image and the digest of a reference
+// of the form <image>:<tag>@<digest>.
+func splitPinnedReference(ref string) (string, string, bool) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", "", false
+	}
+
+	digested, ok := named.(reference.Digested)
+	if !ok {
+		return "", "", false
+	}
+
+	if _, ok := named.(reference.Tagged); !ok {
+		return "", "", false
+	}
+
+	image, _, _ := strings.Cut(ref, "@")
+	return image, digested.Digest().String(), true
+}
+
+// singleArg returns the name of the variable when the word is a single
+// variable, ex. $BASE or ${BASE}.
+func singleArg(word string) (string, bool) {
+	name, ok := strings.CutPrefix(word, "$")
+	if !ok {
+		return "", false
+	}
+
+	if braced, ok := strings.CutPrefix(name, "{"); ok {
+		name, ok = strings.CutSuffix(braced, "}")
+		if !ok {
+			return "", false
+		}
+	}
+
+	if name == "" || strings.ContainsAny(name, "${}:-+?") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// dockerfileArg is the value of a global ARG of a Dockerfile.
+type dockerfileArg struct {
+	value    string
+	hasValue bool
+	buildArg bool
+	node     *parser.Node
+}
+
+func argsEnv(args map[string]dockerfileArg) shell.EnvGetter {
+	var env []string
+	for key, arg := range args {
+		if arg.hasValue {
+			env = append(env, fmt.Sprintf("%s=%s", key, arg.value))
+		}
+	}
+
+	return shell.EnvsFromSlice(env)
+}
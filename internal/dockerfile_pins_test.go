@@ -0,0 +1,169 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testDockerfilePins(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		oldDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		newDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+		dockerfile []byte
+	)
+
+	it.Before(func() {
+		dockerfile = []byte(`ARG base_image="ubuntu:jammy@` + oldDigest + `"
+ARG other_image=alpine:3@` + oldDigest + `
+ARG sources
+
+FROM --platform=$BUILDPLATFORM golang:1.22@` + oldDigest + ` AS builder
+RUN echo building
+
+FROM ${base_image}
+COPY --from=builder /some-file /some-file
+
+FROM $other_image AS other
+
+FROM \
+  busybox:1@` + oldDigest + `
+
+FROM builder
+`)
+	})
+
+	context("FindDockerfilePins", func() {
+		it("returns the pinned base images of every stage", func() {
+			pins, err := internal.FindDockerfilePins(dockerfile, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pins).To(Equal([]internal.DockerfilePin{
+				{
+					Reference: "golang:1.22@" + oldDigest,
+					Image:     "golang:1.22",
+					Digest:    oldDigest,
+					StartLine: 5,
+					EndLine:   5,
+					Platform:  "$BUILDPLATFORM",
+				},
+				{
+					Reference: "ubuntu:jammy@" + oldDigest,
+					Image:     "ubuntu:jammy",
+					Digest:    oldDigest,
+					Arg:       "base_image",
+					StartLine: 1,
+					EndLine:   1,
+				},
+				{
+					Reference: "alpine:3@" + oldDigest,
+					Image:     "alpine:3",
+					Digest:    oldDigest,
+					Arg:       "other_image",
+					StartLine: 2,
+					EndLine:   2,
+				},
+				{
+					Reference: "busybox:1@" + oldDigest,
+					Image:     "busybox:1",
+					Digest:    oldDigest,
+					StartLine: 13,
+					EndLine:   14,
+				},
+			}))
+		})
+
+		context("when a build argument overrides an ARG", func() {
+			it("returns the pin as a build argument", func() {
+				pins, err := internal.FindDockerfilePins(dockerfile, []string{"base_image=ubuntu:noble@" + oldDigest, "unused=value"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pins[1]).To(Equal(internal.DockerfilePin{
+					Reference: "ubuntu:noble@" + oldDigest,
+					Image:     "ubuntu:noble",
+					Digest:    oldDigest,
+					Arg:       "base_image",
+					BuildArg:  true,
+				}))
+			})
+		})
+
+		context("when a reference is not pinned or has no tag", func() {
+			it("is skipped", func() {
+				pins, err := internal.FindDockerfilePins([]byte("FROM ubuntu:jammy\nFROM ubuntu@"+oldDigest+"\nFROM scratch\n"), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pins).To(BeEmpty())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the Dockerfile cannot be parsed", func() {
+				it("returns an error", func() {
+					_, err := internal.FindDockerfilePins([]byte("# escape=x\nFROM ubuntu:jammy\n"), nil)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse Dockerfile")))
+				})
+			})
+		})
+	})
+
+	context("RewriteDockerfilePins", func() {
+		it("replaces the references in the FROM and ARG instructions", func() {
+			pins, err := internal.FindDockerfilePins(dockerfile, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := internal.RewriteDockerfilePins(dockerfile, pins, map[string]string{
+				"ubuntu:jammy@" + oldDigest: "ubuntu:jammy@" + newDigest,
+				"busybox:1@" + oldDigest:    "busybox:1@" + newDigest,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`ARG base_image="ubuntu:jammy@` + newDigest + `"
+ARG other_image=alpine:3@` + oldDigest + `
+ARG sources
+
+FROM --platform=$BUILDPLATFORM golang:1.22@` + oldDigest + ` AS builder
+RUN echo building
+
+FROM ${base_image}
+COPY --from=builder /some-file /some-file
+
+FROM $other_image AS other
+
+FROM \
+  busybox:1@` + newDigest + `
+
+FROM builder
+`))
+		})
+
+		context("when the image of the reference is parameterized by an ARG", func() {
+			it("only replaces the digest", func() {
+				content := []byte("ARG tag=jammy\nARG base=ubuntu:${tag}@" + oldDigest + "\nFROM $base\nFROM ubuntu:${tag}@" + oldDigest + " AS other\n")
+				pins, err := internal.FindDockerfilePins(content, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(pins).To(HaveLen(2))
+
+				content, err = internal.RewriteDockerfilePins(content, pins, map[string]string{"ubuntu:jammy@" + oldDigest: "ubuntu:jammy@" + newDigest})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("ARG tag=jammy\nARG base=ubuntu:${tag}@" + newDigest + "\nFROM $base\nFROM ubuntu:${tag}@" + newDigest + " AS other\n"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when neither the reference nor its digest is written out in the Dockerfile", func() {
+				it("returns an error", func() {
+					content := []byte("ARG digest=" + oldDigest + "\nFROM ubuntu:jammy@${digest}\n")
+					pins, err := internal.FindDockerfilePins(content, nil)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pins).To(HaveLen(1))
+
+					_, err = internal.RewriteDockerfilePins(content, pins, map[string]string{"ubuntu:jammy@" + oldDigest: "ubuntu:jammy@" + newDigest})
+					Expect(err).To(MatchError(ContainSubstring("neither the reference nor its digest is written out in the Dockerfile")))
+				})
+			})
+		})
+	})
+}
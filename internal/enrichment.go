@@ -0,0 +1,275 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/pelletier/go-toml"
+)
+
+// EnrichmentTemplate holds the text/template strings used to compute the PURL
+// and CPEs of a dependency. The templates are executed with an
+// EnrichmentData value, ex. "pkg:generic/node@{{.Version}}".
+type EnrichmentTemplate struct {
+	PURL string   `json:"purl,omitempty"`
+	CPEs []string `json:"cpes,omitempty"`
+}
+
+// EnrichmentTemplates maps dependency IDs to their templates. The template
+// with the ID "*" is used for dependencies that have no template of their own.
+type EnrichmentTemplates map[string]EnrichmentTemplate
+
+// Get returns the template of the dependency ID, or the "*" template when the
+// ID has none.
+func (t EnrichmentTemplates) Get(id string) (EnrichmentTemplate, bool) {
+	value, ok := t[id]
+	if !ok {
+		value, ok = t["*"]
+	}
+
+	return value, ok
+}
+
+// EnrichmentData is the data the enrichment templates are executed with.
+type EnrichmentData struct {
+	ID             string
+	Name           string
+	Version        string
+	OS             string
+	Arch           string
+	URI            string
+	Checksum       string
+	Source         string
+	SourceChecksum string
+}
+
+// A DependencyEnrichment records the identifiers that were computed for the
+// dependency at Index.
+type DependencyEnrichment struct {
+	Index int
+	PURL  string
+	CPEs  []string
+}
+
+// ReadEnrichmentTemplates reads the enrichment templates from a JSON file of
+// the form {"node": {"purl": "...", "cpes": ["..."]}} and checks that every
+// template can be parsed.
+func ReadEnrichmentTemplates(path string) (EnrichmentTemplates, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open enrichment templates: %w", err)
+	}
+
+	var templates EnrichmentTemplates
+	err = json.Unmarshal(content, &templates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode enrichment templates %s: %w", path, err)
+	}
+
+	for id, t := range templates {
+		for _, text := range append([]string{t.PURL}, t.CPEs...) {
+			_, err = template.New(id).Option("missingkey=error").Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enrichment template for %s in %s: %w", id, path, err)
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// EnrichDependencies computes the PURL of every dependency that has none, and
+// the CPEs of every dependency that has neither a CPE nor explicit cpes, from
+// the template of its ID. Explicit values are never overwritten. The computed
+// CPEs are not set on the dependency, which cargo only models as a single
+// `cpe`, but returned in the enrichments to be written as `cpes`, see
+// IndexDependencyCPEs.
+func EnrichDependencies(dependencies []cargo.ConfigMetadataDependency, templates EnrichmentTemplates, explicit DependencyCPEs) ([]cargo.ConfigMetadataDependency, []DependencyEnrichment, error) {
+	var (
+		enriched    []cargo.ConfigMetadataDependency
+		enrichments []DependencyEnrichment
+	)
+	for i, dependency := range dependencies {
+		t, ok := templates.Get(dependency.ID)
+		if !ok {
+			enriched = append(enriched, dependency)
+			continue
+		}
+
+		data := EnrichmentData{
+			ID:             dependency.ID,
+			Name:           dependency.Name,
+			Version:        dependency.Version,
+			OS:             dependency.OS,
+			Arch:           dependency.Arch,
+			URI:            dependency.URI,
+			Checksum:       dependencyChecksum(dependency),
+			Source:         dependency.Source,
+			SourceChecksum: dependency.SourceChecksum,
+		}
+		if data.SourceChecksum == "" && dependency.SourceSHA256 != "" {
+			data.SourceChecksum = fmt.Sprintf("sha256:%s", dependency.SourceSHA256)
+		}
+
+		enrichment := DependencyEnrichment{Index: i}
+		if dependency.PURL == "" && t.PURL != "" {
+			purl, err := executeEnrichmentTemplate(t.PURL, data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to compute purl of %s: %w", DescribeDependency(dependency), err)
+			}
+			dependency.PURL = purl
+			enrichment.PURL = purl
+		}
+
+		if dependency.CPE == "" && len(explicit.Get(dependency)) == 0 {
+			for _, text := range t.CPEs {
+				cpe, err := executeEnrichmentTemplate(text, data)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to compute cpes of %s: %w", DescribeDependency(dependency), err)
+				}
+				enrichment.CPEs = append(enrichment.CPEs, cpe)
+			}
+		}
+
+		enriched = append(enriched, dependency)
+		if enrichment.PURL != "" || len(enrichment.CPEs) > 0 {
+			enrichments = append(enrichments, enrichment)
+		}
+	}
+
+	return enriched, enrichments, nil
+}
+
+// DependencyCPEs holds the explicit `cpes` of dependencies, keyed by their ID,
+// version, OS, architecture and stacks. cargo does not model `cpes`, so they
+// are read from the TOML file and written back by SetDependencyCPEs.
+type DependencyCPEs map[string][]string
+
+// ReadDependencyCPEs returns the `cpes` of the metadata dependencies of the
+// TOML file at path.
+func ReadDependencyCPEs(path string) (DependencyCPEs, error) {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependency cpes: %w", err)
+	}
+
+	cpes := DependencyCPEs{}
+	dependencies, _ := tree.GetPath([]string{"metadata", "dependencies"}).([]*toml.Tree)
+	for _, dependency := range dependencies {
+		var entry struct {
+			ID      string   `toml:"id"`
+			Version string   `toml:"version"`
+			OS      string   `toml:"os"`
+			Arch    string   `toml:"arch"`
+			Stacks  []string `toml:"stacks"`
+			CPEs    []string `toml:"cpes"`
+		}
+		err = dependency.Unmarshal(&entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dependency cpes: %w", err)
+		}
+
+		if len(entry.CPEs) > 0 {
+			cpes.Set(cargo.ConfigMetadataDependency{ID: entry.ID, Version: entry.Version, OS: entry.OS, Arch: entry.Arch, Stacks: entry.Stacks}, entry.CPEs)
+		}
+	}
+
+	return cpes, nil
+}
+
+// Set sets the explicit cpes of the dependency.
+func (c DependencyCPEs) Set(dependency cargo.ConfigMetadataDependency, cpes []string) {
+	c[dependencyKey(dependency)] = cpes
+}
+
+// Get returns the explicit cpes of the dependency.
+func (c DependencyCPEs) Get(dependency cargo.ConfigMetadataDependency) []string {
+	return c[dependencyKey(dependency)]
+}
+
+// Merge adds the cpes of the other dependencies that have none yet.
+func (c DependencyCPEs) Merge(other DependencyCPEs) {
+	for key, cpes := range other {
+		if _, ok := c[key]; !ok {
+			c[key] = cpes
+		}
+	}
+}
+
+// IndexDependencyCPEs returns the `cpes` to write for each of the
+// dependencies, by index: their explicit cpes, or else the cpes computed by
+// the enrichments.
+func IndexDependencyCPEs(dependencies []cargo.ConfigMetadataDependency, explicit DependencyCPEs, enrichments []DependencyEnrichment) map[int][]string {
+	cpes := map[int][]string{}
+	for i, dependency := range dependencies {
+		if values := explicit.Get(dependency); len(values) > 0 {
+			cpes[i] = values
+		}
+	}
+
+	for _, enrichment := range enrichments {
+		if _, ok := cpes[enrichment.Index]; !ok && len(enrichment.CPEs) > 0 {
+			cpes[enrichment.Index] = enrichment.CPEs
+		}
+	}
+
+	return cpes
+}
+
+// SetDependencyCPEs sets the `cpes` of the metadata dependencies in the TOML
+// content, by index. The content is returned unchanged when there are no cpes
+// to set.
+func SetDependencyCPEs(content []byte, cpes map[int][]string) ([]byte, error) {
+	if len(cpes) == 0 {
+		return content, nil
+	}
+
+	tree, err := toml.LoadBytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set dependency cpes: %w", err)
+	}
+
+	dependencies, ok := tree.GetPath([]string{"metadata", "dependencies"}).([]*toml.Tree)
+	if !ok {
+		return content, nil
+	}
+
+	for i, values := range cpes {
+		if i >= len(dependencies) {
+			continue
+		}
+
+		var array []interface{}
+		for _, cpe := range values {
+			array = append(array, cpe)
+		}
+		dependencies[i].Set("cpes", array)
+	}
+
+	content, err = tree.Marshal()
+	if err != nil {
+		//untested
+		return nil, fmt.Errorf("failed to set dependency cpes: %w", err)
+	}
+
+	return content, nil
+}
+
+func executeEnrichmentTemplate(text string, data EnrichmentData) (string, error) {
+	t, err := template.New("enrichment").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = t.Execute(buffer, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
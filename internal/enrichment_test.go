@@ -0,0 +1,254 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/pelletier/go-toml"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEnrichment(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		templates internal.EnrichmentTemplates
+	)
+
+	it.Before(func() {
+		templates = internal.EnrichmentTemplates{
+			"node": {
+				PURL: "pkg:generic/node@{{.Version}}?checksum={{.SourceChecksum}}&download_url={{.Source}}",
+				CPEs: []string{
+					"cpe:2.3:a:nodejs:node.js:{{.Version}}:*:*:*:*:*:*:*",
+					"cpe:2.3:a:nodejs:nodejs:{{.Version}}:*:*:*:*:*:*:*",
+				},
+			},
+			"*": {
+				PURL: "pkg:generic/{{.ID}}@{{.Version}}",
+			},
+		}
+	})
+
+	context("ReadEnrichmentTemplates", func() {
+		var path string
+
+		it.Before(func() {
+			path = filepath.Join(t.TempDir(), "templates.json")
+		})
+
+		it("reads the templates of every dependency ID", func() {
+			Expect(os.WriteFile(path, []byte(`{
+				"node": {"purl": "pkg:generic/node@{{.Version}}", "cpes": ["cpe:2.3:a:nodejs:node.js:{{.Version}}:*:*:*:*:*:*:*"]}
+			}`), 0600)).To(Succeed())
+
+			templates, err := internal.ReadEnrichmentTemplates(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(templates).To(Equal(internal.EnrichmentTemplates{
+				"node": {
+					PURL: "pkg:generic/node@{{.Version}}",
+					CPEs: []string{"cpe:2.3:a:nodejs:node.js:{{.Version}}:*:*:*:*:*:*:*"},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the file does not exist", func() {
+				_, err := internal.ReadEnrichmentTemplates(filepath.Join(t.TempDir(), "missing.json"))
+				Expect(err).To(MatchError(ContainSubstring("failed to open enrichment templates")))
+			})
+
+			it("returns an error when the file is not valid JSON", func() {
+				Expect(os.WriteFile(path, []byte(`%%%`), 0600)).To(Succeed())
+
+				_, err := internal.ReadEnrichmentTemplates(path)
+				Expect(err).To(MatchError(ContainSubstring("failed to decode enrichment templates")))
+			})
+
+			it("returns an error when a template cannot be parsed", func() {
+				Expect(os.WriteFile(path, []byte(`{"node": {"purl": "pkg:generic/node@{{.Version"}}`), 0600)).To(Succeed())
+
+				_, err := internal.ReadEnrichmentTemplates(path)
+				Expect(err).To(MatchError(ContainSubstring("invalid enrichment template for node")))
+			})
+		})
+	})
+
+	context("EnrichDependencies", func() {
+		it("computes the missing purls and cpes without overwriting explicit values", func() {
+			dependencies, enrichments, err := internal.EnrichDependencies([]cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "20.9.0", Source: "https://nodejs.org/node-v20.9.0.tar.gz", SourceSHA256: "some-source-sha"},
+				{ID: "node", Version: "18.18.0", PURL: "some-purl", CPE: "some-cpe"},
+				{ID: "python", Version: "3.12.1"},
+			}, templates, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{
+				{
+					ID:           "node",
+					Version:      "20.9.0",
+					Source:       "https://nodejs.org/node-v20.9.0.tar.gz",
+					SourceSHA256: "some-source-sha",
+					PURL:         "pkg:generic/node@20.9.0?checksum=sha256:some-source-sha&download_url=https://nodejs.org/node-v20.9.0.tar.gz",
+				},
+				{ID: "node", Version: "18.18.0", PURL: "some-purl", CPE: "some-cpe"},
+				{ID: "python", Version: "3.12.1", PURL: "pkg:generic/python@3.12.1"},
+			}))
+			Expect(enrichments).To(Equal([]internal.DependencyEnrichment{
+				{
+					Index: 0,
+					PURL:  "pkg:generic/node@20.9.0?checksum=sha256:some-source-sha&download_url=https://nodejs.org/node-v20.9.0.tar.gz",
+					CPEs: []string{
+						"cpe:2.3:a:nodejs:node.js:20.9.0:*:*:*:*:*:*:*",
+						"cpe:2.3:a:nodejs:nodejs:20.9.0:*:*:*:*:*:*:*",
+					},
+				},
+				{Index: 2, PURL: "pkg:generic/python@3.12.1"},
+			}))
+		})
+
+		it("does not compute the cpes of dependencies with explicit cpes", func() {
+			dependency := cargo.ConfigMetadataDependency{ID: "node", Version: "20.9.0", PURL: "some-purl"}
+
+			explicit := internal.DependencyCPEs{}
+			explicit.Set(dependency, []string{"some-cpe"})
+
+			dependencies, enrichments, err := internal.EnrichDependencies([]cargo.ConfigMetadataDependency{dependency}, templates, explicit)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{dependency}))
+			Expect(enrichments).To(BeEmpty())
+		})
+
+		it("leaves dependencies without a template unchanged", func() {
+			delete(templates, "*")
+
+			dependencies, enrichments, err := internal.EnrichDependencies([]cargo.ConfigMetadataDependency{{ID: "python", Version: "3.12.1"}}, templates, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{{ID: "python", Version: "3.12.1"}}))
+			Expect(enrichments).To(BeEmpty())
+		})
+
+		context("failure cases", func() {
+			it("returns an error when a template refers to an unknown field", func() {
+				templates["node"] = internal.EnrichmentTemplate{PURL: "pkg:generic/node@{{.Release}}"}
+
+				_, _, err := internal.EnrichDependencies([]cargo.ConfigMetadataDependency{{ID: "node", Version: "20.9.0"}}, templates, nil)
+				Expect(err).To(MatchError(ContainSubstring("failed to compute purl of node 20.9.0")))
+			})
+		})
+	})
+
+	context("ReadDependencyCPEs", func() {
+		var path string
+
+		it.Before(func() {
+			path = filepath.Join(t.TempDir(), "buildpack.toml")
+			Expect(os.WriteFile(path, []byte(`[metadata]
+  [[metadata.dependencies]]
+    cpes = ["some-cpe", "other-cpe"]
+    id = "node"
+    stacks = ["some-stack"]
+    version = "20.9.0"
+
+  [[metadata.dependencies]]
+    id = "node"
+    version = "18.18.0"
+`), 0600)).To(Succeed())
+		})
+
+		it("returns the cpes of the dependencies that have them", func() {
+			cpes, err := internal.ReadDependencyCPEs(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cpes).To(HaveLen(1))
+			Expect(cpes.Get(cargo.ConfigMetadataDependency{ID: "node", Version: "20.9.0", Stacks: []string{"some-stack"}})).To(Equal([]string{"some-cpe", "other-cpe"}))
+			Expect(cpes.Get(cargo.ConfigMetadataDependency{ID: "node", Version: "18.18.0"})).To(BeEmpty())
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the file cannot be parsed", func() {
+				Expect(os.WriteFile(path, []byte("not TOML ["), 0600)).To(Succeed())
+
+				_, err := internal.ReadDependencyCPEs(path)
+				Expect(err).To(MatchError(ContainSubstring("failed to read dependency cpes")))
+			})
+		})
+	})
+
+	context("Merge", func() {
+		it("adds the cpes of the dependencies that have none", func() {
+			node := cargo.ConfigMetadataDependency{ID: "node", Version: "20.9.0"}
+			yarn := cargo.ConfigMetadataDependency{ID: "yarn", Version: "1.22.19"}
+
+			cpes := internal.DependencyCPEs{}
+			cpes.Set(node, []string{"some-cpe"})
+
+			other := internal.DependencyCPEs{}
+			other.Set(node, []string{"other-cpe"})
+			other.Set(yarn, []string{"yarn-cpe", "other-yarn-cpe"})
+
+			cpes.Merge(other)
+			Expect(cpes.Get(node)).To(Equal([]string{"some-cpe"}))
+			Expect(cpes.Get(yarn)).To(Equal([]string{"yarn-cpe", "other-yarn-cpe"}))
+		})
+	})
+
+	context("IndexDependencyCPEs", func() {
+		it("prefers the explicit cpes over the computed ones", func() {
+			dependencies := []cargo.ConfigMetadataDependency{
+				{ID: "node", Version: "20.9.0"},
+				{ID: "node", Version: "18.18.0"},
+				{ID: "node", Version: "16.20.2"},
+			}
+
+			explicit := internal.DependencyCPEs{}
+			explicit.Set(dependencies[1], []string{"some-cpe"})
+
+			Expect(internal.IndexDependencyCPEs(dependencies, explicit, []internal.DependencyEnrichment{
+				{Index: 0, CPEs: []string{"computed-cpe"}},
+				{Index: 2, PURL: "some-purl"},
+			})).To(Equal(map[int][]string{
+				0: {"computed-cpe"},
+				1: {"some-cpe"},
+			}))
+		})
+	})
+
+	context("SetDependencyCPEs", func() {
+		it("sets the cpes of the dependencies by index", func() {
+			content, err := internal.SetDependencyCPEs([]byte(`[metadata]
+  [[metadata.dependencies]]
+    id = "node"
+    version = "20.9.0"
+
+  [[metadata.dependencies]]
+    id = "node"
+    version = "18.18.0"
+`), map[int][]string{
+				1: {"cpe:2.3:a:nodejs:node.js:18.18.0:*:*:*:*:*:*:*"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var config struct {
+				Metadata struct {
+					Dependencies []struct {
+						CPEs []string `toml:"cpes"`
+					} `toml:"dependencies"`
+				} `toml:"metadata"`
+			}
+			Expect(toml.Unmarshal(content, &config)).To(Succeed())
+			Expect(config.Metadata.Dependencies[0].CPEs).To(BeEmpty())
+			Expect(config.Metadata.Dependencies[1].CPEs).To(Equal([]string{"cpe:2.3:a:nodejs:node.js:18.18.0:*:*:*:*:*:*:*"}))
+		})
+
+		it("returns the content unchanged when there are no cpes to set", func() {
+			content, err := internal.SetDependencyCPEs([]byte("not TOML ["), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("not TOML ["))
+		})
+	})
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/pelletier/go-toml"
+)
+
+// ParseExtensionMetadata parses the metadata of the extension.toml at path.
+// cargo.ExtensionConfig does not model the dependency-constraints of an
+// extension, or the targets and identifiers of its dependencies, so the
+// metadata is decoded as the metadata of a buildpack. The other tables of the
+// extension.toml are not decoded.
+func ParseExtensionMetadata(path string) (cargo.ConfigMetadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return cargo.ConfigMetadata{}, err
+	}
+	defer func() {
+		if err2 := file.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	var config cargo.Config
+	err = cargo.DecodeConfig(file, &config)
+	if err != nil {
+		return cargo.ConfigMetadata{}, err
+	}
+
+	return config.Metadata, err // err should be nil here, but return err to catch deferred error
+}
+
+// RenderExtensionMetadata returns the content of the extension.toml at path
+// with its dependencies and default versions replaced by those of the given
+// metadata, and with the given `cpes` of the dependencies, see
+// SetDependencyCPEs.
+// Every other table and key is kept, and only the values that changed are
+// edited, see PreserveTOMLLayout, which writes its warnings to output.
+func RenderExtensionMetadata(path string, metadata cargo.ConfigMetadata, cpes map[int][]string, output io.Writer) ([]byte, error) {
+	original, err := toml.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extension config: %w", err)
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	err = cargo.EncodeConfig(buffer, cargo.Config{
+		Metadata: cargo.ConfigMetadata{
+			DefaultVersions: metadata.DefaultVersions,
+			Dependencies:    metadata.Dependencies,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write extension config: %w", err)
+	}
+
+	content, err := SetDependencyCPEs(buffer.Bytes(), cpes)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := toml.LoadBytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write extension config: %w", err)
+	}
+
+	for _, key := range []string{"default-versions", "dependencies"} {
+		keys := []string{"metadata", key}
+		switch {
+		case updated.HasPath(keys):
+			original.SetPath(keys, updated.GetPath(keys))
+		case original.HasPath(keys):
+			err = original.DeletePath(keys)
+			if err != nil {
+				//untested
+				return nil, fmt.Errorf("failed to write extension config: %w", err)
+			}
+		}
+	}
+
+	content, err = original.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write extension config: %w", err)
+	}
+
+	return PreserveTOMLLayout(path, content, output, func(content []byte) (interface{}, error) {
+		tree, err := toml.LoadBytes(content)
+		if err != nil {
+			return nil, err
+		}
+		return tree.ToMap(), nil
+	}), nil
+}
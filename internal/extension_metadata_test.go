@@ -0,0 +1,119 @@
+package internal_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testExtensionMetadata(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "extension.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+
+[extension]
+  id = "some-extension"
+
+# the dependencies are updated by jam
+[metadata]
+  include-files = ["extension.toml"]
+
+  [metadata.default-versions]
+    some-dependency = "1.2.3"
+
+  [[metadata.dependencies]]
+    id = "some-dependency"
+    os = "linux"
+    purl = "some-purl"
+    uri = "some-uri"
+    version = "1.2.3"
+
+[[metadata.dependency-constraints]]
+  constraint = "1.*"
+  id = "some-dependency"
+  patches = 1
+`), 0600)).To(Succeed())
+	})
+
+	context("ParseExtensionMetadata", func() {
+		it("returns the dependencies, dependency-constraints and default versions", func() {
+			metadata, err := internal.ParseExtensionMetadata(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata).To(Equal(cargo.ConfigMetadata{
+				IncludeFiles:    []string{"extension.toml"},
+				DefaultVersions: map[string]string{"some-dependency": "1.2.3"},
+				Dependencies: []cargo.ConfigMetadataDependency{
+					{ID: "some-dependency", OS: "linux", PURL: "some-purl", URI: "some-uri", Version: "1.2.3"},
+				},
+				DependencyConstraints: []cargo.ConfigMetadataDependencyConstraint{
+					{Constraint: "1.*", ID: "some-dependency", Patches: 1},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the file does not exist", func() {
+				_, err := internal.ParseExtensionMetadata(filepath.Join(t.TempDir(), "missing.toml"))
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+			})
+		})
+	})
+
+	context("RenderExtensionMetadata", func() {
+		it("replaces the dependencies and default versions, keeping everything else", func() {
+			content, err := internal.RenderExtensionMetadata(path, cargo.ConfigMetadata{
+				DefaultVersions: map[string]string{"some-dependency": "1.2.4"},
+				Dependencies: []cargo.ConfigMetadataDependency{
+					{ID: "some-dependency", OS: "linux", PURL: "other-purl", URI: "other-uri", Version: "1.2.4"},
+				},
+			}, nil, io.Discard)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal(`api = "0.7"
+
+[extension]
+  id = "some-extension"
+
+# the dependencies are updated by jam
+[metadata]
+  include-files = ["extension.toml"]
+
+  [metadata.default-versions]
+    some-dependency = "1.2.4"
+
+  [[metadata.dependencies]]
+    id = "some-dependency"
+    os = "linux"
+    purl = "other-purl"
+    uri = "other-uri"
+    version = "1.2.4"
+
+[[metadata.dependency-constraints]]
+  constraint = "1.*"
+  id = "some-dependency"
+  patches = 1
+`))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the file cannot be parsed", func() {
+				Expect(os.WriteFile(path, []byte("not TOML ["), 0600)).To(Succeed())
+
+				_, err := internal.RenderExtensionMetadata(path, cargo.ConfigMetadata{}, nil, io.Discard)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse extension config")))
+			})
+		})
+	})
+}
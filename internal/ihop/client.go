@@ -40,6 +40,7 @@ import (
 	"github.com/moby/buildkit/util/progress/progressui"
 	"github.com/moby/go-archive"
 	"github.com/opencontainers/go-digest"
+	"github.com/paketo-buildpacks/jam/v2/internal"
 )
 
 // An Image is a representation of a container image that can be built,
@@ -118,6 +119,7 @@ type Client struct {
 	dir      string
 	docker   *docker.Client
 	keychain authn.Keychain
+	insecure internal.InsecureRegistries
 }
 
 // NewClient returns a Client that has been configured to interact with the
@@ -135,6 +137,13 @@ func NewClient(dir string) (Client, error) {
 	}, nil
 }
 
+// WithInsecureRegistries returns a copy of the Client that uploads to the
+// given registries over plain HTTP or without verifying their certificate.
+func (c Client) WithInsecureRegistries(registries internal.InsecureRegistries) Client {
+	c.insecure = registries
+	return c
+}
+
 // Build uses BuildKit to build a container image from its reference Dockerfile
 // as specified in the given DefinitionImage. Specifying a platform other than
 // the native platform for the Docker daemon will require that the daemon is
@@ -561,12 +570,12 @@ func (c Client) Upload(refName string, fromDir string) error {
 		return err
 	}
 
-	ref, err := name.ParseReference(refName)
+	ref, err := c.insecure.ParseReference(refName)
 	if err != nil {
 		return err
 	}
 
-	return remote.WriteIndex(ref, imageIndex, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	return remote.WriteIndex(ref, imageIndex, c.insecure.RemoteOptions(ref.Context().Registry)...)
 }
 
 func (c Client) imageToDirectory(images []Image) (string, error) {
@@ -3,7 +3,9 @@ package internal
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"sort"
 	"time"
 
@@ -11,7 +13,6 @@ import (
 	"github.com/buildpacks/pack/pkg/buildpack"
 	backoff "github.com/cenkalti/backoff/v4"
 	"github.com/distribution/reference"
-	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
@@ -22,8 +23,192 @@ type Image struct {
 	Version string
 }
 
-func FindLatestImageOnCNBRegistry(uri, api, patchVersion string) (Image, error) {
+// FindOption configures how the latest version of an image is found.
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	constraints []*semver.Constraints
+	lookup      *RegistryLookup
+	platforms   []Platform
+	insecure    InsecureRegistries
+	channel     Channel
+	output      io.Writer
+	apis        *APICompatibility
+
+	registryIndex string
+}
+
+// WithConstraint limits the versions that are considered to those whose
+// major, minor and patch components satisfy the constraint. A nil constraint
+// is ignored.
+func WithConstraint(constraint *semver.Constraints) FindOption {
+	return func(o *findOptions) {
+		if constraint != nil {
+			o.constraints = append(o.constraints, constraint)
+		}
+	}
+}
+
+// WithRegistryLookup fetches tag lists through the given lookup so that they
+// are cached and rate limited requests are retried. The insecure registries
+// and the output of the lookup are used as well.
+func WithRegistryLookup(lookup *RegistryLookup) FindOption {
+	return func(o *findOptions) {
+		o.lookup = lookup
+		o.insecure = append(o.insecure, lookup.insecure...)
+		o.output = lookup.output
+	}
+}
+
+// WithInsecureRegistries allows the given registries to be reached over plain
+// HTTP or without verifying their certificate.
+func WithInsecureRegistries(registries InsecureRegistries) FindOption {
+	return func(o *findOptions) {
+		o.insecure = append(o.insecure, registries...)
+	}
+}
+
+// WithPlatforms limits the versions that are considered to those whose image
+// is available for every one of the given platforms. Versions are checked from
+// the highest down, so only the images of the versions that are skipped and of
+// the version that is found are fetched.
+func WithPlatforms(platforms []Platform) FindOption {
+	return func(o *findOptions) {
+		o.platforms = append(o.platforms, platforms...)
+	}
+}
+
+// WithChannel considers the prerelease versions of the given channel. Only
+// versions without a prerelease component are considered by default.
+func WithChannel(channel Channel) FindOption {
+	return func(o *findOptions) {
+		o.channel = channel
+	}
+}
+
+// WithRegistryIndex reads the versions of CNB registry buildpacks from a local
+// checkout of the registry index at the given directory instead of the
+// registry API.
+func WithRegistryIndex(dir string) FindOption {
+	return func(o *findOptions) {
+		o.registryIndex = dir
+	}
+}
+
+// WithSupportedAPIs limits the versions that are considered to lifecycle
+// images that support every one of the given buildpack and platform APIs, as
+// declared by their io.buildpacks.lifecycle.apis label. Versions are checked
+// from the highest down, so only the labels of the versions that are skipped
+// and of the version that is found are fetched.
+func WithSupportedAPIs(buildpacks []BuildpackAPI, platforms []string) FindOption {
+	return func(o *findOptions) {
+		o.apis = &APICompatibility{Buildpacks: buildpacks, Platforms: platforms}
+	}
+}
+
+// WithRetryOutput reports the retries of rate limited requests to the CNB
+// registry, and the skipped build image versions, to the given output instead
+// of stdout.
+func WithRetryOutput(output io.Writer) FindOption {
+	return func(o *findOptions) {
+		o.output = output
+	}
+}
+
+func newFindOptions(options []FindOption) findOptions {
+	o := findOptions{output: os.Stdout}
+	for _, option := range options {
+		option(&o)
+	}
+
+	return o
+}
+
+// allows checks the version against every constraint. Prerelease suffixes are
+// ignored so that suffixed stack tags (ex. 1.2.3-some-cnb) can be constrained.
+func (o findOptions) allows(version *semver.Version) bool {
+	core := semver.New(version.Major(), version.Minor(), version.Patch(), "", "")
+	for _, constraint := range o.constraints {
+		if !constraint.Check(core) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o findOptions) listTags(repo name.Repository) ([]string, error) {
+	if o.lookup != nil {
+		return o.lookup.ListTags(repo)
+	}
+
+	return listTags(repo, o.insecure)
+}
+
+// supportsAPIs returns true if the lifecycle image supports every buildpack and
+// platform API given to WithSupportedAPIs.
+func (o findOptions) supportsAPIs(uri string) (bool, error) {
+	if o.apis == nil {
+		return true, nil
+	}
+
+	var labels map[string]string
+	var err error
+	if o.lookup != nil {
+		labels, err = o.lookup.Labels(uri)
+	} else {
+		labels, err = getImageLabels(uri, o.insecure)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	lifecycle, err := ParseLifecycleAPIs(labels)
+	if err != nil {
+		return false, fmt.Errorf("failed to read lifecycle APIs of %s: %w", uri, err)
+	}
+
+	compatibility := *o.apis
+	compatibility.Lifecycle = lifecycle
+
+	return compatibility.Check() == nil, nil
+}
+
+func (o findOptions) imagePlatforms(uri string) ([]Platform, error) {
+	if o.lookup != nil {
+		return o.lookup.Platforms(uri)
+	}
+
+	return getImagePlatforms(uri, o.insecure)
+}
+
+// filter removes the tags that are semantic versions which are not allowed by
+// the constraints.
+func (o findOptions) filter(tags []string) []string {
+	if len(o.constraints) == 0 {
+		return tags
+	}
+
+	var allowed []string
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err == nil && !o.allows(version) {
+			continue
+		}
+		allowed = append(allowed, tag)
+	}
+
+	return allowed
+}
+
+func FindLatestImageOnCNBRegistry(uri, api, patchVersion string, options ...FindOption) (Image, error) {
+	opts := newFindOptions(options)
+
 	id, _ := buildpack.ParseIDLocator(uri)
+	if opts.registryIndex != "" {
+		return findLatestImageInRegistryIndex(id, patchVersion, opts)
+	}
+
 	var resp *http.Response
 	var err error
 
@@ -53,8 +238,8 @@ func FindLatestImageOnCNBRegistry(uri, api, patchVersion string) (Image, error)
 	},
 		exponentialBackoff,
 		func(err error, t time.Duration) {
-			fmt.Println(err)
-			fmt.Printf("Retrying in %s\n", t)
+			fmt.Fprintln(opts.output, err)
+			fmt.Fprintf(opts.output, "Retrying in %s\n", t)
 		},
 	)
 	if err != nil {
@@ -80,14 +265,41 @@ func FindLatestImageOnCNBRegistry(uri, api, patchVersion string) (Image, error)
 		return Image{}, err
 	}
 
-	// If a patch version is passed in, get the highest patch in the same minor version line
-	if patchVersion != "" {
-		versions := []string{}
-		for _, v := range metadata.Versions {
-			versions = append(versions, v.Version)
+	var versions []string
+	for _, v := range metadata.Versions {
+		versions = append(versions, v.Version)
+	}
+
+	return cnbRegistryImage(id, metadata.Latest.Version, versions, patchVersion, opts)
+}
+
+// findLatestImageInRegistryIndex selects the version of the buildpack from the
+// versions listed in a local checkout of the CNB registry index. Yanked
+// versions are never selected.
+func findLatestImageInRegistryIndex(id, patchVersion string, opts findOptions) (Image, error) {
+	entries, err := ReadRegistryIndex(opts.registryIndex, id)
+	if err != nil {
+		return Image{}, err
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if !entry.Yanked {
+			versions = append(versions, entry.Version)
 		}
+	}
 
-		highestPatch, err := getHighestPatch(patchVersion, versions)
+	// the index does not record the latest version, so it is found like a constrained version
+	return cnbRegistryImage(id, "", versions, patchVersion, opts)
+}
+
+// cnbRegistryImage selects the version of the buildpack with the given ID from
+// the versions listed by the CNB registry, where latest is the version that
+// the registry reports as the latest.
+func cnbRegistryImage(id, latest string, versions []string, patchVersion string, opts findOptions) (Image, error) {
+	// If a patch version is passed in, get the highest patch in the same minor version line
+	if patchVersion != "" {
+		highestPatch, err := getHighestPatch(patchVersion, opts.filter(versions), opts.channel)
 		if err != nil {
 			return Image{}, fmt.Errorf("could not get the highest patch in the %s line: %w", patchVersion, err)
 		}
@@ -95,39 +307,66 @@ func FindLatestImageOnCNBRegistry(uri, api, patchVersion string) (Image, error)
 			Name:    fmt.Sprintf("urn:cnb:registry:%s", id),
 			Path:    id,
 			Version: highestPatch,
-		}, err // err should be nil here, but return err to catch deferred error
+		}, nil
+	}
+
+	// When the versions are constrained, the latest version may not be allowed,
+	// and the latest version of the registry is never a prerelease
+	if latest == "" || len(opts.constraints) > 0 || !opts.channel.Stable() {
+		var allowed []*semver.Version
+		for _, v := range versions {
+			version, err := semver.StrictNewVersion(v)
+			if err != nil || !opts.channel.Allows(version) || !opts.allows(version) {
+				continue
+			}
+			allowed = append(allowed, version)
+		}
+
+		if len(allowed) == 0 {
+			return Image{}, fmt.Errorf("could not find any valid version for %s", id)
+		}
+
+		sort.Sort(semver.Collection(allowed))
+
+		return Image{
+			Name:    fmt.Sprintf("urn:cnb:registry:%s", id),
+			Path:    id,
+			Version: allowed[len(allowed)-1].String(),
+		}, nil
 	}
 
 	return Image{
 		Name:    fmt.Sprintf("urn:cnb:registry:%s", id),
 		Path:    id,
-		Version: metadata.Latest.Version,
-	}, err // err should be nil here, but return err to catch deferred error
+		Version: latest,
+	}, nil
 }
 
-func FindLatestImage(uri, patchVersion string) (Image, error) {
+func FindLatestImage(uri, patchVersion string, options ...FindOption) (Image, error) {
+	opts := newFindOptions(options)
+
 	named, err := reference.ParseNormalizedNamed(uri)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse image reference %q: %w", uri, err)
 	}
 
-	repo, err := name.NewRepository(reference.Path(named))
+	repo, err := name.NewRepository(reference.Path(named), opts.insecure.NameOptions(reference.Domain(named))...)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse image repository: %w", err)
 	}
 
-	repo.Registry, err = name.NewRegistry(reference.Domain(named))
+	repo.Registry, err = name.NewRegistry(reference.Domain(named), opts.insecure.NameOptions(reference.Domain(named))...)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse image registry: %w", err)
 	}
 
-	tags, err := remote.List(repo, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tags, err := opts.listTags(repo)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to list tags: %w", err)
 	}
 
 	if patchVersion != "" {
-		highestPatch, err := getHighestPatch(patchVersion, tags)
+		highestPatch, err := getHighestPatch(patchVersion, opts.filter(tags), opts.channel)
 		if err != nil {
 			return Image{}, fmt.Errorf("could not get the highest patch in the %s line: %w", patchVersion, err)
 		}
@@ -144,7 +383,10 @@ func FindLatestImage(uri, patchVersion string) (Image, error) {
 		if err != nil {
 			continue
 		}
-		if version.Prerelease() != "" {
+		if !opts.channel.Allows(version) {
+			continue
+		}
+		if !opts.allows(version) {
 			continue
 		}
 		versions = append(versions, version)
@@ -156,6 +398,44 @@ func FindLatestImage(uri, patchVersion string) (Image, error) {
 
 	sort.Sort(semver.Collection(versions))
 
+	if len(opts.platforms) > 0 {
+		for i := len(versions) - 1; i >= 0; i-- {
+			platforms, err := opts.imagePlatforms(fmt.Sprintf("%s:%s", named.Name(), versions[i].Original()))
+			if err != nil {
+				return Image{}, err
+			}
+
+			if len((TargetAvailability{Platforms: platforms}).Missing(opts.platforms)) == 0 {
+				return Image{
+					Name:    named.Name(),
+					Path:    reference.Path(named),
+					Version: versions[i].String(),
+				}, nil
+			}
+		}
+
+		return Image{}, fmt.Errorf("could not find any tag for %s that is available for every target", repo.Name())
+	}
+
+	if opts.apis != nil {
+		for i := len(versions) - 1; i >= 0; i-- {
+			supported, err := opts.supportsAPIs(fmt.Sprintf("%s:%s", named.Name(), versions[i].Original()))
+			if err != nil {
+				return Image{}, err
+			}
+
+			if supported {
+				return Image{
+					Name:    named.Name(),
+					Path:    reference.Path(named),
+					Version: versions[i].String(),
+				}, nil
+			}
+		}
+
+		return Image{}, fmt.Errorf("could not find any tag for %s that supports every buildpack and platform API", repo.Name())
+	}
+
 	return Image{
 		Name:    named.Name(),
 		Path:    reference.Path(named),
@@ -164,8 +444,8 @@ func FindLatestImage(uri, patchVersion string) (Image, error) {
 }
 
 // Finds latest build image, and the matching run image (if the run image has a semantic version, instead of latest)
-func FindLatestStackImages(runURI, buildURI string) (Image, Image, error) {
-	buildImage, err := FindLatestBuildImage(runURI, buildURI)
+func FindLatestStackImages(runURI, buildURI string, options ...FindOption) (Image, Image, error) {
+	buildImage, err := FindLatestBuildImage(runURI, buildURI, options...)
 	if err != nil {
 		return Image{}, Image{}, fmt.Errorf("failed to find latest build image: %w", err)
 	}
@@ -203,7 +483,9 @@ func UpdateRunImageMirrors(version string, mirrors []string) ([]string, error) {
 	return mirrors, nil
 }
 
-func FindLatestBuildImage(runURI, buildURI string) (Image, error) {
+func FindLatestBuildImage(runURI, buildURI string, options ...FindOption) (Image, error) {
+	opts := newFindOptions(options)
+
 	_, runTag, err := ParseImageURI(runURI)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse run image: %w", err)
@@ -215,17 +497,17 @@ func FindLatestBuildImage(runURI, buildURI string) (Image, error) {
 		return Image{}, fmt.Errorf("failed to parse build image reference %q: %w", buildURI, err)
 	}
 
-	repo, err := name.NewRepository(reference.Path(buildNamed))
+	repo, err := name.NewRepository(reference.Path(buildNamed), opts.insecure.NameOptions(reference.Domain(buildNamed))...)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse build image repository: %w", err)
 	}
 
-	repo.Registry, err = name.NewRegistry(reference.Domain(buildNamed))
+	repo.Registry, err = name.NewRegistry(reference.Domain(buildNamed), opts.insecure.NameOptions(reference.Domain(buildNamed))...)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to parse build image registry: %w", err)
 	}
 
-	tags, err := remote.List(repo, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tags, err := opts.listTags(repo)
 	if err != nil {
 		return Image{}, fmt.Errorf("failed to list tags: %w", err)
 	}
@@ -242,8 +524,13 @@ func FindLatestBuildImage(runURI, buildURI string) (Image, error) {
 		// <image>:1.2.3-suffix) (ex. <image>:suffix), it should be equal to the
 		// run image tag suffix in order to be considered as a valid version
 		// See this PR for more context: https://github.com/paketo-buildpacks/jam/pull/81
-		if version.Prerelease() != "" && runTagSuffix != version.Prerelease() {
-			fmt.Printf("Skipping build image version: %s, the tag suffix does not match run image tag: %s\n", tag, runTagSuffix)
+		// Prerelease versions of the channel are considered as well.
+		if version.Prerelease() != "" && runTagSuffix != version.Prerelease() && !opts.channel.Allows(version) {
+			fmt.Fprintf(opts.output, "Skipping build image version: %s, the tag suffix does not match run image tag: %s\n", tag, runTagSuffix)
+			continue
+		}
+
+		if !opts.allows(version) {
 			continue
 		}
 
@@ -307,12 +594,16 @@ func checkRunImageTag(tag string) (bool, string) {
 }
 
 func GetBuildpackageID(uri string) (string, error) {
-	ref, err := name.ParseReference(uri)
+	return getBuildpackageID(uri, nil)
+}
+
+func getBuildpackageID(uri string, insecure InsecureRegistries, options ...remote.Option) (string, error) {
+	ref, err := insecure.ParseReference(uri)
 	if err != nil {
 		return "", err
 	}
 
-	image, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	image, err := remote.Image(ref, append(insecure.RemoteOptions(ref.Context().Registry), options...)...)
 	if err != nil {
 		return "", err
 	}
@@ -340,7 +631,45 @@ func GetBuildpackageID(uri string) (string, error) {
 	return metadata.BuildpackageID, nil
 }
 
-func getHighestPatch(patchVersion string, allVersions []string) (string, error) {
+// GetImageDigest returns the digest of the manifest that the given image
+// reference points to. For multi-arch images this is the digest of the image
+// index, so a pinned reference still resolves for every platform.
+func GetImageDigest(uri string) (string, error) {
+	return getImageDigest(uri, nil)
+}
+
+func getImageDigest(uri string, insecure InsecureRegistries, options ...remote.Option) (string, error) {
+	ref, err := insecure.ParseReference(uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image reference %q: %w", uri, err)
+	}
+
+	descriptor, err := remote.Get(ref, append(insecure.RemoteOptions(ref.Context().Registry), options...)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get digest for %s: %w", uri, err)
+	}
+
+	return descriptor.Digest.String(), nil
+}
+
+// PinnedImageReference returns a reference to the given image version that
+// is pinned to the digest of the tag, ex. <image>:1.2.3@sha256:<digest>.
+func PinnedImageReference(image Image) (string, error) {
+	tagged := fmt.Sprintf("%s:%s", image.Name, image.Version)
+
+	digest, err := GetImageDigest(tagged)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s@%s", tagged, digest), nil
+}
+
+func listTags(repo name.Repository, insecure InsecureRegistries, options ...remote.Option) ([]string, error) {
+	return remote.List(repo, append(insecure.RemoteOptions(repo.Registry), options...)...)
+}
+
+func getHighestPatch(patchVersion string, allVersions []string, channel Channel) (string, error) {
 	versionConstraint, err := semver.NewConstraint(fmt.Sprintf("~%s", patchVersion))
 	if err != nil {
 		return "", fmt.Errorf("version constraint ~%s is not a valid semantic version constraint: %w", patchVersion, err)
@@ -355,11 +684,14 @@ func getHighestPatch(patchVersion string, allVersions []string) (string, error)
 		if err != nil {
 			continue
 		}
-		if version.Prerelease() != "" {
+		if !channel.Allows(version) {
 			continue
 		}
 
-		if versionConstraint.Check(version) {
+		// prerelease versions are checked by their major, minor and patch
+		// components, as the constraint would otherwise exclude them
+		core := semver.New(version.Major(), version.Minor(), version.Patch(), "", "")
+		if versionConstraint.Check(core) {
 			if version.GreaterThan(highestPatch) {
 				highestPatch = version
 			}
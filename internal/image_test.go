@@ -1,6 +1,8 @@
 package internal_test
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/paketo-buildpacks/jam/v2/internal"
 	"github.com/sclevine/spec"
 
@@ -161,13 +164,15 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 
 		context("when the request fails the first time", func() {
 			it("retries the request", func() {
-				image, err := internal.FindLatestImageOnCNBRegistry("retry-endpoint", server.URL, "")
+				output := bytes.NewBuffer(nil)
+				image, err := internal.FindLatestImageOnCNBRegistry("retry-endpoint", server.URL, "", internal.WithRetryOutput(output))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(image).To(Equal(internal.Image{
 					Name:    "urn:cnb:registry:retry-endpoint",
 					Path:    "retry-endpoint",
 					Version: "0.1.0",
 				}))
+				Expect(output.String()).To(ContainSubstring("unexpected response status: 429 Too Many Requests\nRetrying in"))
 			})
 		})
 
@@ -192,6 +197,86 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 						Version: "0.0.1",
 					}))
 				})
+
+				context("when the rc channel is given", func() {
+					it("returns the highest release candidate patch", func() {
+						channel, err := internal.ParseChannel("rc")
+						Expect(err).NotTo(HaveOccurred())
+
+						image, err := internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:no-new-patch@0.0.1", server.URL, "0.0.1", internal.WithChannel(channel))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(image).To(Equal(internal.Image{
+							Name:    "urn:cnb:registry:no-new-patch",
+							Path:    "no-new-patch",
+							Version: "0.0.3-rc",
+						}))
+					})
+				})
+			})
+		})
+
+		context("when a constraint is given", func() {
+			it("returns the latest version that satisfies the constraint", func() {
+				constraint, err := semver.NewConstraint("< 0.1.0")
+				Expect(err).NotTo(HaveOccurred())
+
+				image, err := internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:some-ns/some-name@0.0.1", server.URL, "", internal.WithConstraint(constraint))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image).To(Equal(internal.Image{
+					Name:    "urn:cnb:registry:some-ns/some-name",
+					Path:    "some-ns/some-name",
+					Version: "0.0.3",
+				}))
+			})
+
+			context("when no version satisfies the constraint", func() {
+				it("returns an error", func() {
+					constraint, err := semver.NewConstraint("> 1.0.0")
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:some-ns/some-name@0.0.1", server.URL, "", internal.WithConstraint(constraint))
+					Expect(err).To(MatchError("could not find any valid version for some-ns/some-name"))
+				})
+			})
+		})
+
+		context("when a registry index is given", func() {
+			var dir string
+
+			it.Before(func() {
+				dir = t.TempDir()
+
+				Expect(os.MkdirAll(filepath.Join(dir, "so", "me"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, "so", "me", "some-ns_some-name"), []byte(`{"ns":"some-ns","name":"some-name","version":"0.0.1","yanked":false,"addr":"some-registry/some-name@sha256:aaa"}
+{"ns":"some-ns","name":"some-name","version":"0.0.2","yanked":false,"addr":"some-registry/some-name@sha256:bbb"}
+{"ns":"some-ns","name":"some-name","version":"0.0.3","yanked":true,"addr":"some-registry/some-name@sha256:ccc"}
+{"ns":"some-ns","name":"some-name","version":"0.1.0","yanked":false,"addr":"some-registry/some-name@sha256:ddd"}
+`), 0600)).To(Succeed())
+			})
+
+			it("returns the latest version that is not yanked without using the api", func() {
+				image, err := internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:some-ns/some-name@0.0.1", "not a valid URL", "", internal.WithRegistryIndex(dir))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image).To(Equal(internal.Image{
+					Name:    "urn:cnb:registry:some-ns/some-name",
+					Path:    "some-ns/some-name",
+					Version: "0.1.0",
+				}))
+			})
+
+			context("when a patch version to base the lookup off of is given", func() {
+				it("returns the highest patch that is not yanked", func() {
+					image, err := internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:some-ns/some-name@0.0.1", "not a valid URL", "0.0.1", internal.WithRegistryIndex(dir))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(image.Version).To(Equal("0.0.2"))
+				})
+			})
+
+			context("when the buildpack is not in the index", func() {
+				it("returns an error", func() {
+					_, err := internal.FindLatestImageOnCNBRegistry("urn:cnb:registry:some-ns/other-name@0.0.1", "not a valid URL", "", internal.WithRegistryIndex(dir))
+					Expect(err).To(MatchError(ContainSubstring("failed to read registry index for some-ns/other-name")))
+				})
 			})
 		})
 
@@ -346,6 +431,80 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the rc channel is given", func() {
+			it("returns the latest semver tag including release candidates", func() {
+				channel, err := internal.ParseChannel("rc")
+				Expect(err).NotTo(HaveOccurred())
+
+				image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-repo:latest", strings.TrimPrefix(server.URL, "http://")), "", internal.WithChannel(channel))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image).To(Equal(internal.Image{
+					Name:    fmt.Sprintf("%s/some-org/some-repo", strings.TrimPrefix(server.URL, "http://")),
+					Path:    "some-org/some-repo",
+					Version: "0.20.13-rc1",
+				}))
+			})
+
+			context("when a patch version is also given", func() {
+				it("returns the latest release candidate patch", func() {
+					channel, err := internal.ParseChannel("rc")
+					Expect(err).NotTo(HaveOccurred())
+
+					image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/no-new-patch:0.0.1", strings.TrimPrefix(server.URL, "http://")), "0.0.1", internal.WithChannel(channel))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(image.Version).To(Equal("0.0.3-rc"))
+				})
+			})
+		})
+
+		context("when the image uri is pinned to a digest", func() {
+			it("returns the latest semver tag for the image", func() {
+				image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-repo:0.0.9@sha256:%s", strings.TrimPrefix(server.URL, "http://"), strings.Repeat("a", 64)), "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image).To(Equal(internal.Image{
+					Name:    fmt.Sprintf("%s/some-org/some-repo", strings.TrimPrefix(server.URL, "http://")),
+					Path:    "some-org/some-repo",
+					Version: "0.20.12",
+				}))
+			})
+		})
+
+		context("when a constraint is given", func() {
+			it("returns the latest semver tag that satisfies the constraint", func() {
+				constraint, err := semver.NewConstraint("< 0.20.0")
+				Expect(err).NotTo(HaveOccurred())
+
+				image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-repo:0.0.9", strings.TrimPrefix(server.URL, "http://")), "", internal.WithConstraint(constraint))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image).To(Equal(internal.Image{
+					Name:    fmt.Sprintf("%s/some-org/some-repo", strings.TrimPrefix(server.URL, "http://")),
+					Path:    "some-org/some-repo",
+					Version: "0.0.10",
+				}))
+			})
+
+			context("when a patch version is also given", func() {
+				it("returns the latest semver patch that satisfies the constraint", func() {
+					constraint, err := semver.NewConstraint("< 0.0.10")
+					Expect(err).NotTo(HaveOccurred())
+
+					image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-repo:0.0.8", strings.TrimPrefix(server.URL, "http://")), "0.0.8", internal.WithConstraint(constraint))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(image.Version).To(Equal("0.0.9"))
+				})
+			})
+
+			context("when no tag satisfies the constraint", func() {
+				it("returns an error", func() {
+					constraint, err := semver.NewConstraint("> 1000000.0.0")
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-repo:0.0.9", strings.TrimPrefix(server.URL, "http://")), "", internal.WithConstraint(constraint))
+					Expect(err).To(MatchError(ContainSubstring("could not find any valid tag for")))
+				})
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the uri cannot be parsed", func() {
 				it("returns an error", func() {
@@ -481,6 +640,20 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		it("for suffixed stack repos, when a constraint is given, it returns the latest semver tag within the constraint", func() {
+			constraint, err := semver.NewConstraint(">= 0.0.0, < 0.1.0")
+			Expect(err).NotTo(HaveOccurred())
+
+			runImage, buildImage, err := internal.FindLatestStackImages(
+				fmt.Sprintf("%s/some-org/some-repo-run:0.0.9-some-cnb", strings.TrimPrefix(server.URL, "http://")),
+				fmt.Sprintf("%s/some-org/some-repo-build:0.0.9-some-cnb", strings.TrimPrefix(server.URL, "http://")),
+				internal.WithConstraint(constraint),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buildImage.Version).To(Equal("0.0.10"))
+			Expect(runImage.Version).To(Equal("0.0.10"))
+		})
+
 		it("for suffixed stack repos, when the run image is not semver, it returns the latest semver tag for the build image only", func() {
 			runImage, buildImage, err := internal.FindLatestStackImages(
 				fmt.Sprintf("%s/some-org/some-repo-run:some-cnb", strings.TrimPrefix(server.URL, "http://")),
@@ -513,6 +686,20 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		it("for non-suffixed stack repos, when a channel is given, it returns the latest semver tag including the prereleases of the channel", func() {
+			channel, err := internal.ParseChannel(`^other-cnb$`)
+			Expect(err).NotTo(HaveOccurred())
+
+			runImage, buildImage, err := internal.FindLatestStackImages(
+				fmt.Sprintf("%s/some-org/some-repo-run:0.0.10", strings.TrimPrefix(server.URL, "http://")),
+				fmt.Sprintf("%s/some-org/some-repo-build:0.0.10", strings.TrimPrefix(server.URL, "http://")),
+				internal.WithChannel(channel),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buildImage.Version).To(Equal("0.20.12-other-cnb"))
+			Expect(runImage.Version).To(Equal("0.20.12-other-cnb"))
+		})
+
 		it("for non-suffixed stack repos, when the run image is `latest`, it returns the latest semver tag for the build image only", func() {
 			runImage, buildImage, err := internal.FindLatestStackImages(
 				fmt.Sprintf("%s/some-org/some-repo-run:latest", strings.TrimPrefix(server.URL, "http://")),
@@ -688,6 +875,17 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		it("reports the skipped build image versions to the given output", func() {
+			output := bytes.NewBuffer(nil)
+			_, err := internal.FindLatestBuildImage(
+				fmt.Sprintf("%s/some-org/some-repo-run:some-cnb", strings.TrimPrefix(server.URL, "http://")),
+				fmt.Sprintf("%s/some-org/some-repo-build:0.0.10-some-cnb", strings.TrimPrefix(server.URL, "http://")),
+				internal.WithRetryOutput(output),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.String()).To(ContainSubstring("Skipping build image version: 0.20.12-other-cnb, the tag suffix does not match run image tag: some-cnb"))
+		})
+
 		it("for non-suffixed stack repos, returns the latest semver tag for the given image uri", func() {
 			image, err := internal.FindLatestBuildImage(
 				fmt.Sprintf("%s/some-org/some-repo-run:latest", strings.TrimPrefix(server.URL, "http://")),
@@ -774,6 +972,66 @@ func testImage(t *testing.T, context spec.G, it spec.S) {
 		})
 	}, spec.Sequential())
 
+	context("PinnedImageReference", func() {
+		var manifest string
+
+		it.Before(func() {
+			manifest = `{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json", "manifests": []}`
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/v2/":
+					w.WriteHeader(http.StatusOK)
+
+				case "/v2/some-org/some-repo/manifests/0.20.12":
+					w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+					w.WriteHeader(http.StatusOK)
+					_, err := fmt.Fprint(w, manifest)
+					Expect(err).NotTo(HaveOccurred())
+
+				case "/v2/some-org/some-repo/manifests/0.0.1":
+					w.WriteHeader(http.StatusNotFound)
+
+				default:
+					t.Fatalf("unknown path: %s", req.URL.Path)
+				}
+			}))
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("returns the tagged reference pinned to the digest of the manifest", func() {
+			ref, err := internal.PinnedImageReference(internal.Image{
+				Name:    fmt.Sprintf("%s/some-org/some-repo", strings.TrimPrefix(server.URL, "http://")),
+				Path:    "some-org/some-repo",
+				Version: "0.20.12",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref).To(Equal(fmt.Sprintf("%s/some-org/some-repo:0.20.12@sha256:%x", strings.TrimPrefix(server.URL, "http://"), sha256.Sum256([]byte(manifest)))))
+		})
+
+		context("failure cases", func() {
+			context("when the image reference cannot be parsed", func() {
+				it("returns an error", func() {
+					_, err := internal.PinnedImageReference(internal.Image{Name: "some garbage uri", Version: "0.0.1"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse image reference")))
+				})
+			})
+
+			context("when the manifest cannot be fetched", func() {
+				it("returns an error", func() {
+					_, err := internal.PinnedImageReference(internal.Image{
+						Name:    fmt.Sprintf("%s/some-org/some-repo", strings.TrimPrefix(server.URL, "http://")),
+						Version: "0.0.1",
+					})
+					Expect(err).To(MatchError(ContainSubstring("failed to get digest for")))
+				})
+			})
+		})
+	})
+
 	context("GetBuildpackageID", func() {
 		it("returns the buildpackage ID from the io.buildpacks.buildpackage.metadata image label", func() {
 			id, err := internal.GetBuildpackageID("index.docker.io/paketobuildpacks/go")
@@ -21,19 +21,40 @@ func TestUnitInternal(t *testing.T) {
 	gomega.SetDefaultEventuallyTimeout(10 * time.Second)
 
 	suite := spec.New("jam/internal", spec.Report(report.Terminal{}))
+	suite("APICompatibility", testAPICompatibility)
 	suite("BuilderConfig", testBuilderConfig)
 	suite("BuildpackConfig", testBuildpackConfig)
+	suite("Channel", testChannel)
 	suite("BuildpackInspector", testBuildpackInspector)
 	suite("ExtensionInspector", testExtensionInspector)
+	suite("ExtensionMetadata", testExtensionMetadata)
 	suite("DependencyCacher", testDependencyCacher)
+	suite("DefaultVersions", testDefaultVersions)
 	suite("Dependency", testDependency)
+	suite("DependencyVerifier", testDependencyVerifier)
+	suite("Deprecation", testDeprecation)
+	suite("Diff", testDiff)
+	suite("Enrichment", testEnrichment)
+	suite("DockerfilePins", testDockerfilePins)
 	suite("FileBundler", testFileBundler)
 	suite("Formatter", testFormatter)
 	suite("ExtensionFormatter", testExtensionFormatter)
 	suite("Image", testImage)
+	suite("InsecureRegistries", testInsecureRegistries)
+	suite("MetadataFiles", testMetadataFiles)
+	suite("MetadataSchema", testMetadataSchema)
+	suite("MetadataValidation", testMetadataValidation)
 	suite("PrePackager", testPrePackager)
 	suite("PackageConfig", testPackageConfig)
+	suite("RegistryIndex", testRegistryIndex)
+	suite("RegistryLookup", testRegistryLookup)
+	suite("TOMLLayout", testTOMLLayout)
+	suite("TargetCompatibility", testTargetCompatibility)
 	suite("TarBuilder", testTarBuilder)
+	suite("UnconstrainedDependencies", testUnconstrainedDependencies)
+	suite("UpdatePolicy", testUpdatePolicy)
+	suite("VersionChange", testVersionChange)
+	suite("VersionHold", testVersionHold)
 	suite.Run(t)
 }
 
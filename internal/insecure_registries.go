@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// InsecureRegistries are the registries, given as host or host:port, that are
+// reached over plain HTTP or over HTTPS without verifying their certificate,
+// ex. a local registry on localhost:5000 or an internal HTTP mirror.
+type InsecureRegistries []string
+
+// ParseInsecureRegistries returns the registries given in the list along with
+// the comma separated registries of the env value.
+func ParseInsecureRegistries(list []string, env string) InsecureRegistries {
+	var registries InsecureRegistries
+	for _, registry := range append(slices.Clone(list), strings.Split(env, ",")...) {
+		registry = strings.TrimSpace(registry)
+		if registry != "" && !slices.Contains(registries, registry) {
+			registries = append(registries, registry)
+		}
+	}
+
+	return registries
+}
+
+// Contains returns true when the given registry is insecure.
+func (r InsecureRegistries) Contains(registry string) bool {
+	for _, insecure := range r {
+		if normalizeRegistry(insecure) == normalizeRegistry(registry) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NameOptions returns the options to construct a name.Reference or
+// name.Repository on the given registry.
+func (r InsecureRegistries) NameOptions(registry string) []name.Option {
+	if r.Contains(registry) {
+		return []name.Option{name.Insecure}
+	}
+
+	return nil
+}
+
+// ParseReference parses the given image reference, allowing plain HTTP when
+// its registry is insecure.
+func (r InsecureRegistries) ParseReference(uri string) (name.Reference, error) {
+	ref, err := name.ParseReference(uri)
+	if err != nil || !r.Contains(ref.Context().RegistryStr()) {
+		return ref, err
+	}
+
+	return name.ParseReference(uri, name.Insecure)
+}
+
+// RemoteOptions returns the options to access the given registry, with the
+// credentials of the default keychain. The certificate of an insecure registry
+// is not verified.
+func (r InsecureRegistries) RemoteOptions(registry name.Registry) []remote.Option {
+	options := []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+	if r.Contains(registry.RegistryStr()) {
+		transport := remote.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // the registry was explicitly marked as insecure
+		options = append(options, remote.WithTransport(transport))
+	}
+
+	return options
+}
+
+// normalizeRegistry returns the registry as it is named by
+// go-containerregistry, ex. docker.io is index.docker.io.
+func normalizeRegistry(registry string) string {
+	r, err := name.NewRegistry(registry)
+	if err != nil {
+		return registry
+	}
+
+	return r.RegistryStr()
+}
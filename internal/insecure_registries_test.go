@@ -0,0 +1,91 @@
+package internal_test
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testInsecureRegistries(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("ParseInsecureRegistries", func() {
+		it("returns the registries of the list and the env value without duplicates", func() {
+			registries := internal.ParseInsecureRegistries(
+				[]string{"localhost:5000", "registry.internal"},
+				" registry.internal, mirror.internal:8080,,",
+			)
+			Expect(registries).To(Equal(internal.InsecureRegistries{"localhost:5000", "registry.internal", "mirror.internal:8080"}))
+		})
+	})
+
+	context("Contains", func() {
+		it("matches the registry as it is named by go-containerregistry", func() {
+			registries := internal.InsecureRegistries{"docker.io", "registry.internal:5000"}
+
+			Expect(registries.Contains("index.docker.io")).To(BeTrue())
+			Expect(registries.Contains("docker.io")).To(BeTrue())
+			Expect(registries.Contains("registry.internal:5000")).To(BeTrue())
+			Expect(registries.Contains("registry.internal")).To(BeFalse())
+			Expect(registries.Contains("gcr.io")).To(BeFalse())
+		})
+	})
+
+	context("ParseReference", func() {
+		it("uses plain HTTP for an insecure registry", func() {
+			registries := internal.InsecureRegistries{"registry.internal:5000"}
+
+			ref, err := registries.ParseReference("registry.internal:5000/some-org/some-image:1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.Context().Scheme()).To(Equal("http"))
+
+			ref, err = registries.ParseReference("gcr.io/some-org/some-image:1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ref.Context().Scheme()).To(Equal("https"))
+		})
+	})
+
+	context("when the registry has a self-signed certificate", func() {
+		var (
+			server *httptest.Server
+			uri    string
+		)
+
+		it.Before(func() {
+			server = httptest.NewTLSServer(registry.New())
+			uri = fmt.Sprintf("%s/some-org/some-image:1.0.0", strings.TrimPrefix(server.URL, "https://"))
+
+			image, err := random.Image(1024, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			ref, err := name.ParseReference(uri)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image, remote.WithTransport(server.Client().Transport))).To(Succeed())
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("reaches the registry only when it is insecure", func() {
+			_, err := internal.NewRegistryLookup(nil, io.Discard).Digest(uri)
+			Expect(err).To(HaveOccurred())
+
+			lookup := internal.NewRegistryLookup(internal.InsecureRegistries{strings.TrimPrefix(server.URL, "https://")}, io.Discard)
+			digest, err := lookup.Digest(uri)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest).To(HavePrefix("sha256:"))
+		})
+	})
+}
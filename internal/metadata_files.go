@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+// A MetadataFile is a JSON, YAML or TOML file that lists new versions of
+// dependencies, as produced by the dependency pipelines, see DecodeMetadata.
+type MetadataFile struct {
+	// Path is the path of the file, or the URL of the dependency API query
+	// the dependencies were read from.
+	Path         string
+	Dependencies []cargo.ConfigMetadataDependency
+	// CPEs are the `cpes` of the dependencies, which cargo does not model.
+	CPEs DependencyCPEs
+}
+
+// ExpandMetadataPaths returns the metadata files given by the paths, which can
+// be files, directories of .json, .yaml, .yml and .toml files or glob
+// patterns. Directories and patterns must contain at least one file.
+func ExpandMetadataPaths(paths []string) ([]string, error) {
+	var files []string
+	add := func(paths ...string) {
+		for _, path := range paths {
+			if !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			var matches []string
+			for _, extension := range MetadataFileExtensions {
+				m, err := filepath.Glob(filepath.Join(path, "*"+extension))
+				if err != nil {
+					//untested
+					return nil, fmt.Errorf("failed to list metadata files in %s: %w", path, err)
+				}
+				matches = append(matches, m...)
+			}
+			sort.Strings(matches)
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no metadata files found in %s", path)
+			}
+			add(matches...)
+
+		case err != nil && strings.ContainsAny(path, "*?["):
+			matches, err := filepath.Glob(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata file pattern %q: %w", path, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no metadata files match %q", path)
+			}
+			add(matches...)
+
+		default:
+			// files that do not exist fail when they are read
+			add(path)
+		}
+	}
+
+	return files, nil
+}
+
+// ReadMetadataFiles reads the metadata files given by the paths, see
+// ExpandMetadataPaths.
+func ReadMetadataFiles(paths []string) ([]MetadataFile, error) {
+	files, err := ExpandMetadataPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadataFiles []MetadataFile
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open metadata file: %w", err)
+		}
+
+		dependencies, cpes, err := DecodeMetadata(path, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode metadata file %s: %w", path, err)
+		}
+
+		metadataFiles = append(metadataFiles, MetadataFile{Path: path, Dependencies: dependencies, CPEs: cpes})
+	}
+
+	return metadataFiles, nil
+}
+
+// MergeMetadataFiles returns the dependencies of every metadata file, in order,
+// without duplicates. Dependencies are duplicates when they have the same ID,
+// version, OS, architecture and stacks. It returns an error when duplicates
+// have different checksums.
+func MergeMetadataFiles(files []MetadataFile) ([]cargo.ConfigMetadataDependency, error) {
+	var merged []cargo.ConfigMetadataDependency
+	seen := map[string]int{}
+	sources := map[string]string{}
+	for _, file := range files {
+		for _, dependency := range file.Dependencies {
+			key := dependencyKey(dependency)
+			i, ok := seen[key]
+			if !ok {
+				seen[key] = len(merged)
+				sources[key] = file.Path
+				merged = append(merged, dependency)
+				continue
+			}
+
+			existing, checksum := dependencyChecksum(merged[i]), dependencyChecksum(dependency)
+			switch {
+			case existing != "" && checksum != "" && existing != checksum:
+				return nil, fmt.Errorf("conflicting checksums for %s: %s in %s and %s in %s",
+					DescribeDependency(dependency), existing, sources[key], checksum, file.Path)
+			case existing == "" && checksum != "":
+				merged[i] = dependency
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+// MetadataSource returns the path of the first metadata file that has a
+// version of the dependency, or an empty string if there is none.
+func MetadataSource(files []MetadataFile, id, version string) string {
+	for _, file := range files {
+		for _, dependency := range file.Dependencies {
+			if dependency.ID == id && dependency.Version == version {
+				return file.Path
+			}
+		}
+	}
+
+	return ""
+}
+
+func dependencyKey(dependency cargo.ConfigMetadataDependency) string {
+	stacks := slices.Clone(dependency.Stacks)
+	slices.Sort(stacks)
+
+	return strings.Join([]string{dependency.ID, dependency.Version, dependency.OS, dependency.Arch, strings.Join(stacks, ",")}, "\x00")
+}
+
+// dependencyChecksum returns the checksum of the dependency as an
+// <algorithm>:<hash> string, falling back to the deprecated SHA256 field.
+func dependencyChecksum(dependency cargo.ConfigMetadataDependency) string {
+	if dependency.Checksum != "" {
+		return dependency.Checksum
+	}
+
+	if dependency.SHA256 != "" {
+		return fmt.Sprintf("sha256:%s", dependency.SHA256)
+	}
+
+	return ""
+}
+
+// DescribeDependency returns the ID and version of the dependency, followed
+// by its target when it has one, ex. node 20.1.0 (linux/amd64) [*].
+func DescribeDependency(dependency cargo.ConfigMetadataDependency) string {
+	description := fmt.Sprintf("%s %s", dependency.ID, dependency.Version)
+	if dependency.OS != "" || dependency.Arch != "" {
+		description = fmt.Sprintf("%s (%s/%s)", description, dependency.OS, dependency.Arch)
+	}
+
+	if len(dependency.Stacks) > 0 {
+		description = fmt.Sprintf("%s [%s]", description, strings.Join(dependency.Stacks, ", "))
+	}
+
+	return description
+}
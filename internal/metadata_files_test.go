@@ -0,0 +1,182 @@
+package internal_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMetadataFiles(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dir string
+	)
+
+	it.Before(func() {
+		dir = t.TempDir()
+		Expect(os.Mkdir(filepath.Join(dir, "metadata"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "metadata", "amd64.json"), []byte(`[{"id": "some-dependency", "version": "1.2.3", "arch": "amd64", "checksum": "sha256:amd64-sha"}]`), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "metadata", "arm64.json"), []byte(`[{"id": "some-dependency", "version": "1.2.3", "arch": "arm64", "checksum": "sha256:arm64-sha"}]`), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "metadata", "notes.txt"), []byte("not metadata"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "other.json"), []byte(`[{"id": "other-dependency", "version": "2.0.0"}]`), 0600)).To(Succeed())
+	})
+
+	context("ExpandMetadataPaths", func() {
+		it("expands directories and glob patterns without duplicates", func() {
+			files, err := internal.ExpandMetadataPaths([]string{
+				filepath.Join(dir, "metadata"),
+				filepath.Join(dir, "*.json"),
+				filepath.Join(dir, "metadata", "arm64.json"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(Equal([]string{
+				filepath.Join(dir, "metadata", "amd64.json"),
+				filepath.Join(dir, "metadata", "arm64.json"),
+				filepath.Join(dir, "other.json"),
+			}))
+		})
+
+		it("expands directories to their JSON, YAML and TOML files", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "metadata", "ppc64le.yaml"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "metadata", "s390x.toml"), nil, 0600)).To(Succeed())
+
+			files, err := internal.ExpandMetadataPaths([]string{filepath.Join(dir, "metadata")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(Equal([]string{
+				filepath.Join(dir, "metadata", "amd64.json"),
+				filepath.Join(dir, "metadata", "arm64.json"),
+				filepath.Join(dir, "metadata", "ppc64le.yaml"),
+				filepath.Join(dir, "metadata", "s390x.toml"),
+			}))
+		})
+
+		it("keeps files that do not exist for them to fail when they are read", func() {
+			files, err := internal.ExpandMetadataPaths([]string{filepath.Join(dir, "missing.json")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(Equal([]string{filepath.Join(dir, "missing.json")}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when a directory has no metadata files", func() {
+				Expect(os.Mkdir(filepath.Join(dir, "empty"), os.ModePerm)).To(Succeed())
+
+				_, err := internal.ExpandMetadataPaths([]string{filepath.Join(dir, "empty")})
+				Expect(err).To(MatchError(ContainSubstring("no metadata files found in")))
+			})
+
+			it("returns an error when a pattern matches no files", func() {
+				_, err := internal.ExpandMetadataPaths([]string{filepath.Join(dir, "*.yml")})
+				Expect(err).To(MatchError(ContainSubstring("no metadata files match")))
+			})
+
+			it("returns an error when a pattern is malformed", func() {
+				_, err := internal.ExpandMetadataPaths([]string{filepath.Join(dir, "[.json")})
+				Expect(err).To(MatchError(ContainSubstring("invalid metadata file pattern")))
+			})
+		})
+	})
+
+	context("ReadMetadataFiles", func() {
+		it("reads the dependencies of every metadata file", func() {
+			files, err := internal.ReadMetadataFiles([]string{filepath.Join(dir, "metadata")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(Equal([]internal.MetadataFile{
+				{
+					Path:         filepath.Join(dir, "metadata", "amd64.json"),
+					Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", Arch: "amd64", Checksum: "sha256:amd64-sha"}},
+					CPEs:         internal.DependencyCPEs{},
+				},
+				{
+					Path:         filepath.Join(dir, "metadata", "arm64.json"),
+					Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", Arch: "arm64", Checksum: "sha256:arm64-sha"}},
+					CPEs:         internal.DependencyCPEs{},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when a file does not exist", func() {
+				_, err := internal.ReadMetadataFiles([]string{filepath.Join(dir, "missing.json")})
+				Expect(err).To(MatchError(ContainSubstring("failed to open metadata file")))
+			})
+
+			it("returns an error that locates the field that does not match the schema", func() {
+				Expect(os.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte("schema-version: 1\ndependencies:\n- id: node\n  version: 1.2.3\n  uri: some-uri\n  sha: some-sha\n"), 0600)).To(Succeed())
+
+				_, err := internal.ReadMetadataFiles([]string{filepath.Join(dir, "invalid.yaml")})
+				Expect(err).To(MatchError(fmt.Sprintf("failed to decode metadata file %s: dependencies[0].sha: unknown field", filepath.Join(dir, "invalid.yaml"))))
+			})
+
+			it("returns an error when a file is not a JSON list of dependencies", func() {
+				_, err := internal.ReadMetadataFiles([]string{filepath.Join(dir, "metadata", "notes.txt")})
+				Expect(err).To(MatchError(ContainSubstring("failed to decode metadata file")))
+			})
+		})
+	})
+
+	context("MergeMetadataFiles", func() {
+		it("de-duplicates the dependencies by ID, version and target", func() {
+			dependencies, err := internal.MergeMetadataFiles([]internal.MetadataFile{
+				{
+					Path: "first.json",
+					Dependencies: []cargo.ConfigMetadataDependency{
+						{ID: "some-dependency", Version: "1.2.3", Stacks: []string{"some-stack", "other-stack"}, Checksum: "sha256:some-sha"},
+						{ID: "some-dependency", Version: "1.2.3", Stacks: []string{"another-stack"}, Checksum: "sha256:another-sha"},
+					},
+				},
+				{
+					Path: "second.json",
+					Dependencies: []cargo.ConfigMetadataDependency{
+						{ID: "some-dependency", Version: "1.2.3", Stacks: []string{"other-stack", "some-stack"}, SHA256: "some-sha"},
+						{ID: "some-dependency", Version: "1.2.3", OS: "linux", Arch: "arm64", Checksum: "sha256:arm64-sha"},
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "some-dependency", Version: "1.2.3", Stacks: []string{"some-stack", "other-stack"}, Checksum: "sha256:some-sha"},
+				{ID: "some-dependency", Version: "1.2.3", Stacks: []string{"another-stack"}, Checksum: "sha256:another-sha"},
+				{ID: "some-dependency", Version: "1.2.3", OS: "linux", Arch: "arm64", Checksum: "sha256:arm64-sha"},
+			}))
+		})
+
+		it("prefers the duplicate that has a checksum", func() {
+			dependencies, err := internal.MergeMetadataFiles([]internal.MetadataFile{
+				{Path: "first.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3"}}},
+				{Path: "second.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", Checksum: "sha256:some-sha"}}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", Checksum: "sha256:some-sha"}}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when duplicates have different checksums", func() {
+				_, err := internal.MergeMetadataFiles([]internal.MetadataFile{
+					{Path: "first.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", OS: "linux", Arch: "amd64", Checksum: "sha256:some-sha"}}},
+					{Path: "second.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3", OS: "linux", Arch: "amd64", SHA256: "other-sha"}}},
+				})
+				Expect(err).To(MatchError("conflicting checksums for some-dependency 1.2.3 (linux/amd64): sha256:some-sha in first.json and sha256:other-sha in second.json"))
+			})
+		})
+	})
+
+	context("MetadataSource", func() {
+		it("returns the first metadata file with the version of the dependency", func() {
+			files := []internal.MetadataFile{
+				{Path: "first.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.3"}}},
+				{Path: "second.json", Dependencies: []cargo.ConfigMetadataDependency{{ID: "some-dependency", Version: "1.2.4"}}},
+			}
+
+			Expect(internal.MetadataSource(files, "some-dependency", "1.2.4")).To(Equal("second.json"))
+			Expect(internal.MetadataSource(files, "other-dependency", "1.2.4")).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,443 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"go.yaml.in/yaml/v3"
+)
+
+// MetadataSchemaVersion is the latest version of the jam metadata schema.
+const MetadataSchemaVersion = 1
+
+// MetadataFileExtensions are the file extensions of the metadata documents
+// that can be read, see DecodeMetadata.
+var MetadataFileExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// metadataField is a field of a dependency in the jam metadata schema. Kind is
+// one of string, strings, integer or date. Integers are non-negative.
+type metadataField struct {
+	Name        string
+	Kind        string
+	Required    bool
+	Description string
+}
+
+// metadataFields are the fields of a dependency in version 1 of the jam
+// metadata schema. They are named independently of cargo, so that producers
+// are not broken by changes to cargo.
+var metadataFields = []metadataField{
+	{Name: "id", Kind: "string", Required: true, Description: "ID of the dependency, ex. node"},
+	{Name: "name", Kind: "string", Description: "human-readable name of the dependency"},
+	{Name: "version", Kind: "string", Required: true, Description: "semantic version of the dependency"},
+	{Name: "uri", Kind: "string", Required: true, Description: "URI of the dependency artifact"},
+	{Name: "checksum", Kind: "string", Description: "checksum of the artifact as <algorithm>:<hash>, ex. sha256:<hash>"},
+	{Name: "source", Kind: "string", Description: "URI of the source of the dependency"},
+	{Name: "source-checksum", Kind: "string", Description: "checksum of the source as <algorithm>:<hash>"},
+	{Name: "stacks", Kind: "strings", Description: "IDs of the stacks the artifact runs on"},
+	{Name: "os", Kind: "string", Description: "operating system the artifact runs on, ex. linux"},
+	{Name: "arch", Kind: "string", Description: "architecture the artifact runs on, ex. amd64"},
+	{Name: "cpes", Kind: "strings", Description: "CPEs of the dependency"},
+	{Name: "purl", Kind: "string", Description: "package URL of the dependency"},
+	{Name: "licenses", Kind: "strings", Description: "SPDX license identifiers of the dependency"},
+	{Name: "deprecation-date", Kind: "date", Description: "date after which the dependency is no longer supported, as an RFC 3339 timestamp"},
+	{Name: "strip-components", Kind: "integer", Description: "number of leading path components to strip when extracting the artifact"},
+}
+
+// metadataDocument is a metadata document of version 1 of the jam metadata
+// schema, once it has been checked against the schema.
+type metadataDocument struct {
+	SchemaVersion int                  `json:"schema-version"`
+	Dependencies  []metadataDependency `json:"dependencies"`
+}
+
+type metadataDependency struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	Version         string    `json:"version"`
+	URI             string    `json:"uri"`
+	Checksum        string    `json:"checksum"`
+	Source          string    `json:"source"`
+	SourceChecksum  string    `json:"source-checksum"`
+	Stacks          []string  `json:"stacks"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	CPEs            []string  `json:"cpes"`
+	PURL            string    `json:"purl"`
+	Licenses        []string  `json:"licenses"`
+	DeprecationDate time.Time `json:"deprecation-date"`
+	StripComponents int       `json:"strip-components"`
+}
+
+// DecodeMetadata decodes the dependencies of a metadata document. The format
+// of the document is given by the extension of its path: YAML for .yaml and
+// .yml, TOML for .toml and JSON otherwise. Documents are objects with a
+// schema-version and a list of dependencies, see MetadataJSONSchema. JSON
+// documents can also be a bare list of dependencies, as decoded by cargo, to
+// support metadata files written before the schema was versioned. The `cpes`
+// of the dependencies are returned separately, as cargo does not model them.
+func DecodeMetadata(path string, content []byte) ([]cargo.ConfigMetadataDependency, DependencyCPEs, error) {
+	var (
+		document interface{}
+		err      error
+	)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &document)
+	case ".toml":
+		var table map[string]interface{}
+		err = toml.Unmarshal(content, &table)
+		document = table
+	default:
+		if trimmed := bytes.TrimSpace(content); bytes.HasPrefix(trimmed, []byte("[")) {
+			return decodeLegacyMetadata(content)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	document = normalizeMetadataValue(document)
+	err = checkMetadataDocument(document)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the document matches the schema, so it can be decoded without losing
+	// values
+	normalized, err := json.Marshal(document)
+	if err != nil {
+		//untested
+		return nil, nil, err
+	}
+
+	var decoded metadataDocument
+	err = json.Unmarshal(normalized, &decoded)
+	if err != nil {
+		//untested
+		return nil, nil, err
+	}
+
+	var dependencies []cargo.ConfigMetadataDependency
+	cpes := DependencyCPEs{}
+	for _, d := range decoded.Dependencies {
+		dependency := cargo.ConfigMetadataDependency{
+			ID:              d.ID,
+			Name:            d.Name,
+			Version:         d.Version,
+			URI:             d.URI,
+			Checksum:        d.Checksum,
+			Source:          d.Source,
+			SourceChecksum:  d.SourceChecksum,
+			Stacks:          d.Stacks,
+			OS:              d.OS,
+			Arch:            d.Arch,
+			PURL:            d.PURL,
+			StripComponents: d.StripComponents,
+		}
+
+		if len(d.CPEs) > 0 {
+			cpes.Set(dependency, d.CPEs)
+		}
+
+		for _, license := range d.Licenses {
+			dependency.Licenses = append(dependency.Licenses, license)
+		}
+
+		if !d.DeprecationDate.IsZero() {
+			deprecationDate := d.DeprecationDate
+			dependency.DeprecationDate = &deprecationDate
+		}
+
+		dependencies = append(dependencies, dependency)
+	}
+
+	return dependencies, cpes, nil
+}
+
+// decodeLegacyMetadata decodes a JSON list of dependencies, as written before
+// the schema was versioned.
+func decodeLegacyMetadata(content []byte) ([]cargo.ConfigMetadataDependency, DependencyCPEs, error) {
+	var dependencies []cargo.ConfigMetadataDependency
+	err := json.Unmarshal(content, &dependencies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var entries []struct {
+		CPEs []string `json:"cpes"`
+	}
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		//untested
+		return nil, nil, err
+	}
+
+	cpes := DependencyCPEs{}
+	for i, entry := range entries {
+		if len(entry.CPEs) > 0 {
+			cpes.Set(dependencies[i], entry.CPEs)
+		}
+	}
+
+	return dependencies, cpes, nil
+}
+
+// checkMetadataDocument returns an error that locates the first field of the
+// document that does not match the jam metadata schema.
+func checkMetadataDocument(document interface{}) error {
+	object, ok := document.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected an object with schema-version and dependencies, got %s", metadataKind(document))
+	}
+
+	for _, key := range sortedKeys(object) {
+		if key != "schema-version" && key != "dependencies" {
+			return fmt.Errorf("%s: unknown field", key)
+		}
+	}
+
+	version, ok := object["schema-version"]
+	if !ok {
+		return fmt.Errorf("schema-version: missing required field")
+	}
+
+	if number, ok := metadataInteger(version); !ok || number != MetadataSchemaVersion {
+		return fmt.Errorf("schema-version: unsupported version %v, must be %d", version, MetadataSchemaVersion)
+	}
+
+	list, ok := object["dependencies"]
+	if !ok {
+		return fmt.Errorf("dependencies: missing required field")
+	}
+
+	dependencies, ok := list.([]interface{})
+	if !ok {
+		return fmt.Errorf("dependencies: expected a list, got %s", metadataKind(list))
+	}
+
+	for i, d := range dependencies {
+		dependency, ok := d.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("dependencies[%d]: expected an object, got %s", i, metadataKind(d))
+		}
+
+		for _, key := range sortedKeys(dependency) {
+			if !containsMetadataField(key) {
+				return fmt.Errorf("dependencies[%d].%s: unknown field", i, key)
+			}
+		}
+
+		for _, field := range metadataFields {
+			value, ok := dependency[field.Name]
+			if !ok {
+				if field.Required {
+					return fmt.Errorf("dependencies[%d].%s: missing required field", i, field.Name)
+				}
+				continue
+			}
+
+			err := checkMetadataValue(field, value)
+			if err != nil {
+				return fmt.Errorf("dependencies[%d].%s: %w", i, field.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkMetadataValue(field metadataField, value interface{}) error {
+	switch field.Kind {
+	case "strings":
+		values, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected a list of strings, got %s", metadataKind(value))
+		}
+
+		for i, v := range values {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("[%d]: expected a string, got %s", i, metadataKind(v))
+			}
+		}
+
+	case "integer":
+		number, ok := metadataInteger(value)
+		if !ok {
+			return fmt.Errorf("expected an integer, got %s", metadataKind(value))
+		}
+
+		if number < 0 {
+			return fmt.Errorf("expected a non-negative integer, got %d", number)
+		}
+
+	case "date":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected an RFC 3339 timestamp, got %s", metadataKind(value))
+		}
+
+		_, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("expected an RFC 3339 timestamp, got %q", v)
+		}
+
+	default:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string, got %s", metadataKind(value))
+		}
+	}
+
+	return nil
+}
+
+// metadataInteger returns the value as an integer if it is one, as decoded
+// from JSON, YAML or TOML.
+func metadataInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case json.Number:
+		number, err := v.Int64()
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// metadataKind describes the type of a decoded value in error messages.
+func metadataKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case int, int64, uint64, float64, json.Number:
+		return "a number"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// normalizeMetadataValue converts the values decoded from YAML and TOML to
+// the values decoded from JSON, ex. timestamps to strings.
+func normalizeMetadataValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		var normalized []interface{}
+		for _, item := range v {
+			normalized = append(normalized, normalizeMetadataValue(item))
+		}
+		return normalized
+	case map[string]interface{}:
+		normalized := map[string]interface{}{}
+		for key, item := range v {
+			normalized[key] = normalizeMetadataValue(item)
+		}
+		return normalized
+	case []interface{}:
+		var normalized []interface{}
+		for _, item := range v {
+			normalized = append(normalized, normalizeMetadataValue(item))
+		}
+		return normalized
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+
+	return value
+}
+
+func containsMetadataField(name string) bool {
+	for _, field := range metadataFields {
+		if field.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	var keys []string
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// MetadataJSONSchema returns the JSON Schema of the latest version of the jam
+// metadata schema.
+func MetadataJSONSchema() ([]byte, error) {
+	properties := map[string]interface{}{}
+	var required []string
+	for _, field := range metadataFields {
+		var property map[string]interface{}
+		switch field.Kind {
+		case "strings":
+			property = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+		case "integer":
+			property = map[string]interface{}{"type": "integer", "minimum": 0}
+		case "date":
+			property = map[string]interface{}{"type": "string", "format": "date-time"}
+		default:
+			property = map[string]interface{}{"type": "string"}
+		}
+		property["description"] = field.Description
+		properties[field.Name] = property
+
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "jam dependency metadata",
+		"description": "New versions of dependencies to add to a buildpack.toml or extension.toml with jam update-dependencies. Documents can be written as JSON, YAML or TOML.",
+		"type":        "object",
+		"properties": map[string]interface{}{
+			"schema-version": map[string]interface{}{
+				"description": "version of the jam metadata schema",
+				"const":       MetadataSchemaVersion,
+			},
+			"dependencies": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":                 "object",
+					"properties":           properties,
+					"required":             required,
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"schema-version", "dependencies"},
+		"additionalProperties": false,
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
@@ -0,0 +1,231 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMetadataSchema(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		expected []cargo.ConfigMetadataDependency
+	)
+
+	it.Before(func() {
+		deprecationDate := time.Date(2026, 4, 30, 0, 0, 0, 0, time.UTC)
+		expected = []cargo.ConfigMetadataDependency{
+			{
+				ID:              "node",
+				Version:         "20.9.0",
+				URI:             "https://example.com/node-20.9.0.tgz",
+				Checksum:        "sha256:some-sha",
+				Stacks:          []string{"some-stack"},
+				Licenses:        []interface{}{"MIT"},
+				DeprecationDate: &deprecationDate,
+				StripComponents: 1,
+			},
+		}
+	})
+
+	context("DecodeMetadata", func() {
+		it("decodes JSON documents", func() {
+			dependencies, cpes, err := internal.DecodeMetadata("metadata.json", []byte(`{
+				"schema-version": 1,
+				"dependencies": [{
+					"id": "node",
+					"version": "20.9.0",
+					"uri": "https://example.com/node-20.9.0.tgz",
+					"checksum": "sha256:some-sha",
+					"stacks": ["some-stack"],
+					"cpes": ["some-cpe", "other-cpe"],
+					"licenses": ["MIT"],
+					"deprecation-date": "2026-04-30T00:00:00Z",
+					"strip-components": 1
+				}]
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal(expected))
+			Expect(cpes.Get(dependencies[0])).To(Equal([]string{"some-cpe", "other-cpe"}))
+		})
+
+		it("decodes YAML documents", func() {
+			dependencies, cpes, err := internal.DecodeMetadata("metadata.yml", []byte(`schema-version: 1
+dependencies:
+- id: node
+  version: "20.9.0"
+  uri: https://example.com/node-20.9.0.tgz
+  checksum: sha256:some-sha
+  stacks: [some-stack]
+  cpes: [some-cpe]
+  licenses: [MIT]
+  deprecation-date: 2026-04-30T00:00:00Z
+  strip-components: 1
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal(expected))
+			Expect(cpes.Get(dependencies[0])).To(Equal([]string{"some-cpe"}))
+		})
+
+		it("decodes TOML documents", func() {
+			dependencies, cpes, err := internal.DecodeMetadata("metadata.toml", []byte(`schema-version = 1
+
+[[dependencies]]
+  id = "node"
+  version = "20.9.0"
+  uri = "https://example.com/node-20.9.0.tgz"
+  checksum = "sha256:some-sha"
+  stacks = ["some-stack"]
+  cpes = ["some-cpe"]
+  licenses = ["MIT"]
+  deprecation-date = 2026-04-30T00:00:00Z
+  strip-components = 1
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal(expected))
+			Expect(cpes.Get(dependencies[0])).To(Equal([]string{"some-cpe"}))
+		})
+
+		it("decodes JSON lists of dependencies written before the schema was versioned", func() {
+			dependencies, cpes, err := internal.DecodeMetadata("metadata.json", []byte(`[{"id": "node", "version": "20.9.0", "sha256": "some-sha", "cpes": ["some-cpe", "other-cpe"]}]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]cargo.ConfigMetadataDependency{{ID: "node", Version: "20.9.0", SHA256: "some-sha"}}))
+			Expect(cpes.Get(dependencies[0])).To(Equal([]string{"some-cpe", "other-cpe"}))
+		})
+
+		context("failure cases", func() {
+			it("returns an error when the document cannot be parsed", func() {
+				_, _, err := internal.DecodeMetadata("metadata.yaml", []byte("schema-version: [1"))
+				Expect(err).To(HaveOccurred())
+			})
+
+			it("returns an error when the document is not an object", func() {
+				_, _, err := internal.DecodeMetadata("metadata.yaml", []byte("- id: node"))
+				Expect(err).To(MatchError("expected an object with schema-version and dependencies, got a list"))
+			})
+
+			it("returns an error when the schema version is missing", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"dependencies": []}`))
+				Expect(err).To(MatchError("schema-version: missing required field"))
+			})
+
+			it("returns an error when the schema version is not supported", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 2, "dependencies": []}`))
+				Expect(err).To(MatchError("schema-version: unsupported version 2, must be 1"))
+			})
+
+			it("returns an error when the document has an unknown field", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": [], "stacks": []}`))
+				Expect(err).To(MatchError("stacks: unknown field"))
+			})
+
+			it("returns an error when the dependencies are not a list", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": {}}`))
+				Expect(err).To(MatchError("dependencies: expected a list, got an object"))
+			})
+
+			it("returns an error when a dependency is not an object", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": ["node"]}`))
+				Expect(err).To(MatchError("dependencies[0]: expected an object, got a string"))
+			})
+
+			it("returns an error when a dependency has an unknown field", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": [
+					{"id": "node", "version": "20.9.0", "uri": "some-uri"},
+					{"id": "node", "version": "20.9.1", "uri": "some-uri", "source_sha256": "some-sha"}
+				]}`))
+				Expect(err).To(MatchError("dependencies[1].source_sha256: unknown field"))
+			})
+
+			it("returns an error when a dependency is missing a required field", func() {
+				_, _, err := internal.DecodeMetadata("metadata.toml", []byte(`schema-version = 1
+
+[[dependencies]]
+  id = "node"
+  uri = "some-uri"
+`))
+				Expect(err).To(MatchError("dependencies[0].version: missing required field"))
+			})
+
+			it("returns an error when a field has the wrong type", func() {
+				_, _, err := internal.DecodeMetadata("metadata.yaml", []byte(`schema-version: 1
+dependencies:
+- id: node
+  version: 20.9
+  uri: some-uri
+`))
+				Expect(err).To(MatchError("dependencies[0].version: expected a string, got a number"))
+			})
+
+			it("returns an error when a list has an item of the wrong type", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": [
+					{"id": "node", "version": "20.9.0", "uri": "some-uri", "stacks": ["some-stack", 1]}
+				]}`))
+				Expect(err).To(MatchError("dependencies[0].stacks: [1]: expected a string, got a number"))
+			})
+
+			it("returns an error when a date is not an RFC 3339 timestamp", func() {
+				_, _, err := internal.DecodeMetadata("metadata.json", []byte(`{"schema-version": 1, "dependencies": [
+					{"id": "node", "version": "20.9.0", "uri": "some-uri", "deprecation-date": "April 2026"}
+				]}`))
+				Expect(err).To(MatchError(`dependencies[0].deprecation-date: expected an RFC 3339 timestamp, got "April 2026"`))
+			})
+
+			it("returns an error when an integer is negative", func() {
+				_, _, err := internal.DecodeMetadata("metadata.toml", []byte(`schema-version = 1
+
+[[dependencies]]
+  id = "node"
+  version = "20.9.0"
+  uri = "some-uri"
+
+[[dependencies]]
+  id = "node"
+  version = "20.10.0"
+  uri = "some-uri"
+  strip-components = -1
+`))
+				Expect(err).To(MatchError("dependencies[1].strip-components: expected a non-negative integer, got -1"))
+			})
+		})
+	})
+
+	context("MetadataJSONSchema", func() {
+		it("returns a JSON Schema of every field of the dependencies", func() {
+			content, err := internal.MetadataJSONSchema()
+			Expect(err).NotTo(HaveOccurred())
+
+			var schema struct {
+				Required   []string `json:"required"`
+				Properties struct {
+					SchemaVersion struct {
+						Const int `json:"const"`
+					} `json:"schema-version"`
+					Dependencies struct {
+						Items struct {
+							Required   []string                   `json:"required"`
+							Properties map[string]json.RawMessage `json:"properties"`
+						} `json:"items"`
+					} `json:"dependencies"`
+				} `json:"properties"`
+			}
+			Expect(json.Unmarshal(content, &schema)).To(Succeed())
+			Expect(schema.Required).To(Equal([]string{"schema-version", "dependencies"}))
+			Expect(schema.Properties.SchemaVersion.Const).To(Equal(internal.MetadataSchemaVersion))
+			Expect(schema.Properties.Dependencies.Items.Required).To(Equal([]string{"id", "version", "uri"}))
+			Expect(schema.Properties.Dependencies.Items.Properties).To(HaveKey("source-checksum"))
+			Expect(string(schema.Properties.Dependencies.Items.Properties["deprecation-date"])).To(MatchJSON(`{
+				"description": "date after which the dependency is no longer supported, as an RFC 3339 timestamp",
+				"format": "date-time",
+				"type": "string"
+			}`))
+		})
+	})
+}
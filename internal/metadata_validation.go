@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+// A MetadataProblem is an issue with an entry of the dependency metadata.
+// Entries with problems that are not warnings must not be written to a
+// buildpack.toml.
+type MetadataProblem struct {
+	Dependency cargo.ConfigMetadataDependency
+	Message    string
+	Warning    bool
+}
+
+func (p MetadataProblem) Error() string {
+	return fmt.Sprintf("%s: %s", DescribeDependency(p.Dependency), p.Message)
+}
+
+// ValidateMetadataDependencies returns the problems of the dependency
+// metadata entries, in order. Stack IDs of the entries must be in the given
+// stacks of the buildpack, unless the buildpack has no stacks or supports
+// any stack. Entries have a CPE when they have a cpe or explicit cpes. Missing
+// purls and cpes are not reported when the enrichment template of the entry
+// computes them.
+func ValidateMetadataDependencies(dependencies []cargo.ConfigMetadataDependency, stacks []cargo.ConfigStack, cpes DependencyCPEs, templates EnrichmentTemplates) []MetadataProblem {
+	var stackIDs []string
+	for _, stack := range stacks {
+		stackIDs = append(stackIDs, stack.ID)
+	}
+
+	var problems []MetadataProblem
+	for _, dependency := range dependencies {
+		problem := func(warning bool, format string, a ...any) {
+			problems = append(problems, MetadataProblem{Dependency: dependency, Message: fmt.Sprintf(format, a...), Warning: warning})
+		}
+
+		if dependency.ID == "" {
+			problem(false, "missing id")
+		}
+
+		if _, err := semver.NewVersion(dependency.Version); err != nil {
+			problem(false, "invalid version %q: must be a semantic version", dependency.Version)
+		}
+
+		if dependency.URI == "" {
+			problem(false, "missing uri")
+		}
+
+		for _, field := range []struct{ name, checksum, sha256 string }{
+			{name: "checksum", checksum: dependency.Checksum, sha256: dependency.SHA256},
+			{name: "source-checksum", checksum: dependency.SourceChecksum, sha256: dependency.SourceSHA256},
+		} {
+			if field.checksum != "" && !validChecksum(field.checksum) {
+				problem(false, "invalid %s %q: must be sha256:<64 hex characters> or sha512:<128 hex characters>", field.name, field.checksum)
+			}
+
+			if field.sha256 != "" && !validChecksum("sha256:"+field.sha256) {
+				problem(false, "invalid %s SHA256 %q: must be 64 hex characters", field.name, field.sha256)
+			}
+		}
+
+		if len(stackIDs) > 0 && !slices.Contains(stackIDs, "*") {
+			for _, stack := range dependency.Stacks {
+				if stack != "*" && !slices.Contains(stackIDs, stack) {
+					problem(false, "unknown stack %q: the buildpack supports %s", stack, strings.Join(stackIDs, ", "))
+				}
+			}
+		}
+
+		if uri, err := url.Parse(dependency.URI); dependency.URI != "" && (err != nil || uri.Scheme == "") {
+			problem(true, "uri %q is not an absolute URL", dependency.URI)
+		}
+
+		if dependency.Checksum == "" && dependency.SHA256 == "" {
+			problem(true, "missing checksum")
+		}
+
+		if dependency.Source != "" && dependency.SourceChecksum == "" && dependency.SourceSHA256 == "" {
+			problem(true, "missing source-checksum")
+		}
+
+		template, _ := templates.Get(dependency.ID)
+		if dependency.PURL == "" && template.PURL == "" {
+			problem(true, "missing purl")
+		}
+
+		if dependency.CPE == "" && len(cpes.Get(dependency)) == 0 && len(template.CPEs) == 0 {
+			problem(true, "missing cpe")
+		}
+	}
+
+	return problems
+}
+
+// validChecksum returns true when the checksum is a sha256 or sha512 hash
+// of the form <algorithm>:<hex>.
+func validChecksum(checksum string) bool {
+	algorithm, hash, found := strings.Cut(checksum, ":")
+	if !found {
+		return false
+	}
+
+	sizes := map[string]int{"sha256": 32, "sha512": 64}
+	size, ok := sizes[algorithm]
+	if !ok {
+		return false
+	}
+
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == size
+}
@@ -0,0 +1,133 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMetadataValidation(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dependency cargo.ConfigMetadataDependency
+	)
+
+	it.Before(func() {
+		dependency = cargo.ConfigMetadataDependency{
+			ID:             "some-dependency",
+			Version:        "1.2.3",
+			URI:            "https://example.com/some-dependency-1.2.3.tgz",
+			Checksum:       "sha256:" + strings.Repeat("a", 64),
+			Source:         "https://example.com/some-dependency-1.2.3-source.tgz",
+			SourceChecksum: "sha512:" + strings.Repeat("b", 128),
+			PURL:           "pkg:generic/some-dependency@1.2.3",
+			CPE:            "cpe:2.3:a:some:dependency:1.2.3:*:*:*:*:*:*:*",
+			Stacks:         []string{"some-stack"},
+		}
+	})
+
+	messages := func(problems []internal.MetadataProblem) []string {
+		var messages []string
+		for _, problem := range problems {
+			messages = append(messages, problem.Error())
+		}
+		return messages
+	}
+
+	context("ValidateMetadataDependencies", func() {
+		it("returns no problems for a complete entry", func() {
+			problems := internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, []cargo.ConfigStack{{ID: "some-stack"}}, nil, nil)
+			Expect(problems).To(BeEmpty())
+		})
+
+		it("returns an error for malformed entries", func() {
+			dependency.ID = ""
+			dependency.Version = "latest"
+			dependency.URI = ""
+			dependency.Checksum = "sha256:not-hex"
+			dependency.SourceChecksum = ""
+			dependency.SourceSHA256 = strings.Repeat("c", 63)
+
+			problems := internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, nil, nil)
+			Expect(messages(problems)).To(Equal([]string{
+				" latest [some-stack]: missing id",
+				` latest [some-stack]: invalid version "latest": must be a semantic version`,
+				" latest [some-stack]: missing uri",
+				` latest [some-stack]: invalid checksum "sha256:not-hex": must be sha256:<64 hex characters> or sha512:<128 hex characters>`,
+				` latest [some-stack]: invalid source-checksum SHA256 "` + strings.Repeat("c", 63) + `": must be 64 hex characters`,
+			}))
+			for _, problem := range problems {
+				Expect(problem.Warning).To(BeFalse())
+			}
+		})
+
+		it("rejects checksums with an unsupported algorithm", func() {
+			dependency.Checksum = "md5:" + strings.Repeat("a", 32)
+
+			problems := internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, nil, nil)
+			Expect(messages(problems)).To(ConsistOf(ContainSubstring(`invalid checksum "md5:`)))
+		})
+
+		it("returns an error for stacks the buildpack does not support", func() {
+			dependency.Stacks = []string{"some-stack", "other-stack", "*"}
+
+			problems := internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, []cargo.ConfigStack{{ID: "some-stack"}, {ID: "another-stack"}}, nil, nil)
+			Expect(messages(problems)).To(Equal([]string{
+				`some-dependency 1.2.3 [some-stack, other-stack, *]: unknown stack "other-stack": the buildpack supports some-stack, another-stack`,
+			}))
+
+			Expect(internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, []cargo.ConfigStack{{ID: "*"}}, nil, nil)).To(BeEmpty())
+		})
+
+		it("returns warnings for incomplete entries", func() {
+			dependency.URI = "some-dependency.tgz"
+			dependency.Checksum = ""
+			dependency.SourceChecksum = ""
+			dependency.PURL = ""
+			dependency.CPE = ""
+
+			problems := internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, nil, nil)
+			Expect(messages(problems)).To(Equal([]string{
+				`some-dependency 1.2.3 [some-stack]: uri "some-dependency.tgz" is not an absolute URL`,
+				"some-dependency 1.2.3 [some-stack]: missing checksum",
+				"some-dependency 1.2.3 [some-stack]: missing source-checksum",
+				"some-dependency 1.2.3 [some-stack]: missing purl",
+				"some-dependency 1.2.3 [some-stack]: missing cpe",
+			}))
+			for _, problem := range problems {
+				Expect(problem.Warning).To(BeTrue())
+			}
+		})
+
+		it("does not warn about a missing cpe when the entry has cpes", func() {
+			dependency.CPE = ""
+
+			cpes := internal.DependencyCPEs{}
+			cpes.Set(dependency, []string{"cpe:2.3:a:some:dependency:1.2.3:*:*:*:*:*:*:*"})
+
+			Expect(internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, cpes, nil)).To(BeEmpty())
+		})
+
+		it("does not warn about a missing purl or cpe that the enrichment template computes", func() {
+			dependency.PURL = ""
+			dependency.CPE = ""
+
+			templates := internal.EnrichmentTemplates{
+				"some-dependency": {CPEs: []string{"cpe:2.3:a:some:dependency:{{.Version}}:*:*:*:*:*:*:*"}},
+				"*":               {PURL: "pkg:generic/{{.ID}}@{{.Version}}"},
+			}
+			Expect(internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, nil, templates)).To(Equal([]internal.MetadataProblem{
+				{Dependency: dependency, Message: "missing purl", Warning: true},
+			}))
+
+			templates["some-dependency"] = internal.EnrichmentTemplate{PURL: "pkg:generic/some-dependency@{{.Version}}", CPEs: templates["some-dependency"].CPEs}
+			Expect(internal.ValidateMetadataDependencies([]cargo.ConfigMetadataDependency{dependency}, nil, nil, templates)).To(BeEmpty())
+		})
+	})
+}
@@ -1,9 +1,12 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/pelletier/go-toml"
@@ -13,10 +16,12 @@ type PackageConfig struct {
 	Buildpack    interface{}               `toml:"buildpack"`
 	Dependencies []PackageConfigDependency `toml:"dependencies"`
 	Targets      []PackageConfigTarget     `toml:"targets,omitempty"`
+	Metadata     ConfigMetadata            `toml:"metadata,omitempty"`
 }
 
 type PackageConfigDependency struct {
-	URI string `toml:"uri"`
+	URI        string `toml:"uri"`
+	Constraint string `toml:"jam-constraint,omitempty"`
 }
 
 type PackageConfigTarget struct {
@@ -34,6 +39,10 @@ func (d *PackageConfigDependency) UnmarshalTOML(v interface{}) error {
 		if uri, ok := m["uri"].(string); ok {
 			d.URI = uri
 		}
+
+		if constraint, ok := m["jam-constraint"].(string); ok {
+			d.Constraint = constraint
+		}
 	}
 
 	if d.URI != "" {
@@ -72,22 +81,45 @@ func ParsePackageConfig(path string) (PackageConfig, error) {
 	return config, err // err should be nil here, but return err to catch deferred error
 }
 
-func OverwritePackageConfig(path string, config PackageConfig) error {
+// MarshalPackageConfig encodes the whole of the given package config.
+func MarshalPackageConfig(config PackageConfig) ([]byte, error) {
+	config.Dependencies = slices.Clone(config.Dependencies)
 	for i, dependency := range config.Dependencies {
 		if !strings.HasPrefix(dependency.URI, "docker://") && !strings.HasPrefix(dependency.URI, "urn:cnb:registry") {
 			config.Dependencies[i].URI = fmt.Sprintf("docker://%s", dependency.URI)
 		}
 	}
 
-	file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC, 0600)
+	buffer := bytes.NewBuffer(nil)
+	err := toml.NewEncoder(buffer).Encode(config)
 	if err != nil {
-		return fmt.Errorf("failed to open package config file: %w", err)
+		return nil, fmt.Errorf("failed to write package config: %w", err)
 	}
 
-	err = toml.NewEncoder(file).Encode(config)
+	return buffer.Bytes(), nil
+}
+
+// RenderPackageConfig returns the content that OverwritePackageConfig writes to
+// the file at path. Only the values that changed are edited in the existing
+// file, see PreserveTOMLLayout, which writes its warnings to output.
+func RenderPackageConfig(path string, config PackageConfig, output io.Writer) ([]byte, error) {
+	content, err := MarshalPackageConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to write package config: %w", err)
+		return nil, err
 	}
 
-	return nil
+	return PreserveTOMLLayout(path, content, output, func(content []byte) (interface{}, error) {
+		var config PackageConfig
+		err := toml.Unmarshal(content, &config)
+		return config, err
+	}), nil
+}
+
+func OverwritePackageConfig(path string, config PackageConfig, output io.Writer) error {
+	content, err := RenderPackageConfig(path, config, output)
+	if err != nil {
+		return err
+	}
+
+	return overwriteFile(path, content, "package config")
 }
@@ -1,6 +1,7 @@
 package internal_test
 
 import (
+	"io"
 	"os"
 	"testing"
 
@@ -79,6 +80,33 @@ func testPackageConfig(t *testing.T, context spec.G, it spec.S) {
 			}))
 		})
 
+		context("when version holds are set", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(path, []byte(`
+[[dependencies]]
+  uri = "docker://some-registry/some-repository/some-buildpack-id:1.2.3"
+  jam-constraint = "< 2.0.0"
+
+[metadata.jam.constraints]
+  "some-repository/other-buildpack-id" = "0.x"
+					`), 0600)).To(Succeed())
+			})
+
+			it("parses the constraints", func() {
+				config, err := internal.ParsePackageConfig(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Dependencies).To(Equal([]internal.PackageConfigDependency{
+					{
+						URI:        "some-registry/some-repository/some-buildpack-id:1.2.3",
+						Constraint: "< 2.0.0",
+					},
+				}))
+				Expect(config.Metadata.Jam.Constraints).To(Equal(map[string]string{
+					"some-repository/other-buildpack-id": "0.x",
+				}))
+			})
+		})
+
 		context("failure cases", func() {
 			context("when the file cannot be opened", func() {
 				it.Before(func() {
@@ -159,7 +187,7 @@ func testPackageConfig(t *testing.T, context spec.G, it spec.S) {
 					{URI: "some-registry/some-repository/other-buildpack-id:0.1.0"},
 					{URI: "urn:cnb:registry:some-registry/some-repository/final-buildpack-id@0.1.0"},
 				},
-			})
+			}, io.Discard)
 			Expect(err).NotTo(HaveOccurred())
 
 			contents, err := os.ReadFile(path)
@@ -182,6 +210,31 @@ func testPackageConfig(t *testing.T, context spec.G, it spec.S) {
 			`))
 		})
 
+		it("preserves version holds", func() {
+			err := internal.OverwritePackageConfig(path, internal.PackageConfig{
+				Dependencies: []internal.PackageConfigDependency{
+					{URI: "some-registry/some-repository/some-buildpack-id:1.2.3", Constraint: "< 2.0.0"},
+				},
+				Metadata: internal.ConfigMetadata{
+					Jam: internal.JamMetadata{
+						Constraints: map[string]string{"some-repository/other-buildpack-id": "0.x"},
+					},
+				},
+			}, io.Discard)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(MatchTOML(`
+				[[dependencies]]
+				uri = "docker://some-registry/some-repository/some-buildpack-id:1.2.3"
+				jam-constraint = "< 2.0.0"
+
+				[metadata.jam.constraints]
+				"some-repository/other-buildpack-id" = "0.x"
+			`))
+		})
+
 		context("failure cases", func() {
 			context("when the file cannot be opened", func() {
 				it.Before(func() {
@@ -189,7 +242,7 @@ func testPackageConfig(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					err := internal.OverwritePackageConfig(path, internal.PackageConfig{})
+					err := internal.OverwritePackageConfig(path, internal.PackageConfig{}, io.Discard)
 					Expect(err).To(MatchError(ContainSubstring("failed to open package config file:")))
 					Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
 				})
@@ -199,7 +252,7 @@ func testPackageConfig(t *testing.T, context spec.G, it spec.S) {
 				it("returns an error", func() {
 					err := internal.OverwritePackageConfig(path, internal.PackageConfig{
 						Buildpack: func() {},
-					})
+					}, io.Discard)
 					Expect(err).To(MatchError(ContainSubstring("failed to write package config:")))
 					Expect(err).To(MatchError(ContainSubstring("Marshal can't handle func()(func)")))
 				})
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RegistryIndexEntry is a version of a buildpack listed in a local checkout
+// of the CNB registry index, see https://github.com/buildpacks/registry-index.
+type RegistryIndexEntry struct {
+	Namespace string `json:"ns"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Yanked    bool   `json:"yanked"`
+	Address   string `json:"addr"`
+}
+
+// RegistryIndexPath returns the path of the file that lists the versions of
+// the buildpack with the given ID (ex. paketo-buildpacks/go) in the registry
+// index at the given directory. The files are laid out in directories named
+// after the first characters of the buildpack name, ex.
+// go/-d/paketo-buildpacks_go-dist or 3/mr/paketo-buildpacks_mri.
+func RegistryIndexPath(dir, id string) (string, error) {
+	namespace, name, ok := strings.Cut(id, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", fmt.Errorf("invalid buildpack ID %q: must be in the form <namespace>/<name>", id)
+	}
+
+	var indexDir string
+	switch len(name) {
+	case 1:
+		indexDir = "1"
+	case 2:
+		indexDir = "2"
+	case 3:
+		indexDir = filepath.Join("3", name[:2])
+	default:
+		indexDir = filepath.Join(name[:2], name[2:4])
+	}
+
+	return filepath.Join(dir, indexDir, fmt.Sprintf("%s_%s", namespace, name)), nil
+}
+
+// ReadRegistryIndex returns every version of the buildpack with the given ID
+// that is listed in the registry index at the given directory, including the
+// yanked versions.
+func ReadRegistryIndex(dir, id string) ([]RegistryIndexEntry, error) {
+	path, err := RegistryIndexPath(dir, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry index for %s: %w", id, err)
+	}
+	defer func() {
+		if err2 := file.Close(); err2 != nil && err == nil {
+			err = err2
+		}
+	}()
+
+	var entries []RegistryIndexEntry
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var entry RegistryIndexEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse registry index for %s at line %d: %w", id, line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry index for %s: %w", id, err)
+	}
+
+	return entries, err // err should be nil here, but return err to catch deferred error
+}
@@ -0,0 +1,85 @@
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistryIndex(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dir string
+	)
+
+	it.Before(func() {
+		dir = t.TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(dir, "go", "-d"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "go", "-d", "paketo-buildpacks_go-dist"), []byte(`{"ns":"paketo-buildpacks","name":"go-dist","version":"0.20.1","yanked":false,"addr":"gcr.io/paketo-buildpacks/go-dist@sha256:aaa"}
+{"ns":"paketo-buildpacks","name":"go-dist","version":"0.21.0","yanked":true,"addr":"gcr.io/paketo-buildpacks/go-dist@sha256:bbb"}
+
+`), 0600)).To(Succeed())
+	})
+
+	context("RegistryIndexPath", func() {
+		it("returns the path of the index file of the buildpack", func() {
+			for id, path := range map[string]string{
+				"some-ns/a":       filepath.Join(dir, "1", "some-ns_a"),
+				"some-ns/ab":      filepath.Join(dir, "2", "some-ns_ab"),
+				"some-ns/mri":     filepath.Join(dir, "3", "mr", "some-ns_mri"),
+				"some-ns/go-dist": filepath.Join(dir, "go", "-d", "some-ns_go-dist"),
+			} {
+				p, err := internal.RegistryIndexPath(dir, id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(Equal(path))
+			}
+		})
+
+		context("failure cases", func() {
+			context("when the ID has no namespace", func() {
+				it("returns an error", func() {
+					_, err := internal.RegistryIndexPath(dir, "go-dist")
+					Expect(err).To(MatchError(`invalid buildpack ID "go-dist": must be in the form <namespace>/<name>`))
+				})
+			})
+		})
+	})
+
+	context("ReadRegistryIndex", func() {
+		it("returns every entry of the index file", func() {
+			entries, err := internal.ReadRegistryIndex(dir, "paketo-buildpacks/go-dist")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]internal.RegistryIndexEntry{
+				{Namespace: "paketo-buildpacks", Name: "go-dist", Version: "0.20.1", Address: "gcr.io/paketo-buildpacks/go-dist@sha256:aaa"},
+				{Namespace: "paketo-buildpacks", Name: "go-dist", Version: "0.21.0", Yanked: true, Address: "gcr.io/paketo-buildpacks/go-dist@sha256:bbb"},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the buildpack is not in the index", func() {
+				it("returns an error", func() {
+					_, err := internal.ReadRegistryIndex(dir, "paketo-buildpacks/go-missing")
+					Expect(err).To(MatchError(ContainSubstring("failed to read registry index for paketo-buildpacks/go-missing")))
+				})
+			})
+
+			context("when a line cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(dir, "go", "-d", "paketo-buildpacks_go-dist"), []byte("{}\n%%%\n"), 0600)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := internal.ReadRegistryIndex(dir, "paketo-buildpacks/go-dist")
+					Expect(err).To(MatchError(ContainSubstring("failed to parse registry index for paketo-buildpacks/go-dist at line 2")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// RegistryLookup caches the tag lists, buildpackage IDs, digests, platforms
+// and labels fetched from image registries so that each is only requested
+// once. It is safe for concurrent use. Requests that are rate limited (429)
+// are retried with an exponential backoff, and every other request waits out
+// the backoff too so that concurrent lookups do not make the rate limiting
+// worse. The retries are reported to the output of the lookup.
+type RegistryLookup struct {
+	mutex      sync.Mutex
+	tags       map[string]*lookupEntry[[]string]
+	ids        map[string]*lookupEntry[string]
+	digests    map[string]*lookupEntry[string]
+	platforms  map[string]*lookupEntry[[]Platform]
+	labels     map[string]*lookupEntry[map[string]string]
+	pauseUntil time.Time
+
+	insecure       InsecureRegistries
+	output         io.Writer
+	maxElapsedTime time.Duration
+}
+
+// lookupRetryStatusCodes leaves rate limited requests to the lookup so that
+// they are retried with its shared backoff instead of by the transport.
+var lookupRetryStatusCodes = remote.WithRetryStatusCodes(
+	http.StatusRequestTimeout,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+)
+
+type lookupEntry[T any] struct {
+	once  sync.Once
+	value T
+	err   error
+}
+
+// NewRegistryLookup returns a lookup that reaches the given insecure
+// registries over plain HTTP or without verifying their certificate, and that
+// reports its retries to the given output.
+func NewRegistryLookup(insecure InsecureRegistries, output io.Writer) *RegistryLookup {
+	return &RegistryLookup{
+		tags:      map[string]*lookupEntry[[]string]{},
+		ids:       map[string]*lookupEntry[string]{},
+		digests:   map[string]*lookupEntry[string]{},
+		platforms: map[string]*lookupEntry[[]Platform]{},
+		labels:    map[string]*lookupEntry[map[string]string]{},
+
+		insecure:       insecure,
+		output:         output,
+		maxElapsedTime: 3 * time.Minute,
+	}
+}
+
+// ListTags returns the tags of the given repository.
+func (l *RegistryLookup) ListTags(repo name.Repository) ([]string, error) {
+	return lookup(l, l.tags, repo.String(), func() ([]string, error) {
+		return listTags(repo, l.insecure, lookupRetryStatusCodes)
+	})
+}
+
+// BuildpackageID returns the buildpackage ID of the given image, see
+// GetBuildpackageID.
+func (l *RegistryLookup) BuildpackageID(uri string) (string, error) {
+	return lookup(l, l.ids, uri, func() (string, error) {
+		return getBuildpackageID(uri, l.insecure, lookupRetryStatusCodes)
+	})
+}
+
+// Digest returns the digest of the given image, see GetImageDigest.
+func (l *RegistryLookup) Digest(uri string) (string, error) {
+	return lookup(l, l.digests, uri, func() (string, error) {
+		return getImageDigest(uri, l.insecure, lookupRetryStatusCodes)
+	})
+}
+
+// Platforms returns the platforms that the given image is available for, see
+// GetImagePlatforms.
+func (l *RegistryLookup) Platforms(uri string) ([]Platform, error) {
+	return lookup(l, l.platforms, uri, func() ([]Platform, error) {
+		return getImagePlatforms(uri, l.insecure, lookupRetryStatusCodes)
+	})
+}
+
+// Labels returns the labels of the given image, see GetImageLabels.
+func (l *RegistryLookup) Labels(uri string) (map[string]string, error) {
+	return lookup(l, l.labels, uri, func() (map[string]string, error) {
+		return getImageLabels(uri, l.insecure, lookupRetryStatusCodes)
+	})
+}
+
+// PinnedImageReference returns a reference to the given image version that
+// is pinned to the digest of the tag, see PinnedImageReference.
+func (l *RegistryLookup) PinnedImageReference(image Image) (string, error) {
+	tagged := fmt.Sprintf("%s:%s", image.Name, image.Version)
+
+	digest, err := l.Digest(tagged)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s@%s", tagged, digest), nil
+}
+
+func lookup[T any](l *RegistryLookup, cache map[string]*lookupEntry[T], key string, fetch func() (T, error)) (T, error) {
+	l.mutex.Lock()
+	entry, ok := cache[key]
+	if !ok {
+		entry = &lookupEntry[T]{}
+		cache[key] = entry
+	}
+	l.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.err = l.retry(func() error {
+			var err error
+			entry.value, err = fetch()
+			return err
+		})
+	})
+
+	return entry.value, entry.err
+}
+
+func (l *RegistryLookup) retry(operation func() error) error {
+	exponentialBackoff := backoff.NewExponentialBackOff()
+	exponentialBackoff.MaxElapsedTime = l.maxElapsedTime
+
+	// only retry when the registry status code is http.StatusTooManyRequests (429)
+	return backoff.RetryNotify(func() error {
+		l.wait()
+
+		err := operation()
+		if err != nil && !isTooManyRequests(err) {
+			return &backoff.PermanentError{Err: err}
+		}
+
+		return err
+	},
+		exponentialBackoff,
+		func(err error, t time.Duration) {
+			l.pause(t)
+			fmt.Fprintln(l.output, err)
+			fmt.Fprintf(l.output, "Retrying in %s\n", t)
+		},
+	)
+}
+
+// pause delays every request made through the lookup by at least the given
+// duration.
+func (l *RegistryLookup) pause(d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pauseUntil) {
+		l.pauseUntil = until
+	}
+}
+
+func (l *RegistryLookup) wait() {
+	l.mutex.Lock()
+	until := l.pauseUntil
+	l.mutex.Unlock()
+
+	time.Sleep(time.Until(until))
+}
+
+func isTooManyRequests(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,175 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRegistryLookup(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		server   *httptest.Server
+		requests map[string]*atomic.Int32
+		lookup   *internal.RegistryLookup
+		host     string
+		output   *bytes.Buffer
+	)
+
+	it.Before(func() {
+		requests = map[string]*atomic.Int32{
+			"/v2/some-org/some-repo/tags/list":      {},
+			"/v2/some-org/limited-repo/tags/list":   {},
+			"/v2/some-org/some-buildpack/manifests": {},
+		}
+
+		handler := registry.New()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/v2/some-org/some-repo/tags/list":
+				requests[req.URL.Path].Add(1)
+				_, err := fmt.Fprintln(w, `{"tags": ["0.0.1", "0.1.0"]}`)
+				Expect(err).NotTo(HaveOccurred())
+
+			case req.URL.Path == "/v2/some-org/limited-repo/tags/list":
+				if requests[req.URL.Path].Add(1) == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, err := fmt.Fprintln(w, `{"tags": ["1.0.0"]}`)
+				Expect(err).NotTo(HaveOccurred())
+
+			default:
+				if strings.HasPrefix(req.URL.Path, "/v2/some-org/some-buildpack/manifests/") && req.Method == http.MethodGet {
+					requests["/v2/some-org/some-buildpack/manifests"].Add(1)
+				}
+				handler.ServeHTTP(w, req)
+			}
+		}))
+		host = strings.TrimPrefix(server.URL, "http://")
+
+		image, err := random.Image(1024, 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		config, err := image.ConfigFile()
+		Expect(err).NotTo(HaveOccurred())
+
+		config.Config.Labels = map[string]string{
+			"io.buildpacks.buildpackage.metadata": `{"id": "some-org/some-buildpack"}`,
+		}
+
+		image, err = mutate.ConfigFile(image, config)
+		Expect(err).NotTo(HaveOccurred())
+
+		ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/some-buildpack:0.1.0", host))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(remote.Write(ref, image)).To(Succeed())
+
+		output = bytes.NewBuffer(nil)
+		lookup = internal.NewRegistryLookup(nil, output)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	context("ListTags", func() {
+		it("lists the tags of the repository once", func() {
+			repo, err := name.NewRepository(fmt.Sprintf("%s/some-org/some-repo", host))
+			Expect(err).NotTo(HaveOccurred())
+
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Go(func() {
+					tags, err := lookup.ListTags(repo)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(tags).To(Equal([]string{"0.0.1", "0.1.0"}))
+				})
+			}
+			wg.Wait()
+
+			Expect(requests["/v2/some-org/some-repo/tags/list"].Load()).To(BeEquivalentTo(1))
+		})
+
+		context("when the request is rate limited", func() {
+			it("retries the request", func() {
+				repo, err := name.NewRepository(fmt.Sprintf("%s/some-org/limited-repo", host))
+				Expect(err).NotTo(HaveOccurred())
+
+				tags, err := lookup.ListTags(repo)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tags).To(Equal([]string{"1.0.0"}))
+
+				Expect(requests["/v2/some-org/limited-repo/tags/list"].Load()).To(BeEquivalentTo(2))
+				Expect(output.String()).To(ContainSubstring("429 Too Many Requests"))
+				Expect(output.String()).To(ContainSubstring("Retrying in"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the tags cannot be listed", func() {
+				it("returns the same error for every lookup", func() {
+					repo, err := name.NewRepository(fmt.Sprintf("%s/some-org/missing-repo", host))
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = lookup.ListTags(repo)
+					Expect(err).To(MatchError(ContainSubstring("NAME_UNKNOWN")))
+
+					_, err2 := lookup.ListTags(repo)
+					Expect(err2).To(Equal(err))
+				})
+			})
+		})
+	})
+
+	context("BuildpackageID", func() {
+		it("fetches the buildpackage ID of the image once", func() {
+			uri := fmt.Sprintf("%s/some-org/some-buildpack:0.1.0", host)
+
+			id, err := lookup.BuildpackageID(uri)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal("some-org/some-buildpack"))
+
+			id, err = lookup.BuildpackageID(uri)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id).To(Equal("some-org/some-buildpack"))
+
+			Expect(requests["/v2/some-org/some-buildpack/manifests"].Load()).To(BeEquivalentTo(1))
+		})
+	})
+
+	context("PinnedImageReference", func() {
+		it("returns the reference pinned to the digest of the tag", func() {
+			ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/some-buildpack:0.1.0", host))
+			Expect(err).NotTo(HaveOccurred())
+
+			descriptor, err := remote.Get(ref)
+			Expect(err).NotTo(HaveOccurred())
+
+			pinned, err := lookup.PinnedImageReference(internal.Image{
+				Name:    fmt.Sprintf("%s/some-org/some-buildpack", host),
+				Path:    "some-org/some-buildpack",
+				Version: "0.1.0",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pinned).To(Equal(fmt.Sprintf("%s/some-org/some-buildpack:0.1.0@%s", host, descriptor.Digest)))
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Platform is an operating system and architecture that an image can be
+// available for.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+func (p Platform) String() string {
+	return fmt.Sprintf("%s/%s", p.OS, p.Arch)
+}
+
+// ParsePlatform parses a platform of the form os/arch or os/arch/variant, ex.
+// linux/arm64/v8. The variant is ignored.
+func ParsePlatform(platform string) (Platform, error) {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q: must be in the form os/arch[/variant]", platform)
+	}
+
+	return Platform{OS: parts[0], Arch: parts[1]}, nil
+}
+
+// TargetPlatforms returns the platforms of the given builder targets.
+func TargetPlatforms(targets []BuilderConfigTarget) []Platform {
+	var platforms []Platform
+	for _, target := range targets {
+		platform := Platform{OS: target.OS, Arch: target.Arch}
+		if !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+
+	return platforms
+}
+
+// TargetAvailability is the set of platforms that a version of an image is
+// available for.
+type TargetAvailability struct {
+	ID        string
+	Image     string
+	Version   string
+	Platforms []Platform
+}
+
+// Missing returns the given platforms that the image is not available for.
+func (a TargetAvailability) Missing(platforms []Platform) []Platform {
+	var missing []Platform
+	for _, platform := range platforms {
+		if !slices.Contains(a.Platforms, platform) {
+			missing = append(missing, platform)
+		}
+	}
+
+	return missing
+}
+
+// PrintTargetMatrix writes a table of the availability of each image for
+// each of the given platforms.
+func PrintTargetMatrix(w io.Writer, platforms []Platform, availability []TargetAvailability) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"IMAGE", "VERSION"}
+	for _, platform := range platforms {
+		header = append(header, strings.ToUpper(platform.String()))
+	}
+
+	_, err := fmt.Fprintln(tw, strings.Join(header, "\t"))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range availability {
+		row := []string{a.Image, a.Version}
+		for _, platform := range platforms {
+			cell := "yes"
+			if !slices.Contains(a.Platforms, platform) {
+				cell = "MISSING"
+			}
+			row = append(row, cell)
+		}
+
+		_, err = fmt.Fprintln(tw, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// GetImagePlatforms returns the platforms that the given image is available
+// for. For an image index these are the platforms of its manifests, otherwise
+// the platform is read from the image config.
+func GetImagePlatforms(uri string) ([]Platform, error) {
+	return getImagePlatforms(uri, nil)
+}
+
+func getImagePlatforms(uri string, insecure InsecureRegistries, options ...remote.Option) ([]Platform, error) {
+	ref, err := insecure.ParseReference(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %w", uri, err)
+	}
+
+	descriptor, err := remote.Get(ref, append(insecure.RemoteOptions(ref.Context().Registry), options...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platforms for %s: %w", uri, err)
+	}
+
+	var platforms []Platform
+	if descriptor.MediaType.IsIndex() {
+		index, err := descriptor.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get platforms for %s: %w", uri, err)
+		}
+
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get platforms for %s: %w", uri, err)
+		}
+
+		for _, m := range manifest.Manifests {
+			// attestation manifests are listed with an unknown platform
+			if m.Platform == nil || m.Platform.OS == "unknown" {
+				continue
+			}
+
+			platform := Platform{OS: m.Platform.OS, Arch: m.Platform.Architecture}
+			if !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+
+		return platforms, nil
+	}
+
+	image, err := descriptor.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platforms for %s: %w", uri, err)
+	}
+
+	config, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get platforms for %s: %w", uri, err)
+	}
+
+	return append(platforms, Platform{OS: config.OS, Arch: config.Architecture}), nil
+}
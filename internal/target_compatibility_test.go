@@ -0,0 +1,188 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTargetCompatibility(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		amd64 = internal.Platform{OS: "linux", Arch: "amd64"}
+		arm64 = internal.Platform{OS: "linux", Arch: "arm64"}
+	)
+
+	context("ParsePlatform", func() {
+		it("parses the os and arch of the platform", func() {
+			platform, err := internal.ParsePlatform("linux/amd64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform).To(Equal(amd64))
+
+			platform, err = internal.ParsePlatform("linux/arm64/v8")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform).To(Equal(arm64))
+		})
+
+		context("failure cases", func() {
+			context("when the platform has no arch", func() {
+				it("returns an error", func() {
+					_, err := internal.ParsePlatform("linux")
+					Expect(err).To(MatchError(`invalid platform "linux": must be in the form os/arch[/variant]`))
+				})
+			})
+		})
+	})
+
+	context("TargetPlatforms", func() {
+		it("returns the distinct platforms of the targets", func() {
+			Expect(internal.TargetPlatforms([]internal.BuilderConfigTarget{
+				{OS: "linux", Arch: "amd64"},
+				{OS: "linux", Arch: "arm64"},
+				{OS: "linux", Arch: "amd64"},
+			})).To(Equal([]internal.Platform{amd64, arm64}))
+		})
+	})
+
+	context("TargetAvailability", func() {
+		it("returns the platforms that are missing", func() {
+			availability := internal.TargetAvailability{Platforms: []internal.Platform{amd64}}
+			Expect(availability.Missing([]internal.Platform{amd64, arm64})).To(Equal([]internal.Platform{arm64}))
+			Expect(availability.Missing([]internal.Platform{amd64})).To(BeEmpty())
+		})
+	})
+
+	context("PrintTargetMatrix", func() {
+		it("prints the availability of every image for every platform", func() {
+			buffer := bytes.NewBuffer(nil)
+			err := internal.PrintTargetMatrix(buffer, []internal.Platform{amd64, arm64}, []internal.TargetAvailability{
+				{Image: "some-registry/some-buildpack", Version: "1.2.3", Platforms: []internal.Platform{amd64, arm64}},
+				{Image: "some-registry/other-buildpack", Version: "2.0.0", Platforms: []internal.Platform{amd64}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buffer.String()).To(Equal(strings.Join([]string{
+				"IMAGE                          VERSION  LINUX/AMD64  LINUX/ARM64",
+				"some-registry/some-buildpack   1.2.3    yes          yes",
+				"some-registry/other-buildpack  2.0.0    yes          MISSING",
+				"",
+			}, "\n")))
+		})
+	})
+
+	context("with a registry", func() {
+		var (
+			server *httptest.Server
+			host   string
+		)
+
+		it.Before(func() {
+			server = httptest.NewServer(registry.New())
+			host = strings.TrimPrefix(server.URL, "http://")
+
+			image := func(platform internal.Platform) v1.Image {
+				img, err := random.Image(1024, 1)
+				Expect(err).NotTo(HaveOccurred())
+
+				config, err := img.ConfigFile()
+				Expect(err).NotTo(HaveOccurred())
+
+				config.OS = platform.OS
+				config.Architecture = platform.Arch
+
+				img, err = mutate.ConfigFile(img, config)
+				Expect(err).NotTo(HaveOccurred())
+
+				return img
+			}
+
+			index := func(platforms ...internal.Platform) v1.ImageIndex {
+				var addenda []mutate.IndexAddendum
+				for _, platform := range platforms {
+					addenda = append(addenda, mutate.IndexAddendum{
+						Add: image(platform),
+						Descriptor: v1.Descriptor{
+							Platform: &v1.Platform{OS: platform.OS, Architecture: platform.Arch},
+						},
+					})
+				}
+
+				return mutate.AppendManifests(empty.Index, addenda...)
+			}
+
+			for tag, idx := range map[string]v1.ImageIndex{
+				"1.0.0": index(amd64, arm64),
+				"1.1.0": index(amd64, arm64),
+				"1.2.0": index(amd64),
+			} {
+				ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/some-buildpack:%s", host, tag))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(remote.WriteIndex(ref, idx)).To(Succeed())
+			}
+
+			ref, err := name.ParseReference(fmt.Sprintf("%s/some-org/single-buildpack:1.0.0", host))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remote.Write(ref, image(arm64))).To(Succeed())
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		context("GetImagePlatforms", func() {
+			it("returns the platforms of an image index", func() {
+				platforms, err := internal.GetImagePlatforms(fmt.Sprintf("%s/some-org/some-buildpack:1.1.0", host))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platforms).To(ConsistOf(amd64, arm64))
+			})
+
+			it("returns the platform of a single image", func() {
+				platforms, err := internal.GetImagePlatforms(fmt.Sprintf("%s/some-org/single-buildpack:1.0.0", host))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platforms).To(Equal([]internal.Platform{arm64}))
+			})
+
+			context("failure cases", func() {
+				context("when the image does not exist", func() {
+					it("returns an error", func() {
+						_, err := internal.GetImagePlatforms(fmt.Sprintf("%s/some-org/some-buildpack:9.9.9", host))
+						Expect(err).To(MatchError(ContainSubstring("failed to get platforms for")))
+					})
+				})
+			})
+		})
+
+		context("FindLatestImage WithPlatforms", func() {
+			it("returns the highest version that is available for every platform", func() {
+				image, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/some-buildpack:1.0.0", host), "",
+					internal.WithRegistryLookup(internal.NewRegistryLookup(nil, io.Discard)),
+					internal.WithPlatforms([]internal.Platform{amd64, arm64}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(image.Version).To(Equal("1.1.0"))
+			})
+
+			context("when no version is available for every platform", func() {
+				it("returns an error", func() {
+					_, err := internal.FindLatestImage(fmt.Sprintf("%s/some-org/single-buildpack:1.0.0", host), "",
+						internal.WithPlatforms([]internal.Platform{amd64}))
+					Expect(err).To(MatchError(ContainSubstring("that is available for every target")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,854 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pelletier/go-toml"
+)
+
+// PreserveTOMLLayout returns the current content of the TOML file at path
+// with only the values that differ from the updated content edited in place,
+// so that comments, key ordering and formatting of the unchanged lines are
+// kept. The decode function is used to check that the edited document
+// describes the same config as the updated content. The updated content is
+// returned unchanged when the file does not exist. When the file cannot be
+// read or parsed, or when the changes cannot be applied as targeted edits, a
+// warning is written to output and the updated content is returned unchanged.
+func PreserveTOMLLayout(path string, updated []byte, output io.Writer, decode func([]byte) (interface{}, error)) []byte {
+	edited, err := preserveTOMLLayout(path, updated, decode)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(output, "Warning: failed to preserve the comments and layout of %s, rewriting the whole file: %s\n", path, err)
+		}
+		return updated
+	}
+
+	return edited
+}
+
+func preserveTOMLLayout(path string, updated []byte, decode func([]byte) (interface{}, error)) ([]byte, error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := editTOML(original, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	want, err := decode(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	got, err := decode(edited)
+	if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		return nil, errNotEquivalent
+	}
+
+	return edited, nil
+}
+
+// errNotEditable is returned when a change cannot be applied to the original
+// document as a targeted edit.
+var errNotEditable = errors.New("change cannot be applied as a targeted edit")
+
+// errNotEquivalent is returned when the edited document does not describe the
+// same config as the updated content.
+var errNotEquivalent = errors.New("edited file does not match the updated config")
+
+// tomlKeyAliases are keys that may be used in place of another key, for
+// example buildpack images that are referred to as `image` rather than `uri`.
+var tomlKeyAliases = map[string]string{"uri": "image"}
+
+type tomlDocument struct {
+	content []byte
+	lines   []int
+	tree    *toml.Tree
+}
+
+type tomlEdit struct {
+	start, end int
+	text       string
+}
+
+type tomlEditor struct {
+	original tomlDocument
+	updated  tomlDocument
+	edits    []tomlEdit
+	appended []string
+}
+
+// editTOML applies the differences between the original and updated TOML
+// documents to the original document. Tables and arrays of tables are
+// compared key by key; a value is only rewritten when it differs, and keys
+// that are missing from the updated document are removed. When the length of
+// an array of tables changes, only the elements that were added, removed or
+// changed are rewritten, see diffArrayOfTables.
+func editTOML(original, updated []byte) ([]byte, error) {
+	originalDocument, err := parseTOMLDocument(original)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedDocument, err := parseTOMLDocument(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	editor := tomlEditor{original: originalDocument, updated: updatedDocument}
+	err = editor.diffTable(nil, originalDocument.tree, updatedDocument.tree, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply the edits from the end of the document so that the offsets of the
+	// remaining edits are unchanged, keeping insertions at the same offset in
+	// order
+	slices.Reverse(editor.edits)
+	sort.SliceStable(editor.edits, func(i, j int) bool {
+		return editor.edits[i].start > editor.edits[j].start
+	})
+
+	content := slices.Clone(original)
+	for i, edit := range editor.edits {
+		if i > 0 && edit.end > editor.edits[i-1].start {
+			return nil, errNotEditable
+		}
+		content = slices.Concat(content[:edit.start], []byte(edit.text), content[edit.end:])
+	}
+
+	for _, region := range editor.appended {
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			content = append(content, '\n')
+		}
+		content = append(content, '\n')
+		content = append(content, region...)
+	}
+
+	return content, nil
+}
+
+func parseTOMLDocument(content []byte) (tomlDocument, error) {
+	tree, err := toml.LoadBytes(content)
+	if err != nil {
+		return tomlDocument{}, err
+	}
+
+	lines := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return tomlDocument{content: content, lines: lines, tree: tree}, nil
+}
+
+func (e *tomlEditor) diffTable(path []string, original, updated *toml.Tree, inArray bool) error {
+	keys := updated.Keys()
+	sort.Slice(keys, func(i, j int) bool {
+		return positionBefore(updated.GetPositionPath([]string{keys[i]}), updated.GetPositionPath([]string{keys[j]}))
+	})
+
+	for _, key := range keys {
+		updatedValue := updated.GetPath([]string{key})
+
+		originalKey := key
+		if !original.HasPath([]string{key}) {
+			if alias, ok := tomlKeyAliases[key]; ok && original.HasPath([]string{alias}) {
+				originalKey = alias
+			}
+		}
+
+		if !original.HasPath([]string{originalKey}) {
+			err := e.insert(path, original, updated, key, inArray)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		originalValue := original.GetPath([]string{originalKey})
+		keyPath := append(slices.Clone(path), key)
+
+		switch value := updatedValue.(type) {
+		case *toml.Tree:
+			originalTable, ok := originalValue.(*toml.Tree)
+			if ok && e.original.isTable(originalTable, keyPath) && e.updated.isTable(value, keyPath) {
+				err := e.diffTable(keyPath, originalTable, value, inArray)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+		case []*toml.Tree:
+			originalTables, ok := originalValue.([]*toml.Tree)
+			if ok && e.original.isArrayOfTables(originalTables, keyPath) && e.updated.isArrayOfTables(value, keyPath) {
+				err := e.diffArrayOfTables(keyPath, originalTables, value)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if equivalentTOMLValues(originalValue, updatedValue) {
+			continue
+		}
+
+		err := e.replaceValue(original, originalKey, updated, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range original.Keys() {
+		if updated.HasPath([]string{key}) {
+			continue
+		}
+
+		aliased := false
+		for updatedKey, alias := range tomlKeyAliases {
+			if alias == key && updated.HasPath([]string{updatedKey}) && !original.HasPath([]string{updatedKey}) {
+				aliased = true
+			}
+		}
+		if aliased {
+			continue
+		}
+
+		err := e.remove(path, original, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove deletes a key that only exists in the original document, along with
+// its line. Tables are not removed as they may be followed by other tables.
+func (e *tomlEditor) remove(path []string, original *toml.Tree, key string) error {
+	keyPath := append(slices.Clone(path), key)
+	switch value := original.GetPath([]string{key}).(type) {
+	case *toml.Tree:
+		if e.original.isTable(value, keyPath) {
+			return errNotEditable
+		}
+	case []*toml.Tree:
+		if e.original.isArrayOfTables(value, keyPath) {
+			return errNotEditable
+		}
+	}
+
+	keyStart, _, valueEnd, err := e.original.keyValue(original, key)
+	if err != nil {
+		return err
+	}
+
+	// the key must be alone on its line, optionally followed by a comment
+	start := e.original.lines[original.GetPositionPath([]string{key}).Line-1]
+	end := e.original.lineEnd(valueEnd)
+	rest := strings.TrimSpace(string(e.original.content[valueEnd:end]))
+	if strings.TrimSpace(string(e.original.content[start:keyStart])) != "" || (rest != "" && !strings.HasPrefix(rest, "#")) {
+		return errNotEditable
+	}
+
+	e.edits = append(e.edits, tomlEdit{start: start, end: end})
+
+	return nil
+}
+
+// insert adds a key that only exists in the updated document. Zero values are
+// skipped as they decode the same as a missing key.
+func (e *tomlEditor) insert(path []string, original, updated *toml.Tree, key string, inArray bool) error {
+	value := updated.GetPath([]string{key})
+	if isZeroTOMLValue(value) {
+		return nil
+	}
+
+	keyPath := append(slices.Clone(path), key)
+
+	var region string
+	switch v := value.(type) {
+	case *toml.Tree:
+		if e.updated.isTable(v, keyPath) {
+			region = e.updated.region(v.Position(), keyPath)
+		}
+	case []*toml.Tree:
+		if e.updated.isArrayOfTables(v, keyPath) {
+			start := e.updated.offset(v[0].Position())
+			end := e.updated.arrayEnd(v, keyPath)
+			region = string(e.updated.content[start:end])
+		}
+	}
+
+	if region != "" {
+		// tables can only be appended to the end of the document when they are
+		// not nested in an array of tables
+		if inArray {
+			return errNotEditable
+		}
+		e.appended = append(e.appended, region)
+		return nil
+	}
+
+	keyStart, _, valueEnd, err := e.updated.keyValue(updated, key)
+	if err != nil {
+		return err
+	}
+	text := string(e.updated.content[keyStart:valueEnd])
+
+	offset, indent, err := e.original.insertionPoint(original, path)
+	if err != nil {
+		return err
+	}
+
+	e.edits = append(e.edits, tomlEdit{start: offset, end: offset, text: fmt.Sprintf("%s%s\n", indent, text)})
+
+	return nil
+}
+
+func (e *tomlEditor) replaceValue(original *toml.Tree, originalKey string, updated *toml.Tree, key string) error {
+	_, start, end, err := e.original.keyValue(original, originalKey)
+	if err != nil {
+		return err
+	}
+
+	_, updatedStart, updatedEnd, err := e.updated.keyValue(updated, key)
+	if err != nil {
+		return err
+	}
+	text := string(e.updated.content[updatedStart:updatedEnd])
+
+	// keep the original style of image references that omit the docker scheme
+	originalValue, originalIsString := original.GetPath([]string{originalKey}).(string)
+	updatedValue, updatedIsString := updated.GetPath([]string{key}).(string)
+	if originalIsString && updatedIsString && !strings.HasPrefix(originalValue, "docker://") && strings.HasPrefix(updatedValue, "docker://") {
+		trimmed := strings.TrimPrefix(updatedValue, "docker://")
+		if strings.IndexFunc(trimmed, func(r rune) bool { return r > unicode.MaxASCII || !unicode.IsPrint(r) }) == -1 {
+			text = strconv.Quote(trimmed)
+		}
+	}
+
+	e.edits = append(e.edits, tomlEdit{start: start, end: end, text: text})
+
+	return nil
+}
+
+// diffArrayOfTables applies the differences between the elements of two
+// arrays of tables. Elements that are equal in both arrays are kept as they
+// are, including their comments. The elements in between are diffed key by key
+// when there are as many on both sides, and are otherwise added, removed or
+// replaced as a whole.
+func (e *tomlEditor) diffArrayOfTables(path []string, original, updated []*toml.Tree) error {
+	i, j := 0, 0
+	for _, match := range matchTOMLTables(original, updated) {
+		err := e.diffTableRun(path, original, updated, i, match[0], j, match[1])
+		if err != nil {
+			return err
+		}
+		i, j = match[0]+1, match[1]+1
+	}
+
+	return e.diffTableRun(path, original, updated, i, len(original), j, len(updated))
+}
+
+// diffTableRun applies the differences between the original elements
+// [i0, i1) and the updated elements [j0, j1) of an array of tables. The
+// elements before and after the run are equal in both arrays.
+func (e *tomlEditor) diffTableRun(path []string, original, updated []*toml.Tree, i0, i1, j0, j1 int) error {
+	if i1-i0 == j1-j0 {
+		for n := range i1 - i0 {
+			err := e.diffTable(path, original[i0+n], updated[j0+n], true)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var start, end, updatedStart, updatedEnd int
+	switch {
+	case j0 == j1 && i0 > 0:
+		// removed elements take the separator before them along
+		start, end = e.original.elementEnd(original, i0-1, path), e.original.elementEnd(original, i1-1, path)
+	case j0 == j1:
+		start, end = e.original.offset(original[i0].Position()), e.original.offset(original[i1].Position())
+	case i0 == i1 && i0 > 0:
+		// added elements bring the separator of the updated document along
+		start, end = e.original.elementEnd(original, i0-1, path), e.original.elementEnd(original, i0-1, path)
+		updatedStart, updatedEnd = e.updated.elementEnd(updated, j0-1, path), e.updated.elementEnd(updated, j1-1, path)
+	case i0 == i1:
+		start, end = e.original.offset(original[i0].Position()), e.original.offset(original[i0].Position())
+		updatedStart, updatedEnd = e.updated.offset(updated[j0].Position()), e.updated.offset(updated[j1].Position())
+	default:
+		start, end = e.original.offset(original[i0].Position()), e.original.elementEnd(original, i1-1, path)
+		updatedStart, updatedEnd = e.updated.offset(updated[j0].Position()), e.updated.elementEnd(updated, j1-1, path)
+	}
+
+	// the elements must be contiguous so that no other tables are replaced
+	if !e.original.onlyTables(start, end, path) || !e.updated.onlyTables(updatedStart, updatedEnd, path) {
+		return errNotEditable
+	}
+
+	e.edits = append(e.edits, tomlEdit{start: start, end: end, text: string(e.updated.content[updatedStart:updatedEnd])})
+
+	return nil
+}
+
+// matchTOMLTables returns the pairs of indices of the longest common
+// subsequence of equal elements of the two arrays of tables.
+func matchTOMLTables(original, updated []*toml.Tree) [][2]int {
+	originalMaps := make([]map[string]interface{}, len(original))
+	for i, tree := range original {
+		originalMaps[i] = tree.ToMap()
+	}
+
+	updatedMaps := make([]map[string]interface{}, len(updated))
+	for j, tree := range updated {
+		updatedMaps[j] = tree.ToMap()
+	}
+
+	lengths := make([][]int, len(original)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(updated)+1)
+	}
+
+	for i := len(original) - 1; i >= 0; i-- {
+		for j := len(updated) - 1; j >= 0; j-- {
+			if equivalentTOMLValues(originalMaps[i], updatedMaps[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var matches [][2]int
+	i, j := 0, 0
+	for i < len(original) && j < len(updated) {
+		switch {
+		case equivalentTOMLValues(originalMaps[i], updatedMaps[j]) && lengths[i][j] == lengths[i+1][j+1]+1:
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return matches
+}
+
+func (d tomlDocument) offset(position toml.Position) int {
+	return d.lines[position.Line-1] + position.Col - 1
+}
+
+func (d tomlDocument) line(n int) string {
+	start := d.lines[n-1]
+	end := len(d.content)
+	if n < len(d.lines) {
+		end = d.lines[n] - 1
+	}
+
+	return string(d.content[start:end])
+}
+
+// header returns the key of the table header on the given line, or nil if the
+// line is not a table header.
+func (d tomlDocument) header(n int) []string {
+	line := strings.TrimSpace(d.line(n))
+	if !strings.HasPrefix(line, "[") {
+		return nil
+	}
+
+	tree, err := toml.Load(fmt.Sprintf("%s\n", line))
+	if err != nil {
+		return nil
+	}
+
+	var key []string
+	for {
+		keys := tree.Keys()
+		if len(keys) != 1 {
+			return key
+		}
+		key = append(key, keys[0])
+
+		switch value := tree.GetPath([]string{keys[0]}).(type) {
+		case *toml.Tree:
+			tree = value
+		case []*toml.Tree:
+			if len(value) != 1 {
+				return key
+			}
+			tree = value[0]
+		default:
+			return nil
+		}
+	}
+}
+
+// isTable returns true if the table is declared with its own header.
+func (d tomlDocument) isTable(tree *toml.Tree, path []string) bool {
+	position := tree.Position()
+	return !position.Invalid() && slices.Equal(d.header(position.Line), path)
+}
+
+func (d tomlDocument) isArrayOfTables(trees []*toml.Tree, path []string) bool {
+	if len(trees) == 0 {
+		return false
+	}
+
+	for _, tree := range trees {
+		if !d.isTable(tree, path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// headers returns the keys of the table headers between the given offsets.
+func (d tomlDocument) headers(start, end int) [][]string {
+	var headers [][]string
+	for n := 1; n <= len(d.lines); n++ {
+		if d.lines[n-1] < start || d.lines[n-1] >= end {
+			continue
+		}
+
+		if header := d.header(n); header != nil {
+			headers = append(headers, header)
+		}
+	}
+
+	return headers
+}
+
+// region returns the text of the table declared at the given position,
+// including its sub-tables, up to the next table that is not nested in it.
+func (d tomlDocument) region(position toml.Position, path []string) string {
+	start := d.offset(position)
+	return string(d.content[start:d.tableEnd(position.Line, path)])
+}
+
+func (d tomlDocument) arrayEnd(trees []*toml.Tree, path []string) int {
+	return d.elementEnd(trees, len(trees)-1, path)
+}
+
+// elementEnd returns the offset of the end of the given element of an array
+// of tables, including its sub-tables.
+func (d tomlDocument) elementEnd(trees []*toml.Tree, i int, path []string) int {
+	return d.tableEnd(trees[i].Position().Line, path)
+}
+
+// onlyTables returns true if every table header between the given offsets
+// declares the table at path or one of its sub-tables.
+func (d tomlDocument) onlyTables(start, end int, path []string) bool {
+	for _, header := range d.headers(start, end) {
+		if !slices.Equal(header, path) && !hasTOMLPrefix(header, path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tableEnd returns the offset of the end of the table whose header is on the
+// given line. Trailing blank and comment lines are left to the next table.
+func (d tomlDocument) tableEnd(line int, path []string) int {
+	end := len(d.lines) + 1
+	for n := line + 1; n <= len(d.lines); n++ {
+		header := d.header(n)
+		if header == nil {
+			continue
+		}
+
+		if hasTOMLPrefix(header, path) {
+			continue
+		}
+
+		end = n
+		break
+	}
+
+	for end-1 > line {
+		trimmed := strings.TrimSpace(d.line(end - 1))
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		end--
+	}
+
+	if end > len(d.lines) {
+		return len(d.content)
+	}
+
+	return d.lines[end-1]
+}
+
+// keyValue returns the offsets of the start of the key and the start and end
+// of the value of the given key in the table.
+func (d tomlDocument) keyValue(tree *toml.Tree, key string) (int, int, int, error) {
+	position := tree.GetPositionPath([]string{key})
+	if position.Invalid() {
+		return 0, 0, 0, errNotEditable
+	}
+
+	keyStart := d.offset(position)
+
+	i := keyStart
+	for i < len(d.content) && d.content[i] != '=' {
+		switch d.content[i] {
+		case '"', '\'':
+			i = scanTOMLString(d.content, i)
+		case '\n':
+			return 0, 0, 0, errNotEditable
+		default:
+			i++
+		}
+	}
+
+	i++
+	for i < len(d.content) && (d.content[i] == ' ' || d.content[i] == '\t') {
+		i++
+	}
+
+	if i >= len(d.content) {
+		return 0, 0, 0, errNotEditable
+	}
+
+	return keyStart, i, scanTOMLValue(d.content, i), nil
+}
+
+// insertionPoint returns the offset at which a new key can be added to the
+// table, after its last key, along with the indentation of that key.
+func (d tomlDocument) insertionPoint(tree *toml.Tree, path []string) (int, string, error) {
+	var last toml.Position
+	for _, key := range tree.Keys() {
+		switch tree.GetPath([]string{key}).(type) {
+		case *toml.Tree, []*toml.Tree:
+			continue
+		}
+
+		position := tree.GetPositionPath([]string{key})
+		if position.Invalid() {
+			return 0, "", errNotEditable
+		}
+
+		if positionBefore(last, position) {
+			last = position
+		}
+	}
+
+	if last.Invalid() {
+		if len(path) == 0 {
+			return 0, "", nil
+		}
+
+		position := tree.Position()
+		if !d.isTable(tree, path) {
+			return 0, "", errNotEditable
+		}
+
+		return d.lineEnd(d.offset(position)), "", nil
+	}
+
+	lastKey := ""
+	for _, key := range tree.Keys() {
+		if tree.GetPositionPath([]string{key}) == last {
+			lastKey = key
+		}
+	}
+
+	_, _, valueEnd, err := d.keyValue(tree, lastKey)
+	if err != nil {
+		return 0, "", err
+	}
+
+	line := d.line(last.Line)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+	return d.lineEnd(valueEnd), indent, nil
+}
+
+// lineEnd returns the offset of the start of the line after the given offset.
+func (d tomlDocument) lineEnd(offset int) int {
+	i := bytes.IndexByte(d.content[offset:], '\n')
+	if i == -1 {
+		return len(d.content)
+	}
+
+	return offset + i + 1
+}
+
+// scanTOMLValue returns the offset of the end of the value that starts at the
+// given offset.
+func scanTOMLValue(content []byte, start int) int {
+	switch content[start] {
+	case '"', '\'':
+		return scanTOMLString(content, start)
+	case '[', '{':
+		depth := 0
+		i := start
+		for i < len(content) {
+			switch content[i] {
+			case '"', '\'':
+				i = scanTOMLString(content, i)
+				continue
+			case '#':
+				for i < len(content) && content[i] != '\n' {
+					i++
+				}
+				continue
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			i++
+		}
+		return i
+	default:
+		i := start
+		for i < len(content) && !strings.ContainsRune(" \t\r\n,#]}", rune(content[i])) {
+			i++
+		}
+		return i
+	}
+}
+
+// scanTOMLString returns the offset of the end of the string that starts at
+// the given offset.
+func scanTOMLString(content []byte, start int) int {
+	quote := content[start]
+	delimiter := []byte{quote}
+	if bytes.HasPrefix(content[start:], []byte{quote, quote, quote}) {
+		delimiter = []byte{quote, quote, quote}
+	}
+
+	i := start + len(delimiter)
+	for i < len(content) {
+		if quote == '"' && content[i] == '\\' {
+			i += 2
+			continue
+		}
+
+		if bytes.HasPrefix(content[i:], delimiter) {
+			end := i + len(delimiter)
+			// multi-line strings may end with up to two additional quotes
+			for len(delimiter) == 3 && end < len(content) && content[end] == quote && end-i < 5 {
+				end++
+			}
+			return end
+		}
+		i++
+	}
+
+	return i
+}
+
+func positionBefore(a, b toml.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+}
+
+func hasTOMLPrefix(key, prefix []string) bool {
+	return len(key) > len(prefix) && slices.Equal(key[:len(prefix)], prefix)
+}
+
+// equivalentTOMLValues compares two decoded values. Image references that only
+// differ by the docker scheme are considered equivalent.
+func equivalentTOMLValues(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *toml.Tree:
+		b, ok := b.(*toml.Tree)
+		return ok && equivalentTOMLValues(a.ToMap(), b.ToMap())
+	case []*toml.Tree:
+		b, ok := b.([]*toml.Tree)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equivalentTOMLValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			if !equivalentTOMLValues(value, b[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equivalentTOMLValues(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		b, ok := b.(string)
+		return ok && strings.TrimPrefix(a, "docker://") == strings.TrimPrefix(b, "docker://")
+	case time.Time:
+		b, ok := b.(time.Time)
+		return ok && a.Equal(b)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func isZeroTOMLValue(value interface{}) bool {
+	switch v := value.(type) {
+	case *toml.Tree:
+		for _, key := range v.Keys() {
+			if !isZeroTOMLValue(v.GetPath([]string{key})) {
+				return false
+			}
+		}
+		return true
+	case []*toml.Tree:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	default:
+		return false
+	}
+}
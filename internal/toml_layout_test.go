@@ -0,0 +1,286 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/pelletier/go-toml"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTOMLLayout(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path   string
+		decode func([]byte) (interface{}, error)
+	)
+
+	it.Before(func() {
+		tmpDir := t.TempDir()
+		path = filepath.Join(tmpDir, "config.toml")
+
+		decode = func(content []byte) (interface{}, error) {
+			var config internal.BuilderConfig
+			err := toml.Unmarshal(content, &config)
+			return config, err
+		}
+	})
+
+	context("PreserveTOMLLayout", func() {
+		it("only rewrites the values that changed", func() {
+			Expect(os.WriteFile(path, []byte(`# builder for some stack
+description = "Some description"
+
+[[buildpacks]]
+  # pinned by the release pipeline
+  version = "0.1.0"
+  uri = "docker://some-registry/some-repository/some-buildpack:0.1.0"
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/other-buildpack:0.2.0"
+  version = "0.2.0"
+
+[lifecycle]
+  version = "0.10.2" # keep in sync with the stack
+`), 0600)).To(Succeed())
+
+			updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{
+				Description: "Some description",
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{URI: "docker://some-registry/some-repository/some-buildpack:0.2.0", Version: "0.2.0"},
+					{URI: "docker://some-registry/some-repository/other-buildpack:0.2.0", Version: "0.2.0"},
+				},
+				Lifecycle: internal.BuilderConfigLifecycle{Version: "0.11.0"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(internal.PreserveTOMLLayout(path, updated, io.Discard, decode))).To(Equal(`# builder for some stack
+description = "Some description"
+
+[[buildpacks]]
+  # pinned by the release pipeline
+  version = "0.2.0"
+  uri = "docker://some-registry/some-repository/some-buildpack:0.2.0"
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/other-buildpack:0.2.0"
+  version = "0.2.0"
+
+[lifecycle]
+  version = "0.11.0" # keep in sync with the stack
+`))
+		})
+
+		it("keeps the image key and references without a docker prefix", func() {
+			Expect(os.WriteFile(path, []byte(`[[buildpacks]]
+image = "some-registry/some-repository/some-buildpack:0.1.0"
+version = "0.1.0"
+`), 0600)).To(Succeed())
+
+			updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{URI: "docker://some-registry/some-repository/some-buildpack:0.2.0", Version: "0.2.0"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(internal.PreserveTOMLLayout(path, updated, io.Discard, decode))).To(Equal(`[[buildpacks]]
+image = "some-registry/some-repository/some-buildpack:0.2.0"
+version = "0.2.0"
+`))
+		})
+
+		it("inserts keys that are missing from the original", func() {
+			Expect(os.WriteFile(path, []byte(`[[buildpacks]]
+  # some comment
+  uri = "docker://some-registry/some-repository/some-buildpack:0.1.0"
+`), 0600)).To(Succeed())
+
+			updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{URI: "docker://some-registry/some-repository/some-buildpack:0.1.0", Version: "0.1.0"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(internal.PreserveTOMLLayout(path, updated, io.Discard, decode))).To(Equal(`[[buildpacks]]
+  # some comment
+  uri = "docker://some-registry/some-repository/some-buildpack:0.1.0"
+  version = "0.1.0"
+`))
+		})
+
+		it("removes keys that are missing from the updated content", func() {
+			Expect(os.WriteFile(path, []byte(`[[buildpacks]]
+  # some comment
+  uri = "docker://some-registry/some-repository/some-buildpack:0.1.0"
+  jam-constraint = "0.*" # some other comment
+  version = "0.1.0"
+`), 0600)).To(Succeed())
+
+			updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{URI: "docker://some-registry/some-repository/some-buildpack:0.2.0", Version: "0.2.0"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(internal.PreserveTOMLLayout(path, updated, io.Discard, decode))).To(Equal(`[[buildpacks]]
+  # some comment
+  uri = "docker://some-registry/some-repository/some-buildpack:0.2.0"
+  version = "0.2.0"
+`))
+		})
+
+		it("rewrites an array of tables when its length changes", func() {
+			Expect(os.WriteFile(path, []byte(`# some comment
+description = "Some description"
+
+[[buildpacks]]
+  uri = "docker://some-registry/some-repository/some-buildpack:0.1.0"
+
+[lifecycle]
+  version = "0.10.2"
+`), 0600)).To(Succeed())
+
+			updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{
+				Description: "Some description",
+				Buildpacks: []internal.BuilderConfigBuildpack{
+					{URI: "docker://some-registry/some-repository/some-buildpack:0.1.0"},
+					{URI: "docker://some-registry/some-repository/other-buildpack:0.2.0"},
+				},
+				Lifecycle: internal.BuilderConfigLifecycle{Version: "0.10.2"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			content := string(internal.PreserveTOMLLayout(path, updated, io.Discard, decode))
+			Expect(content).To(HavePrefix("# some comment\n"))
+
+			config, err := decode([]byte(content))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.(internal.BuilderConfig).Buildpacks).To(Equal([]internal.BuilderConfigBuildpack{
+				{URI: "some-registry/some-repository/some-buildpack:0.1.0"},
+				{URI: "some-registry/some-repository/other-buildpack:0.2.0"},
+			}))
+			Expect(config.(internal.BuilderConfig).Lifecycle.Version).To(Equal("0.10.2"))
+		})
+
+		it("keeps the comments of the elements that are kept when the length of an array of tables changes", func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+
+[buildpack]
+  id = "some-buildpack"
+
+[metadata]
+
+  # oldest supported line
+  [[metadata.dependencies]]
+    id = "node"
+    # kept until the line is deprecated
+    uri = "https://example.com/node-18.18.0.tgz"
+    version = "18.18.0"
+
+  [[metadata.dependencies]]
+    id = "node"
+    uri = "https://example.com/node-20.9.0.tgz"
+    version = "20.9.0"
+
+  # latest line
+  [[metadata.dependencies]]
+    id = "node"
+    uri = "https://example.com/node-20.10.0.tgz" # mirrored
+    version = "20.10.0"
+`), 0600)).To(Succeed())
+
+			buffer := bytes.NewBuffer(nil)
+			Expect(cargo.EncodeConfig(buffer, cargo.Config{
+				API:       "0.7",
+				Buildpack: cargo.ConfigBuildpack{ID: "some-buildpack"},
+				Metadata: cargo.ConfigMetadata{
+					Dependencies: []cargo.ConfigMetadataDependency{
+						{ID: "node", URI: "https://example.com/node-18.18.0.tgz", Version: "18.18.0"},
+						{ID: "node", URI: "https://example.com/node-20.10.0.tgz", Version: "20.10.0"},
+						{ID: "node", URI: "https://example.com/node-20.11.0.tgz", Version: "20.11.0"},
+					},
+				},
+			})).To(Succeed())
+
+			content := internal.PreserveTOMLLayout(path, buffer.Bytes(), io.Discard, func(content []byte) (interface{}, error) {
+				var config cargo.Config
+				err := cargo.DecodeConfig(bytes.NewReader(content), &config)
+				return config, err
+			})
+			Expect(string(content)).To(Equal(`api = "0.7"
+
+[buildpack]
+  id = "some-buildpack"
+
+[metadata]
+
+  # oldest supported line
+  [[metadata.dependencies]]
+    id = "node"
+    # kept until the line is deprecated
+    uri = "https://example.com/node-18.18.0.tgz"
+    version = "18.18.0"
+
+  # latest line
+  [[metadata.dependencies]]
+    id = "node"
+    uri = "https://example.com/node-20.10.0.tgz" # mirrored
+    version = "20.10.0"
+
+  [[metadata.dependencies]]
+    id = "node"
+    uri = "https://example.com/node-20.11.0.tgz"
+    version = "20.11.0"
+`))
+		})
+
+		context("when the changes cannot be applied as targeted edits", func() {
+			it("returns the updated content and warns that the layout is not preserved", func() {
+				Expect(os.WriteFile(path, []byte(`description = "Some description"
+
+[stack]
+  id = "some-stack"
+`), 0600)).To(Succeed())
+
+				updated, err := internal.MarshalBuilderConfig(internal.BuilderConfig{Description: "Some description"})
+				Expect(err).NotTo(HaveOccurred())
+
+				output := bytes.NewBuffer(nil)
+				Expect(internal.PreserveTOMLLayout(path, updated, output, decode)).To(Equal(updated))
+				Expect(output.String()).To(Equal(fmt.Sprintf("Warning: failed to preserve the comments and layout of %s, rewriting the whole file: change cannot be applied as a targeted edit\n", path)))
+			})
+		})
+
+		context("when the original file cannot be parsed", func() {
+			it("returns the updated content and warns that the layout is not preserved", func() {
+				Expect(os.WriteFile(path, []byte("%%%"), 0600)).To(Succeed())
+
+				updated := []byte("description = \"Some description\"\n")
+				output := bytes.NewBuffer(nil)
+				Expect(internal.PreserveTOMLLayout(path, updated, output, decode)).To(Equal(updated))
+				Expect(output.String()).To(ContainSubstring(fmt.Sprintf("Warning: failed to preserve the comments and layout of %s, rewriting the whole file:", path)))
+			})
+		})
+
+		context("when the original file does not exist", func() {
+			it("returns the updated content", func() {
+				updated := []byte("description = \"Some description\"\n")
+				output := bytes.NewBuffer(nil)
+				Expect(internal.PreserveTOMLLayout(filepath.Join(path, "missing"), updated, output, decode)).To(Equal(updated))
+				Expect(output.String()).To(BeEmpty())
+			})
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// unrecognizedTOML returns the tables and keys of the given tree that are not
+// modeled by the fields of the given struct type, keeping their paths so that
+// they can be merged back with mergeTOML. Elements of arrays of tables that
+// have no unrecognized keys are kept as empty tables so that the position of
+// the remaining elements is unchanged. It returns nil when every key is
+// recognized.
+func unrecognizedTOML(tree *toml.Tree, t reflect.Type) *toml.Tree {
+	var unrecognized *toml.Tree
+	set := func(key string, value interface{}) {
+		if unrecognized == nil {
+			unrecognized = newTOMLTree()
+		}
+		unrecognized.SetPath([]string{key}, value)
+	}
+
+	for _, key := range tree.Keys() {
+		value := tree.GetPath([]string{key})
+
+		field, ok := tomlField(t, key)
+		if !ok {
+			set(key, value)
+			continue
+		}
+
+		switch v := value.(type) {
+		case *toml.Tree:
+			if field.Kind() == reflect.Struct {
+				if table := unrecognizedTOML(v, field); table != nil {
+					set(key, table)
+				}
+			}
+
+		case []*toml.Tree:
+			if field.Kind() == reflect.Slice && field.Elem().Kind() == reflect.Struct {
+				var found bool
+				tables := make([]*toml.Tree, len(v))
+				for i, element := range v {
+					tables[i] = unrecognizedTOML(element, field.Elem())
+					if tables[i] != nil {
+						found = true
+						continue
+					}
+					tables[i] = newTOMLTree()
+				}
+
+				if found {
+					set(key, tables)
+				}
+			}
+		}
+	}
+
+	return unrecognized
+}
+
+// mergeTOML adds the keys of src that are missing from dst. Arrays of tables
+// are merged element by element, unless their lengths differ in which case
+// the elements of src cannot be matched and are dropped.
+func mergeTOML(dst, src *toml.Tree) {
+	for _, key := range src.Keys() {
+		value := src.GetPath([]string{key})
+
+		if !dst.HasPath([]string{key}) {
+			dst.SetPath([]string{key}, value)
+			continue
+		}
+
+		switch v := value.(type) {
+		case *toml.Tree:
+			if table, ok := dst.GetPath([]string{key}).(*toml.Tree); ok {
+				mergeTOML(table, v)
+			}
+
+		case []*toml.Tree:
+			if tables, ok := dst.GetPath([]string{key}).([]*toml.Tree); ok && len(tables) == len(v) {
+				for i := range v {
+					mergeTOML(tables[i], v[i])
+				}
+			}
+		}
+	}
+}
+
+// tomlField returns the type of the field of the given struct type that is
+// decoded from key, following pointers.
+func tomlField(t reflect.Type, key string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "-" {
+			continue
+		}
+
+		match := name == key
+		if name == "" {
+			match = strings.EqualFold(field.Name, key)
+		}
+		if alias, ok := tomlKeyAliases[name]; ok && alias == key {
+			match = true
+		}
+
+		if match {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			return fieldType, true
+		}
+	}
+
+	return nil, false
+}
+
+func newTOMLTree() *toml.Tree {
+	tree, _ := toml.TreeFromMap(map[string]interface{}{})
+	return tree
+}
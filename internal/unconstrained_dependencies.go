@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+)
+
+const (
+	UnconstrainedKeep  = "keep"
+	UnconstrainedDrop  = "drop"
+	UnconstrainedError = "error"
+)
+
+// ValidateUnconstrainedPolicy returns an error if the given policy for the
+// dependencies not covered by any dependency-constraint is not one of keep,
+// drop or error.
+func ValidateUnconstrainedPolicy(policy string) error {
+	if !slices.Contains([]string{UnconstrainedKeep, UnconstrainedDrop, UnconstrainedError}, policy) {
+		return fmt.Errorf("invalid unconstrained policy %q: must be one of %s, %s or %s", policy, UnconstrainedKeep, UnconstrainedDrop, UnconstrainedError)
+	}
+
+	return nil
+}
+
+// SplitConstrainedDependencies splits the dependencies into those whose ID has
+// at least one dependency-constraint and those whose ID has none, keeping
+// their order.
+func SplitConstrainedDependencies(dependencies []cargo.ConfigMetadataDependency, constraints []cargo.ConfigMetadataDependencyConstraint) ([]cargo.ConfigMetadataDependency, []cargo.ConfigMetadataDependency) {
+	var constrained, unconstrained []cargo.ConfigMetadataDependency
+	for _, dependency := range dependencies {
+		if slices.ContainsFunc(constraints, func(c cargo.ConfigMetadataDependencyConstraint) bool { return c.ID == dependency.ID }) {
+			constrained = append(constrained, dependency)
+		} else {
+			unconstrained = append(unconstrained, dependency)
+		}
+	}
+
+	return constrained, unconstrained
+}
+
+// DependenciesWithinConstraints returns the dependencies whose version is
+// within a dependency-constraint of their ID, keeping their order.
+// Dependencies whose version is not a semantic version are kept when their ID
+// has a constraint, as they cannot be checked against it, and so are
+// dependencies whose constraint cannot be parsed. Dependencies without an ID
+// cannot be matched against any constraint, so they are kept to be reported.
+func DependenciesWithinConstraints(dependencies []cargo.ConfigMetadataDependency, constraints []cargo.ConfigMetadataDependencyConstraint) []cargo.ConfigMetadataDependency {
+	var within []cargo.ConfigMetadataDependency
+	for _, dependency := range dependencies {
+		if dependency.ID == "" {
+			within = append(within, dependency)
+			continue
+		}
+
+		version, versionErr := semver.NewVersion(dependency.Version)
+		for _, c := range constraints {
+			if c.ID != dependency.ID {
+				continue
+			}
+
+			constraint, err := semver.NewConstraint(c.Constraint)
+			if versionErr != nil || err != nil || constraint.Check(version) {
+				within = append(within, dependency)
+				break
+			}
+		}
+	}
+
+	return within
+}
@@ -0,0 +1,81 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/paketo-buildpacks/packit/v2/cargo"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testUnconstrainedDependencies(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("ValidateUnconstrainedPolicy", func() {
+		it("accepts keep, drop and error", func() {
+			Expect(internal.ValidateUnconstrainedPolicy(internal.UnconstrainedKeep)).To(Succeed())
+			Expect(internal.ValidateUnconstrainedPolicy(internal.UnconstrainedDrop)).To(Succeed())
+			Expect(internal.ValidateUnconstrainedPolicy(internal.UnconstrainedError)).To(Succeed())
+		})
+
+		it("returns an error for any other policy", func() {
+			err := internal.ValidateUnconstrainedPolicy("ignore")
+			Expect(err).To(MatchError(`invalid unconstrained policy "ignore": must be one of keep, drop or error`))
+		})
+	})
+
+	context("SplitConstrainedDependencies", func() {
+		it("splits the dependencies by whether their ID has a constraint", func() {
+			constrained, unconstrained := internal.SplitConstrainedDependencies(
+				[]cargo.ConfigMetadataDependency{
+					{ID: "some-dependency", Version: "1.2.3"},
+					{ID: "other-dependency", Version: "2.0.0"},
+					{ID: "some-dependency", Version: "2.3.4"},
+					{ID: "another-dependency", Version: "3.0.0"},
+				},
+				[]cargo.ConfigMetadataDependencyConstraint{
+					{ID: "some-dependency", Constraint: "1.*", Patches: 1},
+					{ID: "some-dependency", Constraint: "2.*", Patches: 1},
+				},
+			)
+
+			Expect(constrained).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "some-dependency", Version: "1.2.3"},
+				{ID: "some-dependency", Version: "2.3.4"},
+			}))
+			Expect(unconstrained).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "other-dependency", Version: "2.0.0"},
+				{ID: "another-dependency", Version: "3.0.0"},
+			}))
+		})
+	})
+
+	context("DependenciesWithinConstraints", func() {
+		it("returns the dependencies within a constraint of their ID, and those without an ID", func() {
+			within := internal.DependenciesWithinConstraints(
+				[]cargo.ConfigMetadataDependency{
+					{ID: "some-dependency", Version: "1.2.3"},
+					{ID: "other-dependency", Version: "1.0.0"},
+					{ID: "some-dependency", Version: "3.0.0"},
+					{ID: "some-dependency", Version: "2.3.4"},
+					{ID: "some-dependency", Version: "latest"},
+					{ID: "other-dependency", Version: "latest"},
+					{Version: "1.0.0"},
+				},
+				[]cargo.ConfigMetadataDependencyConstraint{
+					{ID: "some-dependency", Constraint: "1.*", Patches: 1},
+					{ID: "some-dependency", Constraint: "2.*", Patches: 1},
+				},
+			)
+
+			Expect(within).To(Equal([]cargo.ConfigMetadataDependency{
+				{ID: "some-dependency", Version: "1.2.3"},
+				{ID: "some-dependency", Version: "2.3.4"},
+				{ID: "some-dependency", Version: "latest"},
+				{Version: "1.0.0"},
+			}))
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+	BumpNone  = "none"
+)
+
+// UpdatePolicy determines the largest semver bump that is allowed when
+// updating an image. Overrides, keyed by buildpack ID or image name, take
+// precedence over categories, which take precedence over the default.
+type UpdatePolicy struct {
+	Default    string
+	Categories map[string]string
+	Overrides  map[string]string
+}
+
+// ValidateBump returns an error if the given bump is not one of major, minor,
+// patch or none. An empty bump is valid and means no restriction.
+func ValidateBump(bump string) error {
+	if bump != "" && !slices.Contains([]string{BumpMajor, BumpMinor, BumpPatch, BumpNone}, bump) {
+		return fmt.Errorf("invalid bump %q: must be one of %s, %s, %s or %s", bump, BumpMajor, BumpMinor, BumpPatch, BumpNone)
+	}
+
+	return nil
+}
+
+// ParseBumpOverrides parses a list of key=bump pairs into a map.
+func ParseBumpOverrides(overrides []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, override := range overrides {
+		key, bump, found := strings.Cut(override, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid override %q: must be of the form <id-or-image>=<bump>", override)
+		}
+
+		err := ValidateBump(bump)
+		if err != nil {
+			return nil, fmt.Errorf("invalid override %q: %w", override, err)
+		}
+
+		parsed[key] = bump
+	}
+
+	return parsed, nil
+}
+
+// HasOverrides returns true if the policy contains any per-entry overrides.
+func (p UpdatePolicy) HasOverrides() bool {
+	return len(p.Overrides) > 0
+}
+
+// MaxBump returns the largest bump allowed for an entry in the given category
+// that is identified by any of the given keys.
+func (p UpdatePolicy) MaxBump(category string, keys ...string) string {
+	for _, key := range keys {
+		if bump, ok := p.Overrides[key]; ok && key != "" {
+			return bump
+		}
+	}
+
+	if bump, ok := p.Categories[category]; ok && bump != "" {
+		return bump
+	}
+
+	return p.Default
+}
+
+// BumpConstraint returns a constraint that limits versions to those reachable
+// from the given version with at most the given bump. A nil constraint is
+// returned when the bump is unrestricted or the version is not a semantic
+// version, as there is nothing to constrain against.
+func BumpConstraint(version, bump string) (*semver.Constraints, error) {
+	if bump == "" || bump == BumpMajor {
+		return nil, nil
+	}
+
+	current, err := semver.StrictNewVersion(version)
+	if err != nil {
+		return nil, nil
+	}
+
+	var constraint string
+	switch bump {
+	case BumpMinor:
+		constraint = fmt.Sprintf(">= %d.0.0, < %d.0.0", current.Major(), current.Major()+1)
+	case BumpPatch:
+		constraint = fmt.Sprintf(">= %d.%d.0, < %d.%d.0", current.Major(), current.Minor(), current.Major(), current.Minor()+1)
+	case BumpNone:
+		constraint = fmt.Sprintf("= %d.%d.%d", current.Major(), current.Minor(), current.Patch())
+	default:
+		return nil, ValidateBump(bump)
+	}
+
+	return semver.NewConstraint(constraint)
+}
@@ -0,0 +1,112 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testUpdatePolicy(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("MaxBump", func() {
+		var policy internal.UpdatePolicy
+
+		it.Before(func() {
+			policy = internal.UpdatePolicy{
+				Default: internal.BumpMinor,
+				Categories: map[string]string{
+					"lifecycle": internal.BumpPatch,
+					"images":    "",
+				},
+				Overrides: map[string]string{
+					"some-org/held-buildpack": internal.BumpNone,
+				},
+			}
+		})
+
+		it("prefers overrides, then categories, then the default", func() {
+			Expect(policy.MaxBump("buildpacks", "some-org/held-buildpack", "some-registry/some-org/held-buildpack")).To(Equal(internal.BumpNone))
+			Expect(policy.MaxBump("lifecycle", "some-registry/lifecycle")).To(Equal(internal.BumpPatch))
+			Expect(policy.MaxBump("images", "some-registry/run")).To(Equal(internal.BumpMinor))
+			Expect(policy.MaxBump("buildpacks", "", "some-org/other-buildpack")).To(Equal(internal.BumpMinor))
+		})
+	})
+
+	context("ParseBumpOverrides", func() {
+		it("parses key=bump pairs", func() {
+			overrides, err := internal.ParseBumpOverrides([]string{"some-org/some-buildpack=patch", "some-registry/run=none"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overrides).To(Equal(map[string]string{
+				"some-org/some-buildpack": "patch",
+				"some-registry/run":       "none",
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when an override is missing a bump", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseBumpOverrides([]string{"some-org/some-buildpack"})
+					Expect(err).To(MatchError(ContainSubstring("must be of the form <id-or-image>=<bump>")))
+				})
+			})
+
+			context("when an override has an invalid bump", func() {
+				it("returns an error", func() {
+					_, err := internal.ParseBumpOverrides([]string{"some-org/some-buildpack=huge"})
+					Expect(err).To(MatchError(ContainSubstring(`invalid bump "huge"`)))
+				})
+			})
+		})
+	})
+
+	context("BumpConstraint", func() {
+		check := func(constraint *semver.Constraints, version string) bool {
+			return constraint.Check(semver.MustParse(version))
+		}
+
+		it("limits versions to the same minor line for patch bumps", func() {
+			constraint, err := internal.BumpConstraint("1.2.3", internal.BumpPatch)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check(constraint, "1.2.9")).To(BeTrue())
+			Expect(check(constraint, "1.3.0")).To(BeFalse())
+		})
+
+		it("limits versions to the same major line for minor bumps", func() {
+			constraint, err := internal.BumpConstraint("0.2.3", internal.BumpMinor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check(constraint, "0.9.0")).To(BeTrue())
+			Expect(check(constraint, "1.0.0")).To(BeFalse())
+		})
+
+		it("limits versions to the current version when holding", func() {
+			constraint, err := internal.BumpConstraint("1.2.3-some-cnb", internal.BumpNone)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check(constraint, "1.2.3")).To(BeTrue())
+			Expect(check(constraint, "1.2.4")).To(BeFalse())
+		})
+
+		it("returns no constraint for major bumps or non-semver versions", func() {
+			constraint, err := internal.BumpConstraint("1.2.3", internal.BumpMajor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(constraint).To(BeNil())
+
+			constraint, err = internal.BumpConstraint("latest", internal.BumpPatch)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(constraint).To(BeNil())
+		})
+
+		context("failure cases", func() {
+			context("when the bump is invalid", func() {
+				it("returns an error", func() {
+					_, err := internal.BumpConstraint("1.2.3", "huge")
+					Expect(err).To(MatchError(ContainSubstring(`invalid bump "huge"`)))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// ReportFormatJSON is the format name for JSON update reports.
+const ReportFormatJSON = "json"
+
+// VersionChange records a single value in a configuration file that was moved
+// from one version to another.
+type VersionChange struct {
+	// File is the path to the configuration file that contains the value.
+	File string `json:"file"`
+	// Path is the location of the value within the file, for example
+	// `buildpacks[0].uri` or `order[1].group[0].version`.
+	Path string `json:"path"`
+	// ID is the buildpack, extension or dependency ID, when one is known.
+	ID string `json:"id,omitempty"`
+	// Image is the image or registry reference without its version.
+	Image      string `json:"image,omitempty"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+	// Bump is one of major, minor or patch when both versions are semantic
+	// versions.
+	Bump string `json:"bump,omitempty"`
+	// Source is the registry or file the new version was found in.
+	Source string `json:"source,omitempty"`
+}
+
+// UpdateReport is the machine-readable summary of an update command.
+type UpdateReport struct {
+	HighestBump string          `json:"highest_bump"`
+	Changes     []VersionChange `json:"changes"`
+	// Shortfalls are the targets of dependency-constraints that have fewer
+	// versions available than their patches.
+	Shortfalls []TargetShortfall `json:"shortfalls,omitempty"`
+}
+
+// PrintVersionChanges writes a table of each image with its old and new
+// versions to the given writer. Images that appear more than once with the
+// same versions are only printed once.
+func PrintVersionChanges(w io.Writer, changes []VersionChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, err := fmt.Fprintln(tw, "IMAGE\tOLD VERSION\tNEW VERSION")
+	if err != nil {
+		return err
+	}
+
+	printed := map[VersionChange]bool{}
+	for _, change := range changes {
+		row := VersionChange{Image: change.Image, OldVersion: change.OldVersion, NewVersion: change.NewVersion}
+		if printed[row] {
+			continue
+		}
+		printed[row] = true
+
+		oldVersion := change.OldVersion
+		if oldVersion == "" {
+			oldVersion = "<none>"
+		}
+
+		_, err = fmt.Fprintf(tw, "%s\t%s\t%s\n", change.Image, oldVersion, change.NewVersion)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// WriteUpdateReport writes the report in the given format. Only changes where
+// the version actually moved are included.
+func WriteUpdateReport(w io.Writer, format string, report UpdateReport) error {
+	if format != ReportFormatJSON {
+		return fmt.Errorf("unsupported report format %q: must be %q", format, ReportFormatJSON)
+	}
+
+	changes := []VersionChange{}
+	for _, change := range report.Changes {
+		if change.OldVersion != change.NewVersion {
+			changes = append(changes, change)
+		}
+	}
+	report.Changes = changes
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(report)
+	if err != nil {
+		return fmt.Errorf("failed to write update report: %w", err)
+	}
+
+	return nil
+}
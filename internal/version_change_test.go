@@ -0,0 +1,106 @@
+package internal_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVersionChange(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("PrintVersionChanges", func() {
+		it("prints a table of old and new versions for each image", func() {
+			buffer := bytes.NewBuffer(nil)
+
+			err := internal.PrintVersionChanges(buffer, []internal.VersionChange{
+				{
+					Image:      "some-registry/some-repository/some-buildpack",
+					OldVersion: "0.0.10",
+					NewVersion: "0.20.12",
+				},
+				{
+					Image:      "some-registry/lifecycle",
+					NewVersion: "0.21.1",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(Equal(`IMAGE                                         OLD VERSION  NEW VERSION
+some-registry/some-repository/some-buildpack  0.0.10       0.20.12
+some-registry/lifecycle                       <none>       0.21.1
+`))
+		})
+	})
+
+	context("WriteUpdateReport", func() {
+		it("writes the changed versions as JSON", func() {
+			buffer := bytes.NewBuffer(nil)
+
+			err := internal.WriteUpdateReport(buffer, "json", internal.UpdateReport{
+				HighestBump: "minor",
+				Changes: []internal.VersionChange{
+					{
+						File:       "builder.toml",
+						Path:       "buildpacks[0].uri",
+						ID:         "some-buildpack-id",
+						Image:      "some-registry/some-repository/some-buildpack",
+						OldVersion: "0.0.10",
+						NewVersion: "0.1.0",
+						Bump:       "minor",
+						Source:     "some-registry",
+					},
+					{
+						File:       "builder.toml",
+						Path:       "lifecycle.version",
+						Image:      "some-registry/lifecycle",
+						OldVersion: "0.21.1",
+						NewVersion: "0.21.1",
+						Source:     "some-registry",
+					},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(MatchJSON(`{
+				"highest_bump": "minor",
+				"changes": [
+					{
+						"file": "builder.toml",
+						"path": "buildpacks[0].uri",
+						"id": "some-buildpack-id",
+						"image": "some-registry/some-repository/some-buildpack",
+						"old_version": "0.0.10",
+						"new_version": "0.1.0",
+						"bump": "minor",
+						"source": "some-registry"
+					}
+				]
+			}`))
+		})
+
+		context("when there are no changes", func() {
+			it("writes an empty list of changes", func() {
+				buffer := bytes.NewBuffer(nil)
+
+				err := internal.WriteUpdateReport(buffer, "json", internal.UpdateReport{HighestBump: "<none>"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buffer.String()).To(MatchJSON(`{"highest_bump": "<none>", "changes": []}`))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the format is not supported", func() {
+				it("returns an error", func() {
+					err := internal.WriteUpdateReport(bytes.NewBuffer(nil), "xml", internal.UpdateReport{})
+					Expect(err).To(MatchError(`unsupported report format "xml": must be "json"`))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ConfigMetadata is the [metadata] table of a builder.toml or package.toml
+// file. Only the jam-specific settings are modelled.
+type ConfigMetadata struct {
+	Jam JamMetadata `toml:"jam,omitempty"`
+}
+
+// JamMetadata is the [metadata.jam] table of a builder.toml or package.toml
+// file.
+type JamMetadata struct {
+	// Constraints hold entries at versions that satisfy a semver constraint.
+	// They are keyed by buildpack ID, extension ID or image name, or by
+	// `lifecycle` for the lifecycle version.
+	Constraints map[string]string `toml:"constraints,omitempty"`
+}
+
+// HoldConstraint returns the version hold for an entry. A jam-constraint set
+// on the entry itself takes precedence over the [metadata.jam.constraints]
+// table, which is searched for each of the given keys in order. A nil
+// constraint is returned when the entry is not held.
+func (m JamMetadata) HoldConstraint(entryConstraint string, keys ...string) (*semver.Constraints, error) {
+	constraint := entryConstraint
+	if constraint == "" {
+		for _, key := range keys {
+			if c, ok := m.Constraints[key]; ok && key != "" {
+				constraint = c
+				break
+			}
+		}
+	}
+
+	if constraint == "" {
+		return nil, nil
+	}
+
+	parsed, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version hold %q: %w", constraint, err)
+	}
+
+	return parsed, nil
+}
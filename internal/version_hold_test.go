@@ -0,0 +1,63 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/paketo-buildpacks/jam/v2/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVersionHold(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		metadata internal.JamMetadata
+	)
+
+	it.Before(func() {
+		metadata = internal.JamMetadata{
+			Constraints: map[string]string{
+				"some-repository/some-buildpack-id":   "< 2.0.0",
+				"some-registry/some-repository/image": "~1.2",
+			},
+		}
+	})
+
+	context("HoldConstraint", func() {
+		it("returns the constraint for the first matching key", func() {
+			constraint, err := metadata.HoldConstraint("", "", "some-registry/some-repository/image", "some-repository/some-buildpack-id")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(constraint.Check(semver.MustParse("1.2.9"))).To(BeTrue())
+			Expect(constraint.Check(semver.MustParse("1.3.0"))).To(BeFalse())
+		})
+
+		context("when the entry has its own constraint", func() {
+			it("takes precedence over the metadata table", func() {
+				constraint, err := metadata.HoldConstraint("< 1.0.0", "some-repository/some-buildpack-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(constraint.Check(semver.MustParse("1.0.0"))).To(BeFalse())
+				Expect(constraint.Check(semver.MustParse("0.9.0"))).To(BeTrue())
+			})
+		})
+
+		context("when the entry is not held", func() {
+			it("returns a nil constraint", func() {
+				constraint, err := metadata.HoldConstraint("", "some-repository/other-buildpack-id")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(constraint).To(BeNil())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the constraint is not valid", func() {
+				it("returns an error", func() {
+					_, err := metadata.HoldConstraint("not-a-constraint")
+					Expect(err).To(MatchError(ContainSubstring(`invalid version hold "not-a-constraint"`)))
+				})
+			})
+		})
+	})
+}